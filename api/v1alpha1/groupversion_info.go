@@ -0,0 +1,21 @@
+// Package v1alpha1 contains the TritonLoadBalancer API, which records the
+// desired configuration and observed state of a Service's load balancer
+// +kubebuilder:object:generate=true
+// +groupName=loadbalancer.triton.io
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the objects in this package
+	GroupVersion = schema.GroupVersion{Group: "loadbalancer.triton.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types in this package with a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this package to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,165 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReadyCondition is true once every replica of the load balancer is running
+// with the configuration in its spec
+const ReadyCondition = "Ready"
+
+// PortMapping is a single listener of the load balancer
+type PortMapping struct {
+	// Type is http, https, tcp or udp
+	Type        string `json:"type"`
+	ListenPort  int    `json:"listenPort"`
+	BackendName string `json:"backendName"`
+	// BackendPort defaults to the listen port
+	BackendPort int `json:"backendPort,omitempty"`
+	// ProxyProtocol sends PROXY protocol headers to the backends of this
+	// listener only
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+}
+
+// TritonLoadBalancerSpec is the load balancer configuration derived from a
+// Service
+type TritonLoadBalancerSpec struct {
+	// ServiceName is the Service the load balancer was created for
+	ServiceName string `json:"serviceName"`
+
+	// InstanceName is the name of the first Triton instance; further
+	// replicas are suffixed with their index
+	InstanceName string `json:"instanceName"`
+
+	// Replicas is the number of load balancer instances; zero means one
+	Replicas int `json:"replicas,omitempty"`
+
+	PortMappings   []PortMapping `json:"portMappings,omitempty"`
+	MaxBackends    int           `json:"maxBackends,omitempty"`
+	MaxConnections int           `json:"maxConnections,omitempty"`
+
+	// CertificateName lists the certificate subjects, comma separated
+	CertificateName string `json:"certificateName,omitempty"`
+
+	// CertificateSecretName names a kubernetes.io/tls Secret in the same
+	// namespace whose certificate is installed on the load balancer
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+
+	MetricsACL            []string          `json:"metricsACL,omitempty"`
+	ProxyProtocol         bool              `json:"proxyProtocol,omitempty"`
+	Sticky                bool              `json:"sticky,omitempty"`
+	ExternalTrafficPolicy string            `json:"externalTrafficPolicy,omitempty"`
+	Tags                  map[string]string `json:"tags,omitempty"`
+	Affinity              []string          `json:"affinity,omitempty"`
+	BackendWeights        map[string]int    `json:"backendWeights,omitempty"`
+	AllocatePublicIP      bool              `json:"allocatePublicIP,omitempty"`
+	ReloadOnChange        bool              `json:"reloadOnChange,omitempty"`
+
+	TimeoutConnect *metav1.Duration `json:"timeoutConnect,omitempty"`
+	TimeoutClient  *metav1.Duration `json:"timeoutClient,omitempty"`
+	TimeoutServer  *metav1.Duration `json:"timeoutServer,omitempty"`
+
+	// HealthCheckPath is checked with an HTTP GET on the backends of HTTP
+	// listeners, at HealthCheckInterval with HealthCheckTimeout; a backend is
+	// marked down after HealthCheckUnhealthyThreshold failed checks
+	HealthCheckPath               string           `json:"healthCheckPath,omitempty"`
+	HealthCheckInterval           *metav1.Duration `json:"healthCheckInterval,omitempty"`
+	HealthCheckTimeout            *metav1.Duration `json:"healthCheckTimeout,omitempty"`
+	HealthCheckUnhealthyThreshold int              `json:"healthCheckUnhealthyThreshold,omitempty"`
+
+	// DrainTimeout is how long instances that are deleted or replaced keep
+	// serving their established connections
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+
+	// ProvisionTimeout and DeleteTimeout bound how long the instances are
+	// waited for to run and to be deleted; unset means the controller default
+	ProvisionTimeout *metav1.Duration `json:"provisionTimeout,omitempty"`
+	DeleteTimeout    *metav1.Duration `json:"deleteTimeout,omitempty"`
+
+	// Package and Image override the package, by name or ID, and the image,
+	// by ID or name[@version], of the instances
+	Package string `json:"package,omitempty"`
+	Image   string `json:"image,omitempty"`
+
+	// Networks are the names or IDs of the networks of the instances
+	Networks []string `json:"networks,omitempty"`
+
+	// FirewallSourceRanges restricts the managed firewall rules of the
+	// listen ports to these addresses and CIDRs
+	FirewallSourceRanges []string `json:"firewallSourceRanges,omitempty"`
+
+	// Datacenter is the Triton datacenter the instances run in; empty means
+	// the default datacenter of the controller
+	Datacenter string `json:"datacenter,omitempty"`
+
+	// LocalityNear and LocalityFar are instance UUIDs whose compute nodes
+	// the instances are provisioned on or kept off; they are hints unless
+	// LocalityStrict is set
+	LocalityNear   []string `json:"localityNear,omitempty"`
+	LocalityFar    []string `json:"localityFar,omitempty"`
+	LocalityStrict bool     `json:"localityStrict,omitempty"`
+
+	// SpreadReplicas provisions every replica on its own compute node
+	SpreadReplicas bool `json:"spreadReplicas,omitempty"`
+}
+
+// ReplicaStatus is the observed state of one load balancer instance
+type ReplicaStatus struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	State string   `json:"state"`
+	IPs   []string `json:"ips,omitempty"`
+
+	// DNSNames are the names Triton CNS publishes for the instance
+	DNSNames []string `json:"dnsNames,omitempty"`
+}
+
+// TritonLoadBalancerStatus is the observed state of the load balancer
+type TritonLoadBalancerStatus struct {
+	// ObservedGeneration is the generation of the spec last applied
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// InstanceID is the ID of the first replica
+	InstanceID string `json:"instanceID,omitempty"`
+
+	// IPs are the addresses of every replica
+	IPs []string `json:"ips,omitempty"`
+
+	// State is the Triton state of the first replica, e.g. provisioning
+	// or running
+	State string `json:"state,omitempty"`
+
+	Replicas []ReplicaStatus `json:"replicas,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=tlb
+// +kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.status.instanceID`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="IPs",type=string,JSONPath=`.status.ips`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TritonLoadBalancer is the Triton load balancer of a Service
+type TritonLoadBalancer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TritonLoadBalancerSpec   `json:"spec,omitempty"`
+	Status TritonLoadBalancerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TritonLoadBalancerList is a list of TritonLoadBalancers
+type TritonLoadBalancerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TritonLoadBalancer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TritonLoadBalancer{}, &TritonLoadBalancerList{})
+}
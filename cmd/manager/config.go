@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// configReloadInterval is how often the file named by --config is checked
+// for changes
+const configReloadInterval = 10 * time.Second
+
+// ControllerConfig is the file named by --config, e.g.
+//
+//	triton:
+//	  credentialsSecret: kube-system/triton-credentials
+//	defaults:
+//	  package: g4-highcpu-1G
+//	timeouts:
+//	  provision: 10m
+//	concurrency:
+//	  reconciles: 4
+//	featureGates:
+//	  manageFirewall: true
+//
+// Every setting is optional and stands in for the flag of the same meaning;
+// flags given on the command line take precedence. The defaults and the
+// provision and delete timeouts are applied again whenever the file
+// changes, other settings need a restart.
+type ControllerConfig struct {
+	Triton       TritonConfig       `json:"triton,omitempty"`
+	Defaults     DefaultsConfig     `json:"defaults,omitempty"`
+	Timeouts     TimeoutsConfig     `json:"timeouts,omitempty"`
+	Concurrency  ConcurrencyConfig  `json:"concurrency,omitempty"`
+	FeatureGates FeatureGatesConfig `json:"featureGates,omitempty"`
+}
+
+// TritonConfig references the CloudAPI credentials, either as files and
+// settings or as a Secret
+type TritonConfig struct {
+	URL               string `json:"url,omitempty"`
+	Account           string `json:"account,omitempty"`
+	KeyID             string `json:"keyID,omitempty"`
+	KeyPath           string `json:"keyPath,omitempty"`
+	PassphraseFile    string `json:"passphraseFile,omitempty"`
+	SSHAgent          *bool  `json:"sshAgent,omitempty"`
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	DatacentersConfig string `json:"datacentersConfig,omitempty"`
+}
+
+// DefaultsConfig holds the settings of Services that don't override them
+type DefaultsConfig struct {
+	Package              string   `json:"package,omitempty"`
+	Image                string   `json:"image,omitempty"`
+	CertificateName      string   `json:"certificateName,omitempty"`
+	MetricsACL           []string `json:"metricsACL,omitempty"`
+	InstanceNameTemplate string   `json:"instanceNameTemplate,omitempty"`
+	PropagateLabels      []string `json:"propagateLabels,omitempty"`
+	UserScriptFile       string   `json:"userScriptFile,omitempty"`
+}
+
+// TimeoutsConfig holds the timeouts and intervals of the controller
+type TimeoutsConfig struct {
+	Reconcile       *metav1.Duration `json:"reconcile,omitempty"`
+	Provision       *metav1.Duration `json:"provision,omitempty"`
+	Delete          *metav1.Duration `json:"delete,omitempty"`
+	API             *metav1.Duration `json:"api,omitempty"`
+	Drain           *metav1.Duration `json:"drain,omitempty"`
+	VerifyListener  *metav1.Duration `json:"verifyListener,omitempty"`
+	PollInterval    *metav1.Duration `json:"pollInterval,omitempty"`
+	ResyncPeriod    *metav1.Duration `json:"resyncPeriod,omitempty"`
+	MaxRetryBackoff *metav1.Duration `json:"maxRetryBackoff,omitempty"`
+}
+
+// ConcurrencyConfig bounds the parallel work of the controller
+type ConcurrencyConfig struct {
+	Reconciles *int     `json:"reconciles,omitempty"`
+	APIRPS     *float64 `json:"apiRPS,omitempty"`
+	APIBurst   *int     `json:"apiBurst,omitempty"`
+	APIRetries *int     `json:"apiRetries,omitempty"`
+}
+
+// FeatureGatesConfig turns optional features on or off
+type FeatureGatesConfig struct {
+	AsyncProvisioning   *bool `json:"asyncProvisioning,omitempty"`
+	VerifyListener      *bool `json:"verifyListener,omitempty"`
+	ManageFirewall      *bool `json:"manageFirewall,omitempty"`
+	AutoRecreateFailed  *bool `json:"autoRecreateFailed,omitempty"`
+	LoadBalancerObjects *bool `json:"loadBalancerObjects,omitempty"`
+	OrphanGC            *bool `json:"orphanGC,omitempty"`
+	Webhook             *bool `json:"webhook,omitempty"`
+	PublishHostname     *bool `json:"publishHostname,omitempty"`
+}
+
+// loadConfig reads the YAML or JSON config file at path, returning its
+// contents too so changes can be detected
+func loadConfig(path string) (*ControllerConfig, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	config, err := parseConfig(path, data)
+	return config, data, err
+}
+
+// parseConfig parses data, the contents of the config file at path
+func parseConfig(path string, data []byte) (*ControllerConfig, error) {
+	config := &ControllerConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	for name, timeout := range map[string]*metav1.Duration{"provision": config.Timeouts.Provision, "delete": config.Timeouts.Delete} {
+		if timeout != nil && timeout.Duration < 0 {
+			return nil, fmt.Errorf("invalid config %s: the %s timeout must not be negative", path, name)
+		}
+	}
+	return config, nil
+}
+
+// flags returns the value of every flag the config sets, by flag name
+func (c *ControllerConfig) flags() map[string]string {
+	values := map[string]string{}
+	setString := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			values[name] = strconv.FormatBool(*value)
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			values[name] = strconv.Itoa(*value)
+		}
+	}
+	setDuration := func(name string, value *metav1.Duration) {
+		if value != nil {
+			values[name] = value.Duration.String()
+		}
+	}
+
+	setString("triton-url", c.Triton.URL)
+	setString("triton-account", c.Triton.Account)
+	setString("triton-key-id", c.Triton.KeyID)
+	setString("triton-key-path", c.Triton.KeyPath)
+	setString("triton-key-passphrase-file", c.Triton.PassphraseFile)
+	setBool("triton-ssh-agent", c.Triton.SSHAgent)
+	setString("triton-credentials-secret", c.Triton.CredentialsSecret)
+	setString("datacenters-config", c.Triton.DatacentersConfig)
+
+	setString("default-package", c.Defaults.Package)
+	setString("default-image", c.Defaults.Image)
+	setString("default-certificate-name", c.Defaults.CertificateName)
+	setString("default-metrics-acl", strings.Join(c.Defaults.MetricsACL, ","))
+	setString("instance-name-template", c.Defaults.InstanceNameTemplate)
+	setString("propagate-labels", strings.Join(c.Defaults.PropagateLabels, ","))
+	setString("lb-user-script-file", c.Defaults.UserScriptFile)
+
+	setDuration("reconcile-timeout", c.Timeouts.Reconcile)
+	setDuration("provision-timeout", c.Timeouts.Provision)
+	setDuration("delete-timeout", c.Timeouts.Delete)
+	setDuration("triton-api-timeout", c.Timeouts.API)
+	setDuration("drain-timeout", c.Timeouts.Drain)
+	setDuration("verify-listener-timeout", c.Timeouts.VerifyListener)
+	setDuration("provision-poll-interval", c.Timeouts.PollInterval)
+	setDuration("resync-period", c.Timeouts.ResyncPeriod)
+	setDuration("max-retry-backoff", c.Timeouts.MaxRetryBackoff)
+
+	setInt("concurrent-reconciles", c.Concurrency.Reconciles)
+	if c.Concurrency.APIRPS != nil {
+		values["triton-api-rps"] = strconv.FormatFloat(*c.Concurrency.APIRPS, 'f', -1, 64)
+	}
+	setInt("triton-api-burst", c.Concurrency.APIBurst)
+	setInt("triton-api-retries", c.Concurrency.APIRetries)
+
+	setBool("async-provisioning", c.FeatureGates.AsyncProvisioning)
+	setBool("verify-listener", c.FeatureGates.VerifyListener)
+	setBool("manage-firewall", c.FeatureGates.ManageFirewall)
+	setBool("auto-recreate-failed", c.FeatureGates.AutoRecreateFailed)
+	setBool("enable-loadbalancer-objects", c.FeatureGates.LoadBalancerObjects)
+	setBool("enable-orphan-gc", c.FeatureGates.OrphanGC)
+	setBool("enable-webhook", c.FeatureGates.Webhook)
+	setBool("publish-hostname", c.FeatureGates.PublishHostname)
+	return values
+}
+
+// applyConfig sets the flags of fs the config sets, apart from those given
+// on the command line, and returns the names of those given on the command
+// line
+func applyConfig(fs *flag.FlagSet, config *ControllerConfig) (map[string]bool, error) {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, value := range config.flags() {
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid config setting for --%s: %w", name, err)
+		}
+	}
+	return explicit, nil
+}
+
+// configWatcher applies the live settings of the config file whenever it
+// changes
+type configWatcher struct {
+	path   string
+	client *triton.Client
+	log    logr.Logger
+
+	// flags holds the defaults given on the command line, which the config
+	// file doesn't override, and explicit the names of their flags
+	flags    triton.Defaults
+	explicit map[string]bool
+
+	data   []byte
+	config *ControllerConfig
+}
+
+// Start checks the config file for changes until ctx is done
+func (w *configWatcher) Start(ctx context.Context) error {
+	ticker := time.NewTicker(configReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// NeedLeaderElection lets standby replicas keep their defaults current too
+func (w *configWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// reload applies the config file if it changed. An invalid file is
+// reported and the settings in use are kept.
+func (w *configWatcher) reload() {
+	config, data, err := loadConfig(w.path)
+	if data != nil && bytes.Equal(data, w.data) {
+		return
+	}
+	if err != nil {
+		w.log.Error(err, "Unable to reload config, keeping the current settings", "path", w.path)
+		// An invalid file is reported once, not on every check
+		if data != nil {
+			w.data = data
+		}
+		return
+	}
+
+	defaults := w.defaults(config)
+	w.client.SetDefaults(defaults)
+	w.log.Info("Reloaded config", "path", w.path,
+		"package", defaults.Package, "image", defaults.Image,
+		"provisionTimeout", defaults.ProvisionTimeout.String(), "deleteTimeout", defaults.DeleteTimeout.String())
+	if w.config != nil && !reflect.DeepEqual(restartSettings(config), restartSettings(w.config)) {
+		w.log.Info("WARNING: config changes other than the defaults and the provision and delete timeouts take effect after a restart",
+			"path", w.path)
+	}
+	w.data = data
+	w.config = config
+}
+
+// defaults returns the load balancer defaults of config, apart from those
+// given on the command line
+func (w *configWatcher) defaults(config *ControllerConfig) triton.Defaults {
+	defaults := w.flags
+	if !w.explicit["default-package"] {
+		defaults.Package = config.Defaults.Package
+	}
+	if !w.explicit["default-image"] {
+		defaults.Image = config.Defaults.Image
+	}
+	if !w.explicit["provision-timeout"] {
+		defaults.ProvisionTimeout = 0
+		if config.Timeouts.Provision != nil {
+			defaults.ProvisionTimeout = config.Timeouts.Provision.Duration
+		}
+	}
+	if !w.explicit["delete-timeout"] {
+		defaults.DeleteTimeout = 0
+		if config.Timeouts.Delete != nil {
+			defaults.DeleteTimeout = config.Timeouts.Delete.Duration
+		}
+	}
+	return defaults
+}
+
+// restartSettings returns config without the settings applied on reload
+func restartSettings(config *ControllerConfig) ControllerConfig {
+	settings := *config
+	settings.Defaults.Package = ""
+	settings.Defaults.Image = ""
+	settings.Timeouts.Provision = nil
+	settings.Timeouts.Delete = nil
+	return settings
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// tritonCredentials holds the CloudAPI connection settings shared by the
+// controller and its subcommands
+type tritonCredentials struct {
+	KeyPath string
+	KeyID   string
+	Account string
+	URL     string
+
+	// PassphraseFile names a file holding the passphrase of an encrypted
+	// private key; without it $TRITON_KEY_PASSPHRASE is used
+	PassphraseFile string
+	passphrase     string
+
+	// SSHAgent signs requests with the agent at $SSH_AUTH_SOCK instead of a
+	// private key file
+	SSHAgent bool
+}
+
+// credentialEnv lists, for each setting, its flag and the environment
+// variables consulted in order when the flag is unset
+var credentialEnv = []struct {
+	flag string
+	env  []string
+	get  func(c *tritonCredentials) *string
+}{
+	{"--triton-key-path", []string{"TRITON_KEY_PATH", "SDC_KEY_PATH"}, func(c *tritonCredentials) *string { return &c.KeyPath }},
+	{"--triton-key-id", []string{"TRITON_KEY_ID", "SDC_KEY_ID"}, func(c *tritonCredentials) *string { return &c.KeyID }},
+	{"--triton-account", []string{"TRITON_ACCOUNT", "SDC_ACCOUNT"}, func(c *tritonCredentials) *string { return &c.Account }},
+	{"--triton-url", []string{"TRITON_URL", "SDC_URL"}, func(c *tritonCredentials) *string { return &c.URL }},
+}
+
+// applyEnvFallbacks fills settings not given as flags from the standard
+// Triton environment variables. Flags always take precedence. Without a key
+// path, an SSH agent at $SSH_AUTH_SOCK is used.
+func (c *tritonCredentials) applyEnvFallbacks() {
+	for _, setting := range credentialEnv {
+		value := setting.get(c)
+		for _, env := range setting.env {
+			if *value != "" {
+				break
+			}
+			*value = os.Getenv(env)
+		}
+	}
+	if c.KeyPath == "" && os.Getenv("SSH_AUTH_SOCK") != "" {
+		c.SSHAgent = true
+	}
+}
+
+// loadPassphrase reads the private key passphrase, which is optional, from
+// PassphraseFile or $TRITON_KEY_PASSPHRASE
+func (c *tritonCredentials) loadPassphrase() error {
+	if c.PassphraseFile == "" {
+		c.passphrase = os.Getenv("TRITON_KEY_PASSPHRASE")
+		return nil
+	}
+	data, err := os.ReadFile(c.PassphraseFile)
+	if err != nil {
+		return fmt.Errorf("failed to read private key passphrase: %w", err)
+	}
+	c.passphrase = strings.TrimRight(string(data), "\r\n")
+	return nil
+}
+
+// defaults returns the settings a credentials Secret may leave out
+func (c *tritonCredentials) defaults() triton.Credentials {
+	return triton.Credentials{Account: c.Account, KeyID: c.KeyID, URL: c.URL, Passphrase: c.passphrase}
+}
+
+// credentials reads the private key from KeyPath, unless the SSH agent is
+// used, and returns the complete credentials
+func (c *tritonCredentials) credentials() (triton.Credentials, error) {
+	if c.SSHAgent {
+		creds := c.defaults()
+		creds.SSHAgent = true
+		return creds, nil
+	}
+	key, err := os.ReadFile(c.KeyPath)
+	if err != nil {
+		return triton.Credentials{}, fmt.Errorf("failed to read private key from %s: %w", c.KeyPath, err)
+	}
+	creds := c.defaults()
+	creds.PrivateKey = key
+	return creds, nil
+}
+
+// validate returns a single error naming every setting that is still missing
+func (c *tritonCredentials) validate() error {
+	var missing []string
+	for _, setting := range credentialEnv {
+		if setting.flag == "--triton-key-path" && c.SSHAgent {
+			continue
+		}
+		if *setting.get(c) == "" {
+			missing = append(missing, fmt.Sprintf("%s (or %s)", setting.flag, strings.Join(setting.env, "/")))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required Triton credentials: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// datacentersConfig is the file named by --datacenters-config, e.g.
+//
+//	datacenters:
+//	- name: us-east-1
+//	  url: https://us-east-1.api.example.com
+//	- name: us-west-1
+//	  url: https://us-west-1.api.example.com
+//
+// The first datacenter is the default one.
+type datacentersConfig struct {
+	Datacenters []triton.Datacenter `json:"datacenters"`
+}
+
+// loadDatacenters reads the datacenters listed in the YAML or JSON file at
+// path; an empty path lists none
+func loadDatacenters(path string) ([]triton.Datacenter, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read datacenters config: %w", err)
+	}
+	var config datacentersConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid datacenters config %s: %w", path, err)
+	}
+	if len(config.Datacenters) == 0 {
+		return nil, fmt.Errorf("datacenters config %s lists no datacenters", path)
+	}
+	return config.Datacenters, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/triton/loadbalancer-controller/pkg/dns"
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// dnsOptions are the --dns-* flags
+type dnsOptions struct {
+	Provider   string
+	WebhookURL string
+	TokenFile  string
+	TTL        int
+}
+
+// provider returns the DNS provider the options select, or nil when DNS
+// registration is disabled
+func (o dnsOptions) provider(tritonClient *triton.Client) (dns.Provider, error) {
+	switch o.Provider {
+	case "":
+		return nil, nil
+	case "cns":
+		return &dns.CNS{Tagger: tritonClient}, nil
+	case "webhook":
+		if o.WebhookURL == "" {
+			return nil, fmt.Errorf("--dns-provider=webhook requires --dns-webhook-url")
+		}
+		webhook := &dns.Webhook{URL: o.WebhookURL, TTL: o.TTL}
+		if o.TokenFile != "" {
+			token, err := os.ReadFile(o.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read DNS webhook token: %w", err)
+			}
+			webhook.Token = strings.TrimSpace(string(token))
+		}
+		return webhook, nil
+	default:
+		return nil, fmt.Errorf("unsupported DNS provider %q, expected cns or webhook", o.Provider)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// loadBalancerSummary is one row of the list-lbs output
+type loadBalancerSummary struct {
+	Name         string               `json:"name"`
+	ID           string               `json:"id"`
+	State        string               `json:"state"`
+	IPs          []string             `json:"ips"`
+	PortMappings []triton.PortMapping `json:"portMappings"`
+	Datacenter   string               `json:"datacenter,omitempty"`
+}
+
+// runListLoadBalancers implements the list-lbs subcommand, printing every load
+// balancer managed by the given manager ID. It returns the process exit code.
+func runListLoadBalancers(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("list-lbs", flag.ContinueOnError)
+	var creds tritonCredentials
+	fs.StringVar(&creds.KeyPath, "triton-key-path", "", "Path to the Triton private key (default $TRITON_KEY_PATH or $SDC_KEY_PATH).")
+	fs.StringVar(&creds.KeyID, "triton-key-id", "", "Triton key ID for API authentication (default $TRITON_KEY_ID or $SDC_KEY_ID).")
+	fs.StringVar(&creds.Account, "triton-account", "", "Triton account name (default $TRITON_ACCOUNT or $SDC_ACCOUNT).")
+	fs.StringVar(&creds.URL, "triton-url", "", "Triton CloudAPI URL (default $TRITON_URL or $SDC_URL).")
+	datacentersConfigPath := fs.String("datacenters-config", "",
+		"YAML file listing the name and CloudAPI URL of every Triton datacenter to list; replaces --triton-url.")
+	fs.StringVar(&creds.PassphraseFile, "triton-key-passphrase-file", "",
+		"File holding the passphrase of an encrypted private key (default $TRITON_KEY_PASSPHRASE).")
+	fs.BoolVar(&creds.SSHAgent, "triton-ssh-agent", false,
+		"Sign CloudAPI requests with the SSH agent at $SSH_AUTH_SOCK instead of --triton-key-path; the default when no key path is set and $SSH_AUTH_SOCK is.")
+	tritonAPITimeout := fs.Duration("triton-api-timeout", 30*time.Second,
+		"Timeout for each individual Triton CloudAPI request (0 disables the per-call limit).")
+	managerID := fs.String("manager-id", triton.DefaultManagerID,
+		"Value of the managed-by tag identifying load balancers owned by the controller.")
+	clusterName := fs.String("cluster-name", "",
+		"Only list load balancers tagged with this cluster name.")
+	output := fs.String("o", "table", "Output format: table or json.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "unsupported output format %q\n", *output)
+		return 2
+	}
+	creds.applyEnvFallbacks()
+	datacenters, err := loadDatacenters(*datacentersConfigPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(datacenters) > 0 {
+		creds.URL = datacenters[0].URL
+	}
+	if err := creds.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := creds.loadPassphrase(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fileCreds, err := creds.credentials()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	tritonClient, err := triton.NewMultiDatacenterClient(fileCreds, datacenters,
+		triton.WithManagerID(*managerID), triton.WithClusterName(*clusterName),
+		triton.WithAPITimeout(*tritonAPITimeout))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create Triton client: %v\n", err)
+		return 1
+	}
+
+	summaries, err := listLoadBalancers(context.Background(), tritonClient)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to list load balancers: %v\n", err)
+		return 1
+	}
+
+	if err := writeLoadBalancers(out, *output, summaries); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to write output: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listLoadBalancers gathers a summary of every managed load balancer instance
+func listLoadBalancers(ctx context.Context, tritonClient *triton.Client) ([]loadBalancerSummary, error) {
+	loadBalancers, err := tritonClient.ListLoadBalancers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []loadBalancerSummary{}
+	for _, lb := range loadBalancers {
+		if lb.Instance == nil {
+			continue
+		}
+		replicas := lb.Instance.Replicas
+		if len(replicas) == 0 {
+			replicas = []*triton.TritonInstance{lb.Instance}
+		}
+		for _, instance := range replicas {
+			summaries = append(summaries, loadBalancerSummary{
+				Name:         instance.Name,
+				ID:           instance.ID,
+				State:        instance.State,
+				IPs:          instance.IPs,
+				PortMappings: lb.PortMappings,
+				Datacenter:   lb.Datacenter,
+			})
+		}
+	}
+	return summaries, nil
+}
+
+// writeLoadBalancers prints the summaries as a table or as JSON
+func writeLoadBalancers(out io.Writer, format string, summaries []loadBalancerSummary) error {
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tID\tSTATE\tIPS\tPORTMAP\tDATACENTER")
+	for _, summary := range summaries {
+		portmap := make([]string, 0, len(summary.PortMappings))
+		for _, mapping := range summary.PortMappings {
+			portmap = append(portmap, mapping.String())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", summary.Name, summary.ID, summary.State,
+			strings.Join(summary.IPs, ","), strings.Join(portmap, ","), summary.Datacenter)
+	}
+	return w.Flush()
+}
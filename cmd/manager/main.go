@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/triton/loadbalancer-controller/api/v1alpha1"
 	"github.com/triton/loadbalancer-controller/pkg/controller"
 	"github.com/triton/loadbalancer-controller/pkg/triton"
+	lbwebhook "github.com/triton/loadbalancer-controller/pkg/webhook"
 )
 
 var (
@@ -22,44 +34,296 @@ var (
 
 func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
 }
 
 func main() {
+	// Operational subcommands run instead of the controller
+	if len(os.Args) > 1 && os.Args[1] == "list-lbs" {
+		os.Exit(runListLoadBalancers(os.Args[2:], os.Stdout))
+	}
+
+	var configPath string
 	var metricsAddr string
 	var enableLeaderElection bool
-	var tritonKeyPath string
-	var tritonKeyId string
-	var tritonAccount string
-	var tritonUrl string
+	var leaderElectionNamespace string
+	var shard controller.Shard
+	var leaseDuration time.Duration
+	var renewDeadline time.Duration
+	var retryPeriod time.Duration
+	var creds tritonCredentials
+	var credentialsSecret string
+	var datacentersConfigPath string
+	var tritonAPITimeout time.Duration
+	var tritonAPIRPS float64
+	var tritonAPIBurst int
+	var tritonAPIRetries int
+	var tritonCacheTTL time.Duration
+	var reconcileTimeout time.Duration
+	var autoRecreateFailed bool
+	var resyncPeriod time.Duration
+	var pollInterval time.Duration
+	var maxRetryBackoff time.Duration
+	var asyncProvisioning bool
+	var verifyListener bool
+	var listenerTimeout time.Duration
+	var drainTimeout time.Duration
+	var publishHostname bool
+	var lbDefaults triton.Defaults
 	var probeAddr string
+	var finalizerName string
+	var defaultMetricsACL string
+	var tagLabelPrefix string
+	var propagateLabels string
+	var defaultCertificateName string
+	var instanceNameTemplate string
+	var reloadKeys string
+	var userScriptFile string
+	var annotationPrefix string
+	var managerID string
+	var clusterName string
+	var enableOrphanGC bool
+	var orphanGCDryRun bool
+	var orphanGCInterval time.Duration
+	var migrateTags bool
+	var loadBalancerObjects bool
+	var loadBalancerClass string
+	var ignoreUnclassed bool
+	var manageFirewall bool
+	var dryRun bool
+	var dnsOpts dnsOptions
+	var concurrentReconciles int
+	var enableWebhook bool
+	var webhookPort int
+	var webhookCertDir string
 
+	flag.StringVar(&configPath, "config", "",
+		"Path to a YAML or JSON ControllerConfig file providing the settings of the flags not given on the command line. Changes to the default package and image and the provision and delete timeouts are applied without a restart.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager.")
-	flag.StringVar(&tritonKeyPath, "triton-key-path", "", "Path to the Triton private key.")
-	flag.StringVar(&tritonKeyId, "triton-key-id", "", "Triton key ID for API authentication.")
-	flag.StringVar(&tritonAccount, "triton-account", "", "Triton account name.")
-	flag.StringVar(&tritonUrl, "triton-url", "", "Triton CloudAPI URL.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election lease (defaults to the pod namespace).")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"How long non-leaders wait before trying to acquire leadership.")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"How long the leader keeps retrying to renew the lease before giving up leadership.")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"How long leader election clients wait between attempts.")
+	flag.IntVar(&shard.Index, "shard-index", 0,
+		"Shard of namespaces this controller manages, from 0 to --shard-count - 1.")
+	flag.IntVar(&shard.Count, "shard-count", 0,
+		"Number of shards the namespaces are split into by a hash of their name; each shard elects its own leader. 0 or 1 disables sharding.")
+	flag.StringVar(&creds.KeyPath, "triton-key-path", "", "Path to the Triton private key (default $TRITON_KEY_PATH or $SDC_KEY_PATH).")
+	flag.StringVar(&creds.KeyID, "triton-key-id", "", "Triton key ID for API authentication (default $TRITON_KEY_ID or $SDC_KEY_ID).")
+	flag.StringVar(&creds.Account, "triton-account", "", "Triton account name (default $TRITON_ACCOUNT or $SDC_ACCOUNT).")
+	flag.StringVar(&creds.URL, "triton-url", "", "Triton CloudAPI URL (default $TRITON_URL or $SDC_URL).")
+	flag.StringVar(&creds.PassphraseFile, "triton-key-passphrase-file", "",
+		"File holding the passphrase of an encrypted private key (default $TRITON_KEY_PASSPHRASE).")
+	flag.BoolVar(&creds.SSHAgent, "triton-ssh-agent", false,
+		"Sign CloudAPI requests with the SSH agent at $SSH_AUTH_SOCK instead of --triton-key-path; the default when no key path is set and $SSH_AUTH_SOCK is.")
+	flag.StringVar(&credentialsSecret, "triton-credentials-secret", "",
+		"Secret, as namespace/name, holding the Triton private key and optionally the account, key ID and URL; changes are applied without a restart.")
+	flag.StringVar(&datacentersConfigPath, "datacenters-config", "",
+		"YAML file listing the name and CloudAPI URL of every Triton datacenter Services may select with the datacenter annotation; the first is the default and replaces --triton-url.")
+	flag.DurationVar(&tritonAPITimeout, "triton-api-timeout", 30*time.Second,
+		"Timeout for each individual Triton CloudAPI request (0 disables the per-call limit).")
+	flag.Float64Var(&tritonAPIRPS, "triton-api-rps", 10,
+		"Maximum CloudAPI requests per second; requests beyond it wait (0 disables the limit).")
+	flag.IntVar(&tritonAPIBurst, "triton-api-burst", 20,
+		"CloudAPI requests that may be sent at once before --triton-api-rps applies.")
+	flag.IntVar(&tritonAPIRetries, "triton-api-retries", 3,
+		"Attempts at each CloudAPI request that is throttled or fails with a server error, with exponential backoff in between (1 disables retries).")
+	flag.DurationVar(&tritonCacheTTL, "triton-cache-ttl", 5*time.Second,
+		"How long load balancer lookups are cached; any change made by the controller drops the cache (0 disables it).")
+	flag.DurationVar(&reconcileTimeout, "reconcile-timeout", 10*time.Minute,
+		"Maximum duration of a single reconcile; reconciles exceeding it are requeued (0 disables the limit).")
+	flag.IntVar(&concurrentReconciles, "concurrent-reconciles", controller.DefaultConcurrentReconciles,
+		"How many load balancers are reconciled in parallel; reconciles of the same load balancer never overlap.")
+	flag.BoolVar(&autoRecreateFailed, "auto-recreate-failed", false,
+		"Delete load balancer instances that end up in a failed state so they are provisioned again.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 0,
+		"Re-reconcile every load balancer at this interval to correct out-of-band changes (0 disables it).")
+	flag.DurationVar(&pollInterval, "provision-poll-interval", controller.DefaultPollInterval,
+		"How often to re-check a load balancer that is still provisioning before publishing its IPs.")
+	flag.DurationVar(&maxRetryBackoff, "max-retry-backoff", controller.DefaultMaxRetryBackoff,
+		"Longest wait between retries of a load balancer whose reconciles keep failing; retries start after 5s and double with every failure in a row.")
+	flag.BoolVar(&asyncProvisioning, "async-provisioning", true,
+		"Return from a reconcile as soon as new load balancer instances are created and check on them every --provision-poll-interval, instead of waiting up to TRITON_PROVISION_TIMEOUT for them to run.")
+	flag.BoolVar(&verifyListener, "verify-listener", false,
+		"Only publish a load balancer's IPs once its first TCP listen port accepts connections.")
+	flag.DurationVar(&listenerTimeout, "verify-listener-timeout", controller.DefaultListenerTimeout,
+		"How long --verify-listener waits for a load balancer to accept connections before publishing its IPs anyway.")
+	flag.DurationVar(&drainTimeout, "drain-timeout", 0,
+		"How long load balancer instances that are deleted or replaced keep serving established connections, unless a Service sets cloud.tritoncompute/drain-timeout. 0 deletes them right away.")
+	flag.StringVar(&lbDefaults.Package, "default-package", "",
+		"Triton package of load balancers without a cloud.tritoncompute/package annotation (default $TRITON_LB_PACKAGE or "+triton.DefaultPackage+").")
+	flag.StringVar(&lbDefaults.Image, "default-image", "",
+		"Triton image of load balancers without a cloud.tritoncompute/image annotation (default $TRITON_LB_IMAGE or the HAProxy image).")
+	flag.DurationVar(&lbDefaults.ProvisionTimeout, "provision-timeout", 0,
+		"How long to wait for new load balancer instances to run (default $TRITON_PROVISION_TIMEOUT seconds or 5m).")
+	flag.DurationVar(&lbDefaults.DeleteTimeout, "delete-timeout", 0,
+		"How long to wait for deleted load balancer instances to be gone (default $TRITON_DELETE_TIMEOUT seconds or 5m).")
+	flag.BoolVar(&publishHostname, "publish-hostname", false,
+		"Publish the Triton CNS hostnames of load balancers in the Service status instead of their IPs, unless a Service sets the publish-hostname annotation. Requires CNS to be enabled for the account.")
+	flag.StringVar(&finalizerName, "finalizer-name", controller.DefaultFinalizerName,
+		"Finalizer added to managed Services.")
+	flag.StringVar(&defaultMetricsACL, "default-metrics-acl", "",
+		"Comma-separated CIDRs allowed to reach the metrics endpoint of every load balancer, merged with each Service's metrics_acl annotation.")
+	flag.StringVar(&tagLabelPrefix, "tag-label-prefix", controller.DefaultTagLabelPrefix,
+		"Service labels with this prefix are copied, without the prefix, to the load balancer's Triton tags.")
+	flag.StringVar(&propagateLabels, "propagate-labels", "",
+		"Comma-separated Service label keys, e.g. team,cost-center, copied under the same key to the load balancer's Triton tags.")
+	flag.StringVar(&annotationPrefix, "annotation-prefix", controller.DefaultAnnotationPrefix,
+		"Prefix of the Service annotations read and written by the controller, e.g. example.com for example.com/max_rs.")
+	flag.StringVar(&defaultCertificateName, "default-certificate-name", "",
+		"Certificate subject used by load balancers with an HTTPS port that don't set the certificate_name annotation.")
+	flag.StringVar(&instanceNameTemplate, "instance-name-template", controller.DefaultInstanceNameTemplate,
+		"Go template naming load balancer instances from the Service's {{.Namespace}} and {{.Name}} and the {{.ClusterID}} set by --cluster-name, e.g. {{.ClusterID}}-{{.Namespace}}-{{.Name}}.")
+	flag.StringVar(&reloadKeys, "reload-metadata-keys", strings.Join(triton.DefaultReloadKeys, ","),
+		"Comma-separated metadata keys whose change reboots load balancers with the reload-on-change annotation.")
+	flag.StringVar(&userScriptFile, "lb-user-script-file", "",
+		"Go template of a user-script, or of a #cloud-config document, run by new load balancer instances when they boot. It is rendered with the {{.Name}}, {{.Replica}}, {{.ServiceName}}, {{.Namespace}} and {{.PortMappings}} of the load balancer.")
+	flag.StringVar(&managerID, "manager-id", triton.DefaultManagerID,
+		"Value of the managed-by tag identifying load balancers owned by this controller.")
+	flag.StringVar(&clusterName, "cluster-name", "",
+		"Name of this Kubernetes cluster, recorded in the cluster tag; must be unique among clusters sharing a Triton account.")
+	flag.BoolVar(&migrateTags, "migrate-tags", false,
+		"At startup, add the cluster tag to load balancers created with this manager ID before --cluster-name was set.")
+	flag.BoolVar(&loadBalancerObjects, "enable-loadbalancer-objects", false,
+		"Record each Service's load balancer in a TritonLoadBalancer object and provision it from there; requires the TritonLoadBalancer CRD.")
+	flag.StringVar(&loadBalancerClass, "load-balancer-class", controller.DefaultLoadBalancerClass,
+		"Only manage LoadBalancer Services whose spec.loadBalancerClass is this class, or that have no class.")
+	flag.BoolVar(&ignoreUnclassed, "ignore-unclassed-services", false,
+		"Leave LoadBalancer Services without a spec.loadBalancerClass to another load balancer implementation.")
+	flag.BoolVar(&manageFirewall, "manage-firewall", false,
+		"Enable Cloud Firewall on load balancer instances and manage rules allowing inbound traffic to their listen ports.")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false,
+		"Serve a validating admission webhook that rejects Services with invalid load balancer annotations; requires the ValidatingWebhookConfiguration in config/webhook.yaml.")
+	flag.IntVar(&webhookPort, "webhook-port", 9443, "The port the admission webhook server listens on.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"Directory holding the tls.crt and tls.key of the admission webhook server (default <temp dir>/k8s-webhook-server/serving-certs).")
+	flag.BoolVar(&enableOrphanGC, "enable-orphan-gc", false,
+		"Periodically delete managed load balancers that no longer have a Service.")
+	flag.BoolVar(&orphanGCDryRun, "orphan-gc-dry-run", true,
+		"Only log and record events for orphaned load balancers instead of deleting them.")
+	flag.DurationVar(&orphanGCInterval, "orphan-gc-interval", controller.DefaultOrphanGCInterval,
+		"How often to look for orphaned load balancers.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Only log and record events for the load balancer creates, updates and deletes the controller would perform, without changing anything in Triton or on Services.")
+	flag.StringVar(&dnsOpts.Provider, "dns-provider", "",
+		"Register load balancer addresses under the dns-name annotation of their Services: cns for Triton CNS services, webhook for an HTTP DNS service; empty disables DNS registration.")
+	flag.StringVar(&dnsOpts.WebhookURL, "dns-webhook-url", "", "Base URL of the DNS service used by --dns-provider=webhook.")
+	flag.StringVar(&dnsOpts.TokenFile, "dns-webhook-token-file", "",
+		"File holding a bearer token sent to the DNS service of --dns-provider=webhook.")
+	flag.IntVar(&dnsOpts.TTL, "dns-ttl", 0, "TTL in seconds of records registered with --dns-provider=webhook; 0 leaves it to the DNS service.")
 	flag.Parse()
 
-	// Validate required flags
-	if tritonKeyPath == "" || tritonKeyId == "" || tritonAccount == "" || tritonUrl == "" {
-		setupLog.Error(nil, "Missing required Triton credentials",
-			"keyPath", tritonKeyPath != "",
-			"keyId", tritonKeyId != "",
-			"account", tritonAccount != "",
-			"url", tritonUrl != "")
+	var config *ControllerConfig
+	var configData []byte
+	var explicitFlags map[string]bool
+	if configPath != "" {
+		var err error
+		if config, configData, err = loadConfig(configPath); err == nil {
+			explicitFlags, err = applyConfig(flag.CommandLine, config)
+		}
+		if err != nil {
+			setupLog.Error(err, "Invalid config file")
+			os.Exit(1)
+		}
+	}
+
+	// Validate required flags, falling back to the standard Triton environment
+	creds.applyEnvFallbacks()
+	datacenters, err := loadDatacenters(datacentersConfigPath)
+	if err != nil {
+		setupLog.Error(err, "Invalid Triton configuration")
+		os.Exit(1)
+	}
+	if len(datacenters) > 0 {
+		creds.URL = datacenters[0].URL
+	}
+	if err := creds.loadPassphrase(); err != nil {
+		setupLog.Error(err, "Invalid Triton configuration")
+		os.Exit(1)
+	}
+	var secretKey types.NamespacedName
+	if credentialsSecret != "" {
+		namespace, name, ok := strings.Cut(credentialsSecret, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(nil, "Invalid --triton-credentials-secret, expected namespace/name", "secret", credentialsSecret)
+			os.Exit(1)
+		}
+		secretKey = types.NamespacedName{Namespace: namespace, Name: name}
+	} else if err := creds.validate(); err != nil {
+		setupLog.Error(err, "Invalid Triton configuration")
+		os.Exit(1)
+	}
+
+	if renewDeadline >= leaseDuration || retryPeriod >= renewDeadline {
+		setupLog.Error(nil, "Invalid leader election timings: require retry period < renew deadline < lease duration",
+			"leaseDuration", leaseDuration.String(),
+			"renewDeadline", renewDeadline.String(),
+			"retryPeriod", retryPeriod.String())
+		os.Exit(1)
+	}
+
+	if err := shard.Validate(); err != nil {
+		setupLog.Error(err, "Invalid sharding")
+		os.Exit(1)
+	}
+	// Replicas of a shard stand by for each other, not for other shards
+	leaderElectionID := managerID
+	if shard.Count > 1 {
+		leaderElectionID = managerID + "-shard-" + strconv.Itoa(shard.Index)
+	}
+
+	if errs := validation.IsDNS1123Subdomain(annotationPrefix); len(errs) > 0 {
+		setupLog.Error(nil, "Invalid annotation prefix", "prefix", annotationPrefix, "errors", errs)
+		os.Exit(1)
+	}
+
+	nameTemplate, err := controller.ParseInstanceNameTemplate(instanceNameTemplate)
+	if err != nil {
+		setupLog.Error(err, "Invalid instance name template")
 		os.Exit(1)
 	}
+	if strings.Contains(instanceNameTemplate, ".ClusterID") && clusterName == "" {
+		setupLog.Error(nil, "The instance name template uses {{.ClusterID}}, which requires --cluster-name")
+		os.Exit(1)
+	}
+
+	var userScript *template.Template
+	if userScriptFile != "" {
+		text, err := os.ReadFile(userScriptFile)
+		if err != nil {
+			setupLog.Error(err, "Unable to read user-script template", "path", userScriptFile)
+			os.Exit(1)
+		}
+		if userScript, err = triton.ParseUserScriptTemplate(string(text)); err != nil {
+			setupLog.Error(err, "Invalid user-script template", "path", userScriptFile)
+			os.Exit(1)
+		}
+	}
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(false)))
 
 	// Create manager - use simple version for now
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "triton-loadbalancer-controller",
+		Scheme:                  scheme,
+		Metrics:                 metricsserver.Options{BindAddress: metricsAddr},
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        leaderElectionID,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaseDuration:           &leaseDuration,
+		RenewDeadline:           &renewDeadline,
+		RetryPeriod:             &retryPeriod,
+		WebhookServer:           webhook.NewServer(webhook.Options{Port: webhookPort, CertDir: webhookCertDir}),
+		// The process exits once the manager stops, so hand the lease over
+		// right away instead of making the next leader wait it out
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -68,39 +332,201 @@ func main() {
 
 	// Initialize Triton client
 	setupLog.Info("Initializing Triton client",
-		"account", tritonAccount,
-		"keyId", tritonKeyId,
-		"keyPath", tritonKeyPath,
-		"url", tritonUrl)
+		"account", creds.Account,
+		"keyId", creds.KeyID,
+		"keyPath", creds.KeyPath,
+		"sshAgent", creds.SSHAgent,
+		"url", creds.URL,
+		"credentialsSecret", credentialsSecret,
+		"managerID", managerID,
+		"shard", shard.String(),
+		"clusterName", clusterName)
 
-	// Check for optional environment variables
-	if pkg := os.Getenv("TRITON_LB_PACKAGE"); pkg != "" {
-		setupLog.Info("Using custom load balancer package", "package", pkg)
+	// Initialize client
+	clientOpts := []triton.ClientOption{
+		triton.WithManagerID(managerID), triton.WithClusterName(clusterName),
+		triton.WithAPITimeout(tritonAPITimeout), triton.WithReloadKeys(splitList(reloadKeys)),
+		triton.WithAsyncProvisioning(asyncProvisioning), triton.WithFirewallManagement(manageFirewall),
+		triton.WithRateLimit(tritonAPIRPS, tritonAPIBurst), triton.WithRetries(tritonAPIRetries),
+		triton.WithLookupCache(tritonCacheTTL), triton.WithDefaults(lbDefaults),
 	}
-
-	if img := os.Getenv("TRITON_LB_IMAGE"); img != "" {
-		setupLog.Info("Using custom load balancer image", "image", img)
+	if userScript != nil {
+		clientOpts = append(clientOpts, triton.WithUserScript(userScript))
+	}
+	var tritonClient *triton.Client
+	var secretCreds triton.Credentials
+	if credentialsSecret != "" {
+		// The manager's cache isn't running yet, so read the Secret directly
+		secret := &corev1.Secret{}
+		if err := mgr.GetAPIReader().Get(context.Background(), secretKey, secret); err != nil {
+			setupLog.Error(err, "unable to read Triton credentials secret", "secret", secretKey)
+			os.Exit(1)
+		}
+		secretCreds, err = controller.CredentialsFromSecret(secret, creds.defaults())
+		if err != nil {
+			setupLog.Error(err, "Invalid Triton configuration")
+			os.Exit(1)
+		}
+		tritonClient, err = triton.NewMultiDatacenterClient(secretCreds, datacenters, clientOpts...)
+	} else {
+		var fileCreds triton.Credentials
+		if fileCreds, err = creds.credentials(); err == nil {
+			tritonClient, err = triton.NewMultiDatacenterClient(fileCreds, datacenters, clientOpts...)
+		}
 	}
-
-	// Initialize client
-	tritonClient, err := triton.NewClient(tritonAccount, tritonKeyId, tritonKeyPath, tritonUrl)
 	if err != nil {
 		setupLog.Error(err, "unable to create Triton client")
 		os.Exit(1)
 	}
 
-	setupLog.Info("Triton client initialized successfully")
+	setupLog.Info("Triton client initialized successfully", "datacenters", tritonClient.Datacenters())
+	if lbDefaults.Package != "" || lbDefaults.Image != "" {
+		setupLog.Info("Using custom load balancer defaults", "package", lbDefaults.Package, "image", lbDefaults.Image)
+	}
+
+	if dryRun {
+		// Nothing below may change Triton or the objects of the cluster
+		setupLog.Info("Running in dry-run mode, no load balancers will be changed")
+		orphanGCDryRun = true
+		if migrateTags {
+			setupLog.Info("WARNING: --migrate-tags is skipped in dry-run mode")
+			migrateTags = false
+		}
+		if loadBalancerObjects {
+			setupLog.Info("WARNING: TritonLoadBalancer objects are not reconciled in dry-run mode")
+			loadBalancerObjects = false
+		}
+	}
+
+	if migrateTags {
+		migrated, err := tritonClient.MigrateTags(context.Background())
+		for _, instance := range migrated {
+			setupLog.Info("Migrated load balancer tags", "name", instance.Name, "instance", instance.ID, "clusterName", clusterName)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to migrate load balancer tags")
+			os.Exit(1)
+		}
+		if clusterName == "" {
+			setupLog.Info("WARNING: --migrate-tags has nothing to do without --cluster-name")
+		}
+	}
 
-	if err = controller.NewLoadBalancerReconciler(
+	if err := tritonClient.NetworkError(); err != nil {
+		setupLog.Info("WARNING: Triton network API unavailable, network-dependent features are disabled",
+			"error", err.Error())
+	}
+
+	reconciler := controller.NewLoadBalancerReconciler(
 		mgr.GetClient(),
 		ctrl.Log.WithName("controllers").WithName("LoadBalancer"),
 		mgr.GetScheme(),
 		tritonClient,
-	).SetupWithManager(mgr); err != nil {
+		mgr.GetEventRecorderFor("triton-loadbalancer-controller"),
+	)
+	reconciler.FinalizerName = finalizerName
+	reconciler.TagLabelPrefix = tagLabelPrefix
+	reconciler.PropagateLabels = splitList(propagateLabels)
+	reconciler.DefaultCertificateName = defaultCertificateName
+	reconciler.ReconcileTimeout = reconcileTimeout
+	reconciler.AutoRecreateFailed = autoRecreateFailed
+	reconciler.ResyncPeriod = resyncPeriod
+	reconciler.PollInterval = pollInterval
+	reconciler.MaxRetryBackoff = maxRetryBackoff
+	reconciler.VerifyListener = verifyListener
+	reconciler.ListenerTimeout = listenerTimeout
+	reconciler.DrainTimeout = drainTimeout
+	reconciler.Shard = shard
+	reconciler.PublishHostname = publishHostname
+	reconciler.InstanceNameTemplate = nameTemplate
+	reconciler.ClusterID = clusterName
+	reconciler.AnnotationPrefix = annotationPrefix
+	reconciler.DefaultMetricsACL = splitList(defaultMetricsACL)
+	reconciler.UseLoadBalancerObjects = loadBalancerObjects
+	reconciler.LoadBalancerClass = loadBalancerClass
+	reconciler.IgnoreUnclassed = ignoreUnclassed
+	reconciler.ManageFirewall = manageFirewall
+	reconciler.ConcurrentReconciles = concurrentReconciles
+	reconciler.DryRun = dryRun
+	if reconciler.DNS, err = dnsOpts.provider(tritonClient); err != nil {
+		setupLog.Error(err, "Invalid DNS configuration")
+		os.Exit(1)
+	}
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LoadBalancer")
 		os.Exit(1)
 	}
 
+	if loadBalancerObjects {
+		if err := (&controller.TritonLoadBalancerReconciler{
+			Client:               mgr.GetClient(),
+			Log:                  ctrl.Log.WithName("controllers").WithName("TritonLoadBalancer"),
+			TritonClient:         tritonClient,
+			Recorder:             mgr.GetEventRecorderFor("triton-loadbalancer-controller"),
+			FinalizerName:        finalizerName,
+			PollInterval:         pollInterval,
+			ConcurrentReconciles: concurrentReconciles,
+			Shard:                shard,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "TritonLoadBalancer")
+			os.Exit(1)
+		}
+	}
+
+	if credentialsSecret != "" {
+		if err := (&controller.CredentialsReconciler{
+			Client:       mgr.GetClient(),
+			Log:          ctrl.Log.WithName("controllers").WithName("Credentials"),
+			TritonClient: tritonClient,
+			SecretKey:    secretKey,
+			Defaults:     creds.defaults(),
+			Current:      secretCreds,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Credentials")
+			os.Exit(1)
+		}
+	}
+
+	if enableWebhook {
+		if err := (&lbwebhook.ServiceWebhook{
+			Validator: reconciler,
+			Catalog:   tritonClient,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Service")
+			os.Exit(1)
+		}
+	}
+
+	if enableOrphanGC {
+		if err := mgr.Add(&controller.OrphanCollector{
+			Client:       mgr.GetClient(),
+			TritonClient: tritonClient,
+			Log:          ctrl.Log.WithName("controllers").WithName("OrphanGC"),
+			Recorder:     mgr.GetEventRecorderFor("triton-loadbalancer-controller"),
+			Interval:     orphanGCInterval,
+			DryRun:       orphanGCDryRun,
+			Shard:        shard,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up orphan collector")
+			os.Exit(1)
+		}
+	}
+
+	if config != nil {
+		if err := mgr.Add(&configWatcher{
+			path:     configPath,
+			client:   tritonClient,
+			log:      ctrl.Log.WithName("config"),
+			flags:    lbDefaults,
+			explicit: explicitFlags,
+			data:     configData,
+			config:   config,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up config reload")
+			os.Exit(1)
+		}
+	}
+
 	// Add health checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -117,3 +543,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
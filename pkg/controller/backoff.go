@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// DefaultMaxRetryBackoff caps the retry interval of a Degraded Service, and
+// of reconciles that fail, when no maximum is configured
+const DefaultMaxRetryBackoff = 5 * time.Minute
+
+// retryBackoffBase is how soon a Service is retried after its first
+// transient failure; every further failure in a row doubles it
+const retryBackoffBase = 5 * time.Second
+
+// errorBackoffBase is the first retry interval of a reconcile that returns an
+// error, as in the default controller-runtime rate limiter
+const errorBackoffBase = 5 * time.Millisecond
+
+// retryBackoff spaces the retries of each Degraded Service exponentially,
+// so a broken CloudAPI isn't polled every few seconds while blips still
+// converge quickly. The zero value is ready to use.
+type retryBackoff struct {
+	mu      sync.Mutex
+	limiter workqueue.RateLimiter
+}
+
+// next returns how long key waits before its next retry, counting one more
+// failure of key
+func (b *retryBackoff) next(key types.NamespacedName, max time.Duration) time.Duration {
+	b.mu.Lock()
+	if b.limiter == nil {
+		b.limiter = workqueue.NewItemExponentialFailureRateLimiter(min(retryBackoffBase, max), max)
+	}
+	limiter := b.limiter
+	b.mu.Unlock()
+	return limiter.When(key)
+}
+
+// reset forgets the failures of key
+func (b *retryBackoff) reset(key types.NamespacedName) {
+	b.mu.Lock()
+	limiter := b.limiter
+	b.mu.Unlock()
+	if limiter != nil {
+		limiter.Forget(key)
+	}
+}
+
+// maxRetryBackoff returns MaxRetryBackoff, or DefaultMaxRetryBackoff if it
+// isn't set
+func (r *LoadBalancerReconciler) maxRetryBackoff() time.Duration {
+	if r.MaxRetryBackoff <= 0 {
+		return DefaultMaxRetryBackoff
+	}
+	return r.MaxRetryBackoff
+}
+
+// retryAfter returns how long the Degraded service waits before it is
+// reconciled again
+func (r *LoadBalancerReconciler) retryAfter(service *corev1.Service) time.Duration {
+	return r.retryBackoff.next(types.NamespacedName{Namespace: service.Namespace, Name: service.Name}, r.maxRetryBackoff())
+}
+
+// resetRetries restarts the backoff of service once it reconciles
+// successfully
+func (r *LoadBalancerReconciler) resetRetries(service *corev1.Service) {
+	r.retryBackoff.reset(types.NamespacedName{Namespace: service.Namespace, Name: service.Name})
+}
+
+// errorRateLimiter is the workqueue rate limiter of reconciles that return
+// an error: the default controller-runtime one, with its per-item backoff
+// capped at max
+func errorRateLimiter(max time.Duration) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(errorBackoffBase, max),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
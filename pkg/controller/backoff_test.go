@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	r := &LoadBalancerReconciler{MaxRetryBackoff: time.Minute}
+	a := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}}
+	b := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "other"}}
+
+	for _, want := range []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		if got := r.retryAfter(a); got != want {
+			t.Errorf("expected a retry after %v, got %v", want, got)
+		}
+	}
+
+	// Each Service backs off on its own
+	if got := r.retryAfter(b); got != 5*time.Second {
+		t.Errorf("expected the first retry of another Service after 5s, got %v", got)
+	}
+
+	r.resetRetries(a)
+	if got := r.retryAfter(a); got != 5*time.Second {
+		t.Errorf("expected the backoff to start over after a reset, got %v", got)
+	}
+	if got := r.retryAfter(b); got != 10*time.Second {
+		t.Errorf("expected the reset to leave other Services alone, got %v", got)
+	}
+}
+
+func TestRetryBackoffDefault(t *testing.T) {
+	r := &LoadBalancerReconciler{}
+	if got := r.maxRetryBackoff(); got != DefaultMaxRetryBackoff {
+		t.Errorf("expected the default maximum %v, got %v", DefaultMaxRetryBackoff, got)
+	}
+
+	// A maximum below the first interval caps that one too
+	r = &LoadBalancerReconciler{MaxRetryBackoff: time.Second}
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}}
+	if got := r.retryAfter(service); got != time.Second {
+		t.Errorf("expected a retry after 1s, got %v", got)
+	}
+}
+
+func TestErrorRateLimiter(t *testing.T) {
+	limiter := errorRateLimiter(time.Second)
+	var last time.Duration
+	for i := 0; i < 20; i++ {
+		last = limiter.When("item")
+	}
+	if last != time.Second {
+		t.Errorf("expected the backoff to be capped at 1s, got %v", last)
+	}
+	limiter.Forget("item")
+	if got := limiter.When("item"); got != errorBackoffBase {
+		t.Errorf("expected %v once forgotten, got %v", errorBackoffBase, got)
+	}
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// certificateSecretAnnotation names a kubernetes.io/tls Secret, as "name" or
+// "namespace/name", whose certificate is installed on the load balancer.
+// certificateSecretAliasAnnotation is the same, spelled like certificate_name.
+const (
+	certificateSecretAnnotation      = "cloud.tritoncompute/certificate-secret"
+	certificateSecretAliasAnnotation = "cloud.tritoncompute/certificate_secret"
+)
+
+// certificateSecretKey returns the Secret referenced by the Service's
+// certificate-secret annotation. Secrets may only be referenced from the
+// Service's own namespace so that a Service cannot expose another
+// namespace's private key.
+func (r *LoadBalancerReconciler) certificateSecretKey(service *corev1.Service) (types.NamespacedName, bool, error) {
+	annotation := r.annotation(certificateSecretAnnotation)
+	ref := service.Annotations[annotation]
+	alias := r.annotation(certificateSecretAliasAnnotation)
+	if aliasRef := service.Annotations[alias]; aliasRef != "" {
+		if ref != "" && ref != aliasRef {
+			return types.NamespacedName{}, false, fmt.Errorf("%s %q and %s %q name different Secrets", annotation, ref, alias, aliasRef)
+		}
+		annotation, ref = alias, aliasRef
+	}
+	if ref == "" {
+		return types.NamespacedName{}, false, nil
+	}
+
+	key := types.NamespacedName{Namespace: service.Namespace, Name: ref}
+	if namespace, name, found := strings.Cut(ref, "/"); found {
+		key = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	if key.Name == "" {
+		return key, false, fmt.Errorf("invalid %s annotation %q", annotation, ref)
+	}
+	if key.Namespace != service.Namespace {
+		return key, false, fmt.Errorf("%s %q must be in the Service's namespace %q",
+			annotation, ref, service.Namespace)
+	}
+	return key, true, nil
+}
+
+// resolveCertificateSecret loads the TLS Secret referenced by the Service, if
+// any, and sets the certificate on params. CertificateName is replaced with the
+// certificate's subject names; without the annotation the certificate_name
+// annotation is used as before.
+func (r *LoadBalancerReconciler) resolveCertificateSecret(ctx context.Context, service *corev1.Service, params *triton.LoadBalancerParams) error {
+	key, ok, err := r.certificateSecretKey(service)
+	if err != nil || !ok {
+		return err
+	}
+	return loadCertificateSecret(ctx, r, key, params)
+}
+
+// loadCertificateSecret reads the kubernetes.io/tls Secret key and sets its
+// certificate, key and subject names on params
+func loadCertificateSecret(ctx context.Context, c client.Reader, key types.NamespacedName, params *triton.LoadBalancerParams) error {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return fmt.Errorf("failed to get certificate secret %s: %w", key, err)
+	}
+
+	certPEM := secret.Data[corev1.TLSCertKey]
+	keyPEM := secret.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return fmt.Errorf("certificate secret %s must contain %s and %s", key, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("certificate secret %s is not a valid TLS key pair: %w", key, err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("certificate secret %s: %w", key, err)
+	}
+
+	names := leaf.DNSNames
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = []string{leaf.Subject.CommonName}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("certificate in secret %s has no subject names", key)
+	}
+
+	params.CertificateName = strings.Join(names, ",")
+	params.Certificate = string(certPEM)
+	params.CertificateKey = string(keyPEM)
+	return nil
+}
+
+// servicesForSecret maps a Secret to the Services that reference it through
+// the certificate-secret annotation, so certificate rotation is picked up
+func (r *LoadBalancerReconciler) servicesForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list services for certificate secret", "secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range services.Items {
+		service := &services.Items[i]
+		if !r.managesService(service) {
+			continue
+		}
+		key, ok, err := r.certificateSecretKey(service)
+		if err != nil || !ok || key != client.ObjectKeyFromObject(obj) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(service)})
+	}
+	return requests
+}
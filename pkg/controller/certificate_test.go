@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// newTestKeyPair returns a self-signed PEM certificate and key for dnsNames
+func newTestKeyPair(t *testing.T, dnsNames ...string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func newCertTestReconciler(t *testing.T, objs ...*corev1.Secret) *LoadBalancerReconciler {
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, obj := range objs {
+		builder = builder.WithObjects(obj)
+	}
+	return &LoadBalancerReconciler{
+		Client: builder.Build(),
+		Log:    testr.New(t),
+	}
+}
+
+func TestResolveCertificateSecret(t *testing.T) {
+	certPEM, keyPEM := newTestKeyPair(t, "example.com", "www.example.com")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		},
+	}
+	reconciler := newCertTestReconciler(t, secret)
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Annotations: map[string]string{
+				certificateSecretAnnotation:            "default/web-tls",
+				"cloud.tritoncompute/certificate_name": "ignored.example.com",
+			},
+		},
+	}
+	params := triton.LoadBalancerParams{CertificateName: "ignored.example.com"}
+
+	if err := reconciler.resolveCertificateSecret(context.Background(), service, &params); err != nil {
+		t.Fatalf("resolveCertificateSecret: %v", err)
+	}
+	if params.CertificateName != "example.com,www.example.com" {
+		t.Errorf("expected certificate name from the secret, got %q", params.CertificateName)
+	}
+	if params.Certificate != string(certPEM) || params.CertificateKey != string(keyPEM) {
+		t.Error("expected certificate and key to be copied from the secret")
+	}
+}
+
+func TestCertificateSecretKeyAlias(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{Log: testr.New(t)}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{certificateSecretAliasAnnotation: "web-tls"},
+		},
+	}
+
+	key, ok, err := reconciler.certificateSecretKey(service)
+	if err != nil || !ok || key != (types.NamespacedName{Namespace: "default", Name: "web-tls"}) {
+		t.Errorf("expected the alias to reference default/web-tls, got %v (ok %v, err %v)", key, ok, err)
+	}
+
+	service.Annotations[certificateSecretAnnotation] = "web-tls"
+	if _, ok, err := reconciler.certificateSecretKey(service); err != nil || !ok {
+		t.Errorf("expected matching annotations to be accepted, got ok %v, err %v", ok, err)
+	}
+
+	service.Annotations[certificateSecretAnnotation] = "api-tls"
+	if _, _, err := reconciler.certificateSecretKey(service); err == nil {
+		t.Error("expected an error when the annotations name different Secrets")
+	}
+}
+
+func TestResolveCertificateSecretFallsBack(t *testing.T) {
+	reconciler := newCertTestReconciler(t)
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+	}
+	params := triton.LoadBalancerParams{CertificateName: "example.com"}
+
+	if err := reconciler.resolveCertificateSecret(context.Background(), service, &params); err != nil {
+		t.Fatalf("resolveCertificateSecret: %v", err)
+	}
+	if params.CertificateName != "example.com" || params.Certificate != "" {
+		t.Errorf("expected certificate_name to be used unchanged, got %+v", params)
+	}
+}
+
+func TestResolveCertificateSecretErrors(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "default"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("not a certificate"),
+			corev1.TLSPrivateKeyKey: []byte("not a key"),
+		},
+	}
+	reconciler := newCertTestReconciler(t, secret)
+
+	for _, ref := range []string{"missing", "broken", "kube-system/web-tls"} {
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "web",
+				Namespace:   "default",
+				Annotations: map[string]string{certificateSecretAnnotation: ref},
+			},
+		}
+		var params triton.LoadBalancerParams
+		if err := reconciler.resolveCertificateSecret(context.Background(), service, &params); err == nil {
+			t.Errorf("expected an error for secret reference %q", ref)
+		}
+	}
+}
+
+func TestServicesForSecret(t *testing.T) {
+	referencing := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{certificateSecretAnnotation: "web-tls"},
+		},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+	other := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+	reconciler := &LoadBalancerReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(referencing, other).Build(),
+		Log:    testr.New(t),
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "default"}}
+	requests := reconciler.servicesForSecret(context.Background(), secret)
+	if len(requests) != 1 || requests[0].Name != "web" {
+		t.Errorf("expected only the referencing service to be enqueued, got %v", requests)
+	}
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// Keys of the Triton credentials Secret, matching the triton-credentials
+// Secret in config/controller.yaml
+const (
+	CredentialsAccountKey    = "triton-account"
+	CredentialsKeyIDKey      = "triton-key-id"
+	CredentialsPrivateKeyKey = "triton-key"
+	CredentialsPassphraseKey = "triton-key-passphrase"
+	CredentialsURLKey        = "triton-url"
+)
+
+// CredentialsSetter re-initializes a Triton client with new credentials
+type CredentialsSetter interface {
+	SetCredentials(ctx context.Context, creds triton.Credentials) error
+}
+
+// CredentialsFromSecret reads Triton credentials from secret. The private key
+// must be in the Secret; the account, key ID, URL and key passphrase fall back
+// to defaults.
+func CredentialsFromSecret(secret *corev1.Secret, defaults triton.Credentials) (triton.Credentials, error) {
+	creds := defaults
+	for key, value := range map[string]*string{
+		CredentialsAccountKey:    &creds.Account,
+		CredentialsKeyIDKey:      &creds.KeyID,
+		CredentialsURLKey:        &creds.URL,
+		CredentialsPassphraseKey: &creds.Passphrase,
+	} {
+		if data := secret.Data[key]; len(data) > 0 {
+			*value = string(data)
+		}
+	}
+	creds.PrivateKey = secret.Data[CredentialsPrivateKeyKey]
+
+	var missing []string
+	for _, setting := range []struct {
+		key string
+		ok  bool
+	}{
+		{CredentialsAccountKey, creds.Account != ""},
+		{CredentialsKeyIDKey, creds.KeyID != ""},
+		{CredentialsPrivateKeyKey, len(creds.PrivateKey) > 0},
+		{CredentialsURLKey, creds.URL != ""},
+	} {
+		if !setting.ok {
+			missing = append(missing, setting.key)
+		}
+	}
+	if len(missing) > 0 {
+		return creds, fmt.Errorf("credentials secret %s/%s is missing %s", secret.Namespace, secret.Name, strings.Join(missing, ", "))
+	}
+	return creds, nil
+}
+
+// CredentialsReconciler watches the Triton credentials Secret and
+// re-initializes the Triton client when it changes, so keys can be rotated
+// without restarting the controller
+type CredentialsReconciler struct {
+	client.Client
+	Log          logr.Logger
+	TritonClient CredentialsSetter
+
+	// SecretKey is the Secret holding the credentials
+	SecretKey types.NamespacedName
+
+	// Defaults fill the settings the Secret doesn't contain
+	Defaults triton.Credentials
+
+	// Current are the credentials the client uses; the Secret is only applied
+	// when it differs
+	Current triton.Credentials
+}
+
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+
+// Reconcile applies the credentials in the Secret to the Triton client
+func (r *CredentialsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("secret", req.NamespacedName)
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, req.NamespacedName, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Triton credentials secret not found, keeping the current credentials")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get credentials secret: %w", err)
+	}
+
+	creds, err := CredentialsFromSecret(secret, r.Defaults)
+	if err != nil {
+		// Wait for the Secret to be fixed rather than retrying
+		log.Error(err, "Invalid Triton credentials secret, keeping the current credentials")
+		return ctrl.Result{}, nil
+	}
+	if creds.Equal(r.Current) {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.TritonClient.SetCredentials(ctx, creds); err != nil {
+		log.Error(err, "Failed to apply new Triton credentials, keeping the current credentials")
+		return ctrl.Result{}, err
+	}
+	r.Current = creds
+	log.Info("Reloaded Triton credentials", "account", creds.Account, "keyId", creds.KeyID, "url", creds.URL)
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager watches only the credentials Secret
+func (r *CredentialsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("triton-credentials").
+		For(&corev1.Secret{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return client.ObjectKeyFromObject(obj) == r.SecretKey
+		}))).
+		Complete(r)
+}
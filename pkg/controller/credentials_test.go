@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// fakeCredentialsSetter records the credentials applied to it
+type fakeCredentialsSetter struct {
+	applied []triton.Credentials
+	err     error
+}
+
+func (f *fakeCredentialsSetter) SetCredentials(ctx context.Context, creds triton.Credentials) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.applied = append(f.applied, creds)
+	return nil
+}
+
+func credentialsSecret(key string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "triton-credentials", Namespace: "triton-system"},
+		Data: map[string][]byte{
+			CredentialsKeyIDKey:      []byte("aa:bb"),
+			CredentialsPrivateKeyKey: []byte(key),
+		},
+	}
+}
+
+func TestCredentialsFromSecret(t *testing.T) {
+	defaults := triton.Credentials{Account: "flag-account", KeyID: "flag-key", URL: "https://flag.example.com"}
+
+	creds, err := CredentialsFromSecret(credentialsSecret("pem"), defaults)
+	if err != nil {
+		t.Fatalf("CredentialsFromSecret: %v", err)
+	}
+	want := triton.Credentials{Account: "flag-account", KeyID: "aa:bb", PrivateKey: []byte("pem"), URL: "https://flag.example.com"}
+	if !creds.Equal(want) {
+		t.Errorf("expected Secret values to override the defaults, got %+v", creds)
+	}
+
+	secret := credentialsSecret("pem")
+	secret.Data[CredentialsPassphraseKey] = []byte("secret")
+	creds, err = CredentialsFromSecret(secret, defaults)
+	if err != nil {
+		t.Fatalf("CredentialsFromSecret: %v", err)
+	}
+	if creds.Passphrase != "secret" {
+		t.Errorf("expected the key passphrase from the Secret, got %q", creds.Passphrase)
+	}
+
+	if _, err := CredentialsFromSecret(credentialsSecret(""), defaults); err == nil {
+		t.Error("expected an error for a Secret without a private key")
+	}
+	if _, err := CredentialsFromSecret(credentialsSecret("pem"), triton.Credentials{}); err == nil {
+		t.Error("expected an error when the account and URL are set nowhere")
+	}
+}
+
+func TestCredentialsReconcilerRotation(t *testing.T) {
+	secret := credentialsSecret("old-key")
+	s := scheme.Scheme
+	client := fake.NewClientBuilder().WithScheme(s).WithObjects(secret).Build()
+
+	setter := &fakeCredentialsSetter{}
+	defaults := triton.Credentials{Account: "acct", URL: "https://cloudapi.example.com"}
+	current, err := CredentialsFromSecret(secret, defaults)
+	if err != nil {
+		t.Fatalf("CredentialsFromSecret: %v", err)
+	}
+	r := &CredentialsReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		TritonClient: setter,
+		SecretKey:    types.NamespacedName{Namespace: "triton-system", Name: "triton-credentials"},
+		Defaults:     defaults,
+		Current:      current,
+	}
+	req := ctrl.Request{NamespacedName: r.SecretKey}
+	ctx := context.Background()
+
+	// The credentials the client started with aren't applied again
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(setter.applied) != 0 {
+		t.Fatalf("expected unchanged credentials not to be reapplied, got %d", len(setter.applied))
+	}
+
+	secret.Data[CredentialsPrivateKeyKey] = []byte("new-key")
+	if err := client.Update(ctx, secret); err != nil {
+		t.Fatalf("update secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(setter.applied) != 1 || string(setter.applied[0].PrivateKey) != "new-key" {
+		t.Fatalf("expected the rotated key to be applied, got %+v", setter.applied)
+	}
+
+	// A rejected key is retried and the last working one stays current
+	setter.err = errors.New("invalid key")
+	secret.Data[CredentialsPrivateKeyKey] = []byte("bad-key")
+	if err := client.Update(ctx, secret); err != nil {
+		t.Fatalf("update secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Error("expected the failed update to be returned for a retry")
+	}
+	if string(r.Current.PrivateKey) != "new-key" {
+		t.Errorf("expected the current credentials to be kept, got %q", r.Current.PrivateKey)
+	}
+
+	// A deleted Secret leaves the client alone
+	if err := client.Delete(ctx, secret); err != nil {
+		t.Fatalf("delete secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Errorf("expected a missing Secret to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/triton/loadbalancer-controller/pkg/dns"
+)
+
+const (
+	// dnsNameAnnotation is the DNS name the load balancer addresses are
+	// registered under when a DNS provider is configured
+	dnsNameAnnotation = "cloud.tritoncompute/dns-name"
+	// dnsRecordAnnotation records the name that was registered, so it is
+	// deleted when the Service changes its name or goes away
+	dnsRecordAnnotation = "cloud.tritoncompute/dns-record"
+)
+
+// syncDNSRecord registers the published addresses of the load balancer name
+// under the Service's DNS name, deleting a previously registered name first
+func (r *LoadBalancerReconciler) syncDNSRecord(ctx context.Context, service *corev1.Service, name, instanceID string, ips []string) error {
+	if r.DNS == nil {
+		return nil
+	}
+
+	desired := strings.TrimSpace(service.Annotations[r.annotation(dnsNameAnnotation)])
+	recorded := service.Annotations[r.annotation(dnsRecordAnnotation)]
+	if recorded != "" && recorded != desired {
+		if err := r.deleteDNSRecord(ctx, service, name, instanceID); err != nil {
+			return err
+		}
+	}
+	if desired == "" {
+		return nil
+	}
+
+	record := dns.Record{Name: desired, IPs: ips, LoadBalancer: name, InstanceID: instanceID}
+	if err := r.DNS.Ensure(ctx, record); err != nil {
+		r.recordEvent(service, corev1.EventTypeWarning, "DNSRegistrationFailed", err.Error())
+		return fmt.Errorf("failed to register DNS name %s: %w", desired, err)
+	}
+	if recorded != desired {
+		r.loggerFor(ctx, service).Info("Registered DNS name", "dnsName", desired, "ips", ips)
+		r.recordEvent(service, corev1.EventTypeNormal, "DNSRegistered",
+			fmt.Sprintf("registered %s for %s", desired, strings.Join(ips, ",")))
+		r.setDNSRecordAnnotation(ctx, service, desired)
+	}
+	return nil
+}
+
+// deleteDNSRecord deletes the DNS name registered for the load balancer name,
+// if there is one
+func (r *LoadBalancerReconciler) deleteDNSRecord(ctx context.Context, service *corev1.Service, name, instanceID string) error {
+	recorded := service.Annotations[r.annotation(dnsRecordAnnotation)]
+	if r.DNS == nil || recorded == "" {
+		return nil
+	}
+
+	record := dns.Record{Name: recorded, LoadBalancer: name, InstanceID: instanceID}
+	if err := r.DNS.Delete(ctx, record); err != nil {
+		r.recordEvent(service, corev1.EventTypeWarning, "DNSDeregistrationFailed", err.Error())
+		return fmt.Errorf("failed to delete DNS name %s: %w", recorded, err)
+	}
+	r.loggerFor(ctx, service).Info("Deleted DNS name", "dnsName", recorded)
+	r.recordEvent(service, corev1.EventTypeNormal, "DNSDeregistered", "deleted "+recorded)
+	r.setDNSRecordAnnotation(ctx, service, "")
+	return nil
+}
+
+// setDNSRecordAnnotation records the registered DNS name on the Service, or
+// removes the record when name is empty
+func (r *LoadBalancerReconciler) setDNSRecordAnnotation(ctx context.Context, service *corev1.Service, name string) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if name == "" {
+		delete(service.Annotations, r.annotation(dnsRecordAnnotation))
+	} else {
+		if service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		service.Annotations[r.annotation(dnsRecordAnnotation)] = name
+	}
+
+	if err := r.Patch(ctx, service, patch); err != nil {
+		r.loggerFor(ctx, service).Error(err, "Failed to record DNS name on Service")
+	}
+}
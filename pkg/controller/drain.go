@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// drainTimeoutAnnotation is how many seconds load balancer instances that
+// are deleted or replaced keep serving their established connections
+const drainTimeoutAnnotation = "cloud.tritoncompute/drain-timeout"
+
+// drainTimeout returns the drain timeout of service, or DrainTimeout when it
+// doesn't set one
+func (r *LoadBalancerReconciler) drainTimeout(service *corev1.Service) (time.Duration, error) {
+	value, ok := service.Annotations[r.annotation(drainTimeoutAnnotation)]
+	if !ok {
+		return r.DrainTimeout, nil
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds < 0 {
+		return r.DrainTimeout, fmt.Errorf("invalid %s annotation %q: must be a number of seconds", r.annotation(drainTimeoutAnnotation), value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// drainLoadBalancer stops publishing the load balancer of a Service that is
+// being deleted and drains its instances, returning how much longer they
+// drain. Zero means the load balancer can be deleted.
+func (r *LoadBalancerReconciler) drainLoadBalancer(ctx context.Context, service *corev1.Service, instanceID, name string) (time.Duration, error) {
+	log := r.loggerFor(ctx, service)
+	timeout, err := r.drainTimeout(service)
+	if err != nil {
+		log.Error(err, "Ignoring drain timeout", "drainTimeout", timeout.String())
+	}
+	if timeout <= 0 {
+		return 0, nil
+	}
+
+	// Clients resolving the Service stop being sent to the load balancer
+	// while the connections it already has finish
+	if err := r.clearIngress(ctx, service); err != nil {
+		return 0, err
+	}
+
+	started, err := r.TritonClient.DrainLoadBalancer(ctx, instanceID, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to drain load balancer: %w", err)
+	}
+	if started.IsZero() {
+		// Nothing left to drain
+		return 0, nil
+	}
+
+	remaining := timeout - time.Since(started)
+	if remaining <= 0 {
+		log.Info("Load balancer drained", "name", name)
+		return 0, nil
+	}
+	log.Info("Draining load balancer before deleting it", "name", name, "remaining", remaining.Round(time.Second).String())
+	r.recordEvent(service, corev1.EventTypeNormal, "Draining",
+		fmt.Sprintf("draining load balancer %s for %s before deleting it", name, remaining.Round(time.Second)))
+	return remaining, nil
+}
+
+// clearIngress removes the load balancer addresses from the Service status
+func (r *LoadBalancerReconciler) clearIngress(ctx context.Context, service *corev1.Service) error {
+	if len(service.Status.LoadBalancer.Ingress) == 0 {
+		return nil
+	}
+	updatedService := service.DeepCopy()
+	updatedService.Status.LoadBalancer.Ingress = nil
+	if err := r.Status().Update(ctx, updatedService); err != nil {
+		return fmt.Errorf("failed to clear load balancer IPs from Service status: %w", err)
+	}
+	// Removing the finalizer later in the same reconcile needs the new
+	// resource version
+	service.ResourceVersion = updatedService.ResourceVersion
+	service.Status.LoadBalancer.Ingress = nil
+	r.loggerFor(ctx, service).Info("Removed load balancer IPs from service status")
+	return nil
+}
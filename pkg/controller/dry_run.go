@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// planDryRun works out what a reconcile of service would do in Triton and
+// logs it, recording a DryRun event on the Service, without changing any
+// instance or writing to the Service. It follows the decisions of Reconcile,
+// so a Service that is deleted or no longer managed plans a delete only while
+// it holds the finalizer.
+func (r *LoadBalancerReconciler) planDryRun(ctx context.Context, service *corev1.Service) (ctrl.Result, error) {
+	log := r.loggerFor(ctx, service)
+
+	finalizer := controllerutil.ContainsFinalizer(service, r.finalizerName())
+	deleting := !r.managesService(service) || !service.DeletionTimestamp.IsZero()
+	if deleting && !finalizer {
+		return ctrl.Result{}, nil
+	}
+	if ignored, _ := strconv.ParseBool(service.Annotations[r.annotation(ignoreAnnotation)]); ignored && !deleting {
+		return ctrl.Result{}, nil
+	}
+
+	instanceID := service.Annotations[r.annotation(instanceIDAnnotation)]
+	if deleting {
+		name, err := r.serviceInstanceName(service)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		existingLB, err := r.TritonClient.GetLoadBalancer(ctx, instanceID, name)
+		if err != nil {
+			log.Error(err, "Failed to check if load balancer exists")
+			return ctrl.Result{}, err
+		}
+		if existingLB != nil {
+			r.recordPlan(ctx, service, "delete", name, fmt.Sprintf("%d replica(s)", existingLB.ReplicaCount()))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	endpoints, err := r.serviceEndpoints(ctx, service)
+	if err != nil {
+		log.Error(err, "Failed to list service endpoints")
+		return ctrl.Result{}, err
+	}
+	lbParams, err := r.extractLoadBalancerParamsWithEndpoints(service, endpoints)
+	if err != nil {
+		log.Error(err, "Failed to extract load balancer parameters")
+		r.recordEvent(service, corev1.EventTypeWarning, "InvalidConfiguration", err.Error())
+		return ctrl.Result{}, fmt.Errorf("failed to extract LB params: %w", err)
+	}
+	if err := r.resolveCertificateSecret(ctx, service, &lbParams); err != nil {
+		log.Error(err, "Failed to resolve certificate secret")
+		return ctrl.Result{}, err
+	}
+
+	existingLB, err := r.TritonClient.GetLoadBalancer(ctx, instanceID, lbParams.Name)
+	if err != nil {
+		log.Error(err, "Failed to check if load balancer exists")
+		return ctrl.Result{}, err
+	}
+
+	switch ref := service.Annotations[r.annotation(adoptInstanceAnnotation)]; {
+	case existingLB == nil && ref != "":
+		r.recordPlan(ctx, service, "adopt", lbParams.Name, "instance "+ref)
+	case existingLB == nil:
+		r.recordPlan(ctx, service, "create", lbParams.Name,
+			fmt.Sprintf("%d replica(s), ports %s", lbParams.ReplicaCount(), joinPortGroups(lbParams.PortMappings)))
+	default:
+		if changes := planUpdate(existingLB, lbParams); len(changes) > 0 {
+			r.recordPlan(ctx, service, "update", lbParams.Name, strings.Join(changes, "; "))
+		} else {
+			log.V(1).Info("Load balancer is up to date", "name", lbParams.Name, "dryRun", true)
+		}
+	}
+
+	// Pick up changes of the load balancer as a normal reconcile would
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// recordPlan logs and records the operation a dry run would have performed
+// on the load balancer name
+func (r *LoadBalancerReconciler) recordPlan(ctx context.Context, service *corev1.Service, op, name, details string) {
+	r.loggerFor(ctx, service).Info("Dry run: would "+op+" load balancer", "name", name, "changes", details)
+	r.recordEvent(service, corev1.EventTypeNormal, "DryRun",
+		fmt.Sprintf("would %s load balancer %s: %s", op, name, details))
+}
+
+// joinPortGroups lists the mappings of each listen port in order
+func joinPortGroups(mappings []triton.PortMapping) string {
+	ports, byPort := groupPortMappings(mappings)
+	groups := make([]string, 0, len(ports))
+	for _, port := range ports {
+		groups = append(groups, byPort[port])
+	}
+	if len(groups) == 0 {
+		return "none"
+	}
+	return strings.Join(groups, ",")
+}
+
+// planUpdate describes how updating existing to desired changes the load
+// balancer, comparing what GetLoadBalancer reads back from the instances
+func planUpdate(existing *triton.LoadBalancerParams, desired triton.LoadBalancerParams) []string {
+	var changes []string
+	if diff := diffPortMappings(existing.PortMappings, desired.PortMappings); !diff.empty() {
+		changes = append(changes, "ports "+diff.String())
+	}
+	if from, to := existing.ReplicaCount(), desired.ReplicaCount(); from != to {
+		changes = append(changes, fmt.Sprintf("replicas %d -> %d", from, to))
+	}
+	if existing.Instance != nil && desired.Package != "" && existing.Instance.Package != "" && existing.Instance.Package != desired.Package {
+		changes = append(changes, fmt.Sprintf("package %s -> %s", existing.Instance.Package, desired.Package))
+	}
+
+	for _, field := range []struct {
+		name     string
+		from, to any
+	}{
+		{"max_rs", existing.MaxBackends, desired.MaxBackends},
+		{"max_connections", existing.MaxConnections, desired.MaxConnections},
+		{"certificate_name", existing.CertificateName, desired.CertificateName},
+		{"metrics_acl", strings.Join(existing.MetricsACL, ","), strings.Join(desired.MetricsACL, ",")},
+		{"proxy_protocol", existing.ProxyProtocol, desired.ProxyProtocol},
+		{"sticky", existing.Sticky, desired.Sticky},
+		{"external_traffic_policy", existing.ExternalTrafficPolicy, desired.ExternalTrafficPolicy},
+		{"backend_weights", existing.BackendWeights, desired.BackendWeights},
+		{"timeout_connect", existing.TimeoutConnect, desired.TimeoutConnect},
+		{"timeout_client", existing.TimeoutClient, desired.TimeoutClient},
+		{"timeout_server", existing.TimeoutServer, desired.TimeoutServer},
+		{"health_check_path", existing.HealthCheckPath, desired.HealthCheckPath},
+		{"health_check_interval", existing.HealthCheckInterval, desired.HealthCheckInterval},
+		{"health_check_timeout", existing.HealthCheckTimeout, desired.HealthCheckTimeout},
+		{"health_check_unhealthy_threshold", existing.HealthCheckUnhealthyThreshold, desired.HealthCheckUnhealthyThreshold},
+		{"provision_timeout", existing.ProvisionTimeout, desired.ProvisionTimeout},
+		{"delete_timeout", existing.DeleteTimeout, desired.DeleteTimeout},
+	} {
+		if !reflect.DeepEqual(field.from, field.to) {
+			changes = append(changes, fmt.Sprintf("%s %v -> %v", field.name, field.from, field.to))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,271 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// backendDiscoveryAnnotation selects whether the load balancer reaches the
+// backends through the Service name, its individual endpoints or the
+// NodePorts of the cluster nodes
+const backendDiscoveryAnnotation = "cloud.tritoncompute/backend-discovery"
+
+const (
+	// backendDiscoveryService addresses the backends by the Service name
+	backendDiscoveryService = "service"
+	// backendDiscoveryEndpoints writes the ready endpoints into the portmap
+	backendDiscoveryEndpoints = "endpoints"
+	// backendDiscoveryNodePort writes the NodePorts of the ready nodes into
+	// the portmap, for pods that aren't routable from the load balancer
+	backendDiscoveryNodePort = "nodeport"
+)
+
+// backendEndpoint is a single address serving a Service port
+type backendEndpoint struct {
+	Address string
+	Port    int
+}
+
+// endpointsByPort holds the ready endpoints of a Service by Service port name
+type endpointsByPort map[string][]backendEndpoint
+
+// backendDiscovery returns the backend discovery mode of the Service
+func (r *LoadBalancerReconciler) backendDiscovery(service *corev1.Service) (string, error) {
+	annotation := r.annotation(backendDiscoveryAnnotation)
+	switch mode := service.Annotations[annotation]; mode {
+	case "", backendDiscoveryService:
+		return backendDiscoveryService, nil
+	case backendDiscoveryEndpoints, backendDiscoveryNodePort:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s annotation %q: must be %s, %s or %s",
+			annotation, mode, backendDiscoveryService, backendDiscoveryEndpoints, backendDiscoveryNodePort)
+	}
+}
+
+// serviceEndpoints collects the ready IPv4 endpoints of the Service from its
+// EndpointSlices, or its NodePorts on the ready nodes in nodeport mode, and
+// returns nil with service discovery. IPv6 addresses can't be written into
+// the colon-separated portmap and are skipped.
+func (r *LoadBalancerReconciler) serviceEndpoints(ctx context.Context, service *corev1.Service) (endpointsByPort, error) {
+	switch mode, err := r.backendDiscovery(service); {
+	case err != nil:
+		// An invalid mode is reported by extractLoadBalancerParams
+		return nil, nil
+	case mode == backendDiscoveryNodePort:
+		return r.nodePortEndpoints(ctx, service)
+	case mode != backendDiscoveryEndpoints:
+		return nil, nil
+	}
+
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, slices, client.InNamespace(service.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: service.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list endpoint slices: %w", err)
+	}
+
+	endpoints := endpointsByPort{}
+	seen := map[string]map[backendEndpoint]bool{}
+	for _, slice := range slices.Items {
+		if slice.AddressType != discoveryv1.AddressTypeIPv4 {
+			continue
+		}
+		for _, port := range slice.Ports {
+			if port.Port == nil {
+				continue
+			}
+			name := ""
+			if port.Name != nil {
+				name = *port.Name
+			}
+			if seen[name] == nil {
+				seen[name] = map[backendEndpoint]bool{}
+			}
+			for _, endpoint := range slice.Endpoints {
+				// A nil ready condition means ready
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					continue
+				}
+				for _, address := range endpoint.Addresses {
+					backend := backendEndpoint{Address: address, Port: int(*port.Port)}
+					if !seen[name][backend] {
+						seen[name][backend] = true
+						endpoints[name] = append(endpoints[name], backend)
+					}
+				}
+			}
+		}
+	}
+
+	// Keep the portmap stable so unchanged endpoints don't update the instance
+	for _, backends := range endpoints {
+		sort.Slice(backends, func(i, j int) bool {
+			if backends[i].Address != backends[j].Address {
+				return backends[i].Address < backends[j].Address
+			}
+			return backends[i].Port < backends[j].Port
+		})
+	}
+	return endpoints, nil
+}
+
+// nodePortEndpoints returns the NodePort of every Service port on the
+// InternalIP of each ready node. With externalTrafficPolicy Local only the
+// nodes running a ready endpoint are used, as the others drop the traffic;
+// while there are none every ready node is kept. Nodes labeled to be
+// excluded from external load balancers are skipped.
+func (r *LoadBalancerReconciler) nodePortEndpoints(ctx context.Context, service *corev1.Service) (endpointsByPort, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// The InternalIP of every eligible node, by node name
+	ready := map[string]string{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if _, excluded := node.Labels[corev1.LabelNodeExcludeBalancers]; excluded || !nodeReady(node) {
+			continue
+		}
+		if address := nodeInternalIP(node); address != "" {
+			ready[node.Name] = address
+		}
+	}
+	if service.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyLocal {
+		local, err := r.endpointNodes(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		filtered := map[string]string{}
+		for name, address := range ready {
+			if local[name] {
+				filtered[name] = address
+			}
+		}
+		if len(filtered) > 0 {
+			ready = filtered
+		}
+	}
+
+	addresses := make([]string, 0, len(ready))
+	for _, address := range ready {
+		addresses = append(addresses, address)
+	}
+	// Keep the portmap stable so unchanged nodes don't update the instance
+	sort.Strings(addresses)
+
+	endpoints := endpointsByPort{}
+	for _, port := range service.Spec.Ports {
+		// Ports without a NodePort are reported by extractLoadBalancerParams
+		if port.NodePort == 0 {
+			continue
+		}
+		for _, address := range addresses {
+			endpoints[port.Name] = append(endpoints[port.Name], backendEndpoint{Address: address, Port: int(port.NodePort)})
+		}
+	}
+	return endpoints, nil
+}
+
+// endpointNodes returns the names of the nodes running a ready endpoint of
+// the Service
+func (r *LoadBalancerReconciler) endpointNodes(ctx context.Context, service *corev1.Service) (map[string]bool, error) {
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, slices, client.InNamespace(service.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: service.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list endpoint slices: %w", err)
+	}
+
+	nodes := map[string]bool{}
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.NodeName == nil || (endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready) {
+				continue
+			}
+			nodes[*endpoint.NodeName] = true
+		}
+	}
+	return nodes, nil
+}
+
+// nodeReady reports whether node has a true Ready condition
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeInternalIP returns the first IPv4 InternalIP address of node, or ""
+func nodeInternalIP(node *corev1.Node) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type != corev1.NodeInternalIP {
+			continue
+		}
+		if ip := net.ParseIP(address.Address); ip != nil && ip.To4() != nil {
+			return address.Address
+		}
+	}
+	return ""
+}
+
+// servicesForNode maps a Node to the Services in nodeport mode, so the
+// portmap follows nodes joining, leaving or changing readiness
+func (r *LoadBalancerReconciler) servicesForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	services := &corev1.ServiceList{}
+	if err := r.List(ctx, services); err != nil {
+		r.Log.Error(err, "Failed to list services for node", "node", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range services.Items {
+		service := &services.Items[i]
+		if !r.managesService(service) {
+			continue
+		}
+		if mode, err := r.backendDiscovery(service); err != nil || mode != backendDiscoveryNodePort {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(service)})
+	}
+	return requests
+}
+
+// serviceForEndpointSlice maps an EndpointSlice to its Service when that uses
+// endpoint discovery, or nodeport mode with externalTrafficPolicy Local, so
+// backend changes are written to the portmap
+func (r *LoadBalancerReconciler) serviceForEndpointSlice(ctx context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()[discoveryv1.LabelServiceName]
+	if name == "" {
+		return nil
+	}
+
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}
+	service := &corev1.Service{}
+	if err := r.Get(ctx, key, service); err != nil {
+		return nil
+	}
+	if !r.managesService(service) {
+		return nil
+	}
+	mode, err := r.backendDiscovery(service)
+	if err != nil {
+		return nil
+	}
+	local := service.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyLocal
+	if mode != backendDiscoveryEndpoints && (mode != backendDiscoveryNodePort || !local) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: key}}
+}
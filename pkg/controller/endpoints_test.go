@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// endpointSlice builds an IPv4 EndpointSlice of the web Service
+func endpointSlice(name, portName string, port int32, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+		Endpoints:   endpoints,
+	}
+}
+
+func readyEndpoint(ready *bool, addresses ...string) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{Addresses: addresses, Conditions: discoveryv1.EndpointConditions{Ready: ready}}
+}
+
+func endpointsService(mode string) *corev1.Service {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "web",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromString("web")},
+				{Name: "metrics", Port: 9100, TargetPort: intstr.FromInt(9100)},
+			},
+		},
+	}
+	if mode != "" {
+		service.Annotations = map[string]string{"cloud.tritoncompute/backend-discovery": mode}
+	}
+	return service
+}
+
+func TestServiceEndpoints(t *testing.T) {
+	ready, notReady := true, false
+	ipv6 := endpointSlice("web-v6", "http", 8080, readyEndpoint(nil, "fd00::1"))
+	ipv6.AddressType = discoveryv1.AddressTypeIPv6
+	other := endpointSlice("api-abc", "http", 8080, readyEndpoint(nil, "10.0.9.9"))
+	other.Labels[discoveryv1.LabelServiceName] = "api"
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		endpointSlice("web-abc", "http", 8080,
+			readyEndpoint(&ready, "10.0.0.2"),
+			readyEndpoint(&notReady, "10.0.0.3"),
+			readyEndpoint(nil, "10.0.0.1")),
+		endpointSlice("web-def", "http", 8081, readyEndpoint(&ready, "10.0.1.1")),
+		ipv6, other,
+	).Build()
+	r := &LoadBalancerReconciler{Client: client, Log: testr.New(t)}
+
+	endpoints, err := r.serviceEndpoints(context.Background(), endpointsService("endpoints"))
+	if err != nil {
+		t.Fatalf("serviceEndpoints: %v", err)
+	}
+	want := endpointsByPort{"http": {
+		{Address: "10.0.0.1", Port: 8080},
+		{Address: "10.0.0.2", Port: 8080},
+		{Address: "10.0.1.1", Port: 8081},
+	}}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("expected the ready IPv4 endpoints in order, got %v", endpoints)
+	}
+
+	endpoints, err = r.serviceEndpoints(context.Background(), endpointsService(""))
+	if err != nil || endpoints != nil {
+		t.Errorf("expected no endpoints without endpoint discovery, got %v (%v)", endpoints, err)
+	}
+}
+
+func TestExtractLoadBalancerParamsEndpoints(t *testing.T) {
+	r := &LoadBalancerReconciler{Log: testr.New(t)}
+
+	params, err := r.extractLoadBalancerParamsWithEndpoints(endpointsService("endpoints"), endpointsByPort{
+		"http": {{Address: "10.0.0.1", Port: 8080}, {Address: "10.0.0.2", Port: 8080}},
+	})
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParamsWithEndpoints: %v", err)
+	}
+	want := []triton.PortMapping{
+		{Type: "http", ListenPort: 80, BackendName: "10.0.0.1", BackendPort: 8080},
+		{Type: "http", ListenPort: 80, BackendName: "10.0.0.2", BackendPort: 8080},
+		// Ports without ready endpoints keep addressing the Service
+		{Type: "tcp", ListenPort: 9100, BackendName: "web", BackendPort: 9100},
+	}
+	if !reflect.DeepEqual(params.PortMappings, want) {
+		t.Errorf("expected port mappings %v, got %v", want, params.PortMappings)
+	}
+
+	if _, err := r.extractLoadBalancerParams(endpointsService("pods")); err == nil {
+		t.Error("expected an error for an unknown backend discovery mode")
+	}
+}
+
+func TestServiceForEndpointSlice(t *testing.T) {
+	slice := endpointSlice("web-abc", "http", 8080)
+
+	for _, tt := range []struct {
+		mode string
+		want int
+	}{
+		{mode: "endpoints", want: 1},
+		{mode: "", want: 0},
+	} {
+		client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(endpointsService(tt.mode)).Build()
+		r := &LoadBalancerReconciler{Client: client, Log: testr.New(t)}
+		requests := r.serviceForEndpointSlice(context.Background(), slice)
+		if len(requests) != tt.want {
+			t.Errorf("backend-discovery %q: expected %d requests, got %v", tt.mode, tt.want, requests)
+		}
+	}
+}
+
+// TestReconcileEndpointDiscovery tests that endpoint changes reach the portmap
+func TestReconcileEndpointDiscovery(t *testing.T) {
+	ready := true
+	slice := endpointSlice("web-abc", "http", 8080, readyEndpoint(&ready, "10.0.0.1"))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(endpointsService("endpoints"), slice).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       k8sClient,
+		Log:          testr.New(t),
+		Scheme:       scheme.Scheme,
+		TritonClient: mockClient,
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}}
+	ctx := context.Background()
+
+	backends := func() []string {
+		var names []string
+		for _, mapping := range mockClient.loadBalancers["web"].PortMappings {
+			if mapping.ListenPort == 80 {
+				names = append(names, mapping.BackendName)
+			}
+		}
+		return names
+	}
+
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if got := backends(); !reflect.DeepEqual(got, []string{"10.0.0.1"}) {
+		t.Fatalf("expected the endpoint as the backend, got %v", got)
+	}
+
+	// A pod moves
+	slice.Endpoints = []discoveryv1.Endpoint{readyEndpoint(&ready, "10.0.0.7")}
+	if err := k8sClient.Update(ctx, slice); err != nil {
+		t.Fatalf("update endpoint slice: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if got := backends(); !reflect.DeepEqual(got, []string{"10.0.0.7"}) {
+		t.Errorf("expected the moved endpoint as the backend, got %v", got)
+	}
+}
+
+// node builds a cluster node with an InternalIP
+func node(name, address string, ready bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: name},
+				{Type: corev1.NodeInternalIP, Address: address},
+			},
+		},
+	}
+}
+
+func nodePortService(policy corev1.ServiceExternalTrafficPolicyType) *corev1.Service {
+	service := endpointsService("nodeport")
+	service.Spec.ExternalTrafficPolicy = policy
+	service.Spec.Ports[0].NodePort = 30080
+	service.Spec.Ports[1].NodePort = 30100
+	return service
+}
+
+func TestNodePortEndpoints(t *testing.T) {
+	ready := true
+	nodeName := "node-b"
+	endpoint := readyEndpoint(&ready, "10.244.1.5")
+	endpoint.NodeName = &nodeName
+	excluded := node("node-d", "10.0.0.14", true)
+	excluded.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: ""}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		node("node-a", "10.0.0.11", true),
+		node("node-b", "10.0.0.12", true),
+		node("node-c", "10.0.0.13", false),
+		excluded,
+		endpointSlice("web-abc", "http", 8080, endpoint),
+	).Build()
+	r := &LoadBalancerReconciler{Client: client, Log: testr.New(t)}
+
+	endpoints, err := r.serviceEndpoints(context.Background(), nodePortService(corev1.ServiceExternalTrafficPolicyCluster))
+	if err != nil {
+		t.Fatalf("serviceEndpoints: %v", err)
+	}
+	want := endpointsByPort{
+		"http":    {{Address: "10.0.0.11", Port: 30080}, {Address: "10.0.0.12", Port: 30080}},
+		"metrics": {{Address: "10.0.0.11", Port: 30100}, {Address: "10.0.0.12", Port: 30100}},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("expected the NodePorts of the ready nodes, got %v", endpoints)
+	}
+
+	// Local policy only keeps the nodes running a ready endpoint
+	endpoints, err = r.serviceEndpoints(context.Background(), nodePortService(corev1.ServiceExternalTrafficPolicyLocal))
+	if err != nil {
+		t.Fatalf("serviceEndpoints: %v", err)
+	}
+	want = endpointsByPort{
+		"http":    {{Address: "10.0.0.12", Port: 30080}},
+		"metrics": {{Address: "10.0.0.12", Port: 30100}},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("expected the NodePorts of the nodes with endpoints, got %v", endpoints)
+	}
+}
+
+func TestExtractLoadBalancerParamsNodePort(t *testing.T) {
+	r := &LoadBalancerReconciler{Log: testr.New(t)}
+	service := nodePortService(corev1.ServiceExternalTrafficPolicyCluster)
+
+	params, err := r.extractLoadBalancerParamsWithEndpoints(service, endpointsByPort{
+		"http": {{Address: "10.0.0.11", Port: 30080}},
+	})
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParamsWithEndpoints: %v", err)
+	}
+	if !reflect.DeepEqual(params.PortMappings[0], triton.PortMapping{Type: "http", ListenPort: 80, BackendName: "10.0.0.11", BackendPort: 30080}) {
+		t.Errorf("expected the node as the backend, got %v", params.PortMappings)
+	}
+
+	noNodePort := nodePortService(corev1.ServiceExternalTrafficPolicyCluster)
+	noNodePort.Spec.Ports[1].NodePort = 0
+	if _, err := r.extractLoadBalancerParams(noNodePort); err == nil {
+		t.Error("expected an error for a port without a NodePort")
+	}
+
+	portRange := nodePortService(corev1.ServiceExternalTrafficPolicyCluster)
+	portRange.Annotations["cloud.tritoncompute/port-range.metrics"] = "9100-9110"
+	if _, err := r.extractLoadBalancerParams(portRange); err == nil {
+		t.Error("expected an error for a port range in nodeport mode")
+	}
+}
+
+func TestServicesForNode(t *testing.T) {
+	api := nodePortService(corev1.ServiceExternalTrafficPolicyCluster)
+	api.Name = "api"
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(api, endpointsService("endpoints")).Build()
+	r := &LoadBalancerReconciler{Client: client, Log: testr.New(t)}
+
+	requests := r.servicesForNode(context.Background(), node("node-a", "10.0.0.11", true))
+	want := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "api"}}}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("expected only the nodeport Service, got %v", requests)
+	}
+
+	// Local policy follows the endpoints too
+	local := nodePortService(corev1.ServiceExternalTrafficPolicyLocal)
+	client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(local).Build()
+	r = &LoadBalancerReconciler{Client: client, Log: testr.New(t)}
+	if requests := r.serviceForEndpointSlice(context.Background(), endpointSlice("web-abc", "http", 8080)); len(requests) != 1 {
+		t.Errorf("expected a request for the Local nodeport Service, got %v", requests)
+	}
+}
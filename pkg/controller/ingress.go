@@ -0,0 +1,318 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+const (
+	// ipFamilyPolicyAnnotation controls which address families are published in the Service status
+	ipFamilyPolicyAnnotation = "cloud.tritoncompute/ip-family-policy"
+	// publishHostnameAnnotation publishes the CNS hostnames of the load balancer in the Service status instead of its IPs
+	publishHostnameAnnotation = "cloud.tritoncompute/publish-hostname"
+
+	// IPFamilyPolicyPreferIPv4 publishes the IPv4 address first, adding a public IPv6 address if available
+	IPFamilyPolicyPreferIPv4 = "PreferIPv4"
+	// IPFamilyPolicyPreferIPv6 publishes the IPv6 address first, adding a public IPv4 address if available
+	IPFamilyPolicyPreferIPv6 = "PreferIPv6"
+	// IPFamilyPolicyRequireDualStack requires both an IPv4 and an IPv6 address
+	IPFamilyPolicyRequireDualStack = "RequireDualStack"
+
+	// portErrorUnsupportedProtocol marks a Service port whose protocol the load balancer cannot serve
+	portErrorUnsupportedProtocol = "cloud.tritoncompute/UnsupportedProtocol"
+	// portErrorNotConfigured marks a Service port missing from the load balancer's port mappings
+	portErrorNotConfigured = "cloud.tritoncompute/PortNotConfigured"
+)
+
+// candidateIP is a parsed instance address with its routability
+type candidateIP struct {
+	addr   string
+	public bool
+}
+
+// selectIngressIPs picks the addresses to publish in the Service status from the
+// instance IPs. Public addresses are preferred within each family; private
+// addresses are only used for the preferred family when nothing public exists.
+// Link-local and loopback addresses are never published.
+func selectIngressIPs(ips []string, policy string) ([]string, error) {
+	if policy == "" {
+		policy = IPFamilyPolicyPreferIPv4
+	}
+
+	var v4, v6 *candidateIP
+	for _, addr := range ips {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			continue
+		}
+
+		candidate := &candidateIP{
+			addr:   ip.String(),
+			public: ip.IsGlobalUnicast() && !ip.IsPrivate(),
+		}
+
+		if ip.To4() != nil {
+			if v4 == nil || (!v4.public && candidate.public) {
+				v4 = candidate
+			}
+		} else {
+			if v6 == nil || (!v6.public && candidate.public) {
+				v6 = candidate
+			}
+		}
+	}
+
+	var primary, secondary *candidateIP
+	switch policy {
+	case IPFamilyPolicyPreferIPv4:
+		primary, secondary = v4, v6
+	case IPFamilyPolicyPreferIPv6:
+		primary, secondary = v6, v4
+	case IPFamilyPolicyRequireDualStack:
+		if v4 == nil || v6 == nil {
+			return nil, fmt.Errorf("ip family policy %s requires both IPv4 and IPv6 addresses, instance has %v",
+				policy, ips)
+		}
+		return []string{v4.addr, v6.addr}, nil
+	default:
+		return nil, fmt.Errorf("unknown ip family policy %q, expected one of %s, %s, %s",
+			policy, IPFamilyPolicyPreferIPv4, IPFamilyPolicyPreferIPv6, IPFamilyPolicyRequireDualStack)
+	}
+
+	// Fall back to the other family when the preferred one is missing
+	if primary == nil {
+		primary, secondary = secondary, nil
+	}
+	if primary == nil {
+		return nil, nil
+	}
+
+	selected := []string{primary.addr}
+	if secondary != nil && secondary.public {
+		selected = append(selected, secondary.addr)
+	}
+	return selected, nil
+}
+
+// selectReplicaIngressIPs selects the addresses to publish for every replica
+// of the load balancer, so each replica is reachable from the Service status
+func selectReplicaIngressIPs(lb *triton.TritonInstance, policy string) ([]string, error) {
+	replicas := lb.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{lb}
+	}
+
+	var selected []string
+	seen := map[string]bool{}
+	for _, replica := range replicas {
+		ips, err := selectIngressIPs(replica.IPs, policy)
+		if err != nil {
+			return nil, fmt.Errorf("replica %s: %w", replica.Name, err)
+		}
+		for _, ip := range ips {
+			if !seen[ip] {
+				seen[ip] = true
+				selected = append(selected, ip)
+			}
+		}
+	}
+	return selected, nil
+}
+
+// failedReplica returns the first replica of lb in a terminal state, or nil
+func failedReplica(lb *triton.TritonInstance) *triton.TritonInstance {
+	replicas := lb.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{lb}
+	}
+
+	for _, replica := range replicas {
+		if triton.IsFailedState(replica.State) {
+			return replica
+		}
+	}
+	return nil
+}
+
+// pendingReplicas returns the names and states of the replicas of lb that
+// are not running yet, formatted as name=state
+func pendingReplicas(lb *triton.TritonInstance) []string {
+	replicas := lb.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{lb}
+	}
+
+	var pending []string
+	for _, replica := range replicas {
+		if replica.State != "running" {
+			pending = append(pending, fmt.Sprintf("%s=%s", replica.Name, replica.State))
+		}
+	}
+	return pending
+}
+
+// replicaDNSNames returns the CNS names of every replica of lb, without
+// duplicates, in replica order
+func replicaDNSNames(lb *triton.TritonInstance) []string {
+	replicas := lb.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{lb}
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, replica := range replicas {
+		for _, name := range replica.DNSNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// selectIngressHostnames picks the CNS names to publish for lb instead of its
+// addresses: the service names of the replicas if there are any, since they
+// resolve to every replica, otherwise the names CNS gives each replica after
+// its instance name. Names made of an instance ID are skipped because a
+// replaced instance doesn't keep them.
+func selectIngressHostnames(lb *triton.TritonInstance) []string {
+	replicas := lb.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{lb}
+	}
+
+	var services, instances []string
+	seen := map[string]bool{}
+	for _, replica := range replicas {
+		for _, name := range replica.DNSNames {
+			label, rest, _ := strings.Cut(name, ".")
+			if seen[name] || label == replica.ID {
+				continue
+			}
+			seen[name] = true
+			if strings.HasPrefix(rest, "svc.") {
+				services = append(services, name)
+			} else {
+				instances = append(instances, name)
+			}
+		}
+	}
+	if len(services) > 0 {
+		return services
+	}
+	return instances
+}
+
+// publishHostname reports whether the Service status of service lists CNS
+// hostnames rather than IPs, which PublishHostname sets for every Service
+func (r *LoadBalancerReconciler) publishHostname(service *corev1.Service) (bool, error) {
+	value, ok := service.Annotations[r.annotation(publishHostnameAnnotation)]
+	if !ok {
+		return r.PublishHostname, nil
+	}
+	enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s annotation %q: must be true or false", r.annotation(publishHostnameAnnotation), value)
+	}
+	return enabled, nil
+}
+
+// ingressHostnames returns the CNS hostnames to publish for lb in the status
+// of service, or nil to publish its IPs
+func (r *LoadBalancerReconciler) ingressHostnames(ctx context.Context, service *corev1.Service, lb *triton.TritonInstance) ([]string, error) {
+	enabled, err := r.publishHostname(service)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	hostnames := selectIngressHostnames(lb)
+	if len(hostnames) == 0 {
+		// CNS may not be enabled for the account, or hasn't caught up yet
+		r.loggerFor(ctx, service).Info("Load balancer has no CNS hostname, publishing its IPs instead")
+	}
+	return hostnames, nil
+}
+
+// ingressAddresses returns the IPs and hostnames published in a Service's
+// ingress status
+func ingressAddresses(ingress []corev1.LoadBalancerIngress) []string {
+	var addresses []string
+	for _, entry := range ingress {
+		if entry.IP != "" {
+			addresses = append(addresses, entry.IP)
+		} else if entry.Hostname != "" {
+			addresses = append(addresses, entry.Hostname)
+		}
+	}
+	return addresses
+}
+
+// portStatuses builds the per-port ingress status for the Service from the
+// port mappings written to the load balancer. Ports the load balancer cannot
+// serve are reported with an error instead of being silently omitted.
+func portStatuses(service *corev1.Service, mappings []triton.PortMapping) []corev1.PortStatus {
+	// TCP and UDP listeners may share a port number
+	configured := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		configured[fmt.Sprintf("%d/%t", mapping.ListenPort, mapping.Type == "udp")] = mapping.Type
+	}
+
+	var statuses []corev1.PortStatus
+	for _, port := range service.Spec.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+
+		status := corev1.PortStatus{Port: port.Port, Protocol: protocol}
+		portType, ok := configured[fmt.Sprintf("%d/%t", port.Port, protocol == corev1.ProtocolUDP)]
+		if !ok {
+			// A listener of the other kind is reported as the wrong protocol
+			portType, ok = configured[fmt.Sprintf("%d/%t", port.Port, protocol != corev1.ProtocolUDP)]
+		}
+		switch {
+		case !ok:
+			reason := portErrorNotConfigured
+			status.Error = &reason
+		case (portType == "udp") != (protocol == corev1.ProtocolUDP), protocol == corev1.ProtocolSCTP:
+			reason := portErrorUnsupportedProtocol
+			status.Error = &reason
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// resizedReplicas returns the replicas of after whose package differs from
+// the replica of the same name in before, formatted as name=old->new
+func resizedReplicas(before, after *triton.TritonInstance) []string {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	previous := map[string]string{}
+	for _, replica := range append([]*triton.TritonInstance{before}, before.Replicas...) {
+		previous[replica.Name] = replica.Package
+	}
+
+	replicas := after.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{after}
+	}
+
+	var resized []string
+	for _, replica := range replicas {
+		if old, ok := previous[replica.Name]; ok && old != "" && replica.Package != "" && old != replica.Package {
+			resized = append(resized, fmt.Sprintf("%s=%s->%s", replica.Name, old, replica.Package))
+		}
+	}
+	return resized
+}
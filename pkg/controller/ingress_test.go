@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+func TestSelectIngressIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		ips     []string
+		policy  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "public IPv4 preferred over private",
+			ips:  []string{"10.0.0.1", "203.0.113.1"},
+			want: []string{"203.0.113.1"},
+		},
+		{
+			name: "private IPv4 used when nothing public",
+			ips:  []string{"10.0.0.1", "192.168.1.5"},
+			want: []string{"10.0.0.1"},
+		},
+		{
+			name: "172 outside the private range is public",
+			ips:  []string{"10.0.0.1", "172.217.0.1"},
+			want: []string{"172.217.0.1"},
+		},
+		{
+			name: "dual stack with default policy lists IPv4 first",
+			ips:  []string{"fe80::1", "2001:db8::10", "10.0.0.1", "203.0.113.1"},
+			want: []string{"203.0.113.1", "2001:db8::10"},
+		},
+		{
+			name:   "prefer IPv6",
+			ips:    []string{"203.0.113.1", "2001:db8::10"},
+			policy: IPFamilyPolicyPreferIPv6,
+			want:   []string{"2001:db8::10", "203.0.113.1"},
+		},
+		{
+			name: "ULA IPv6 is not added alongside IPv4",
+			ips:  []string{"203.0.113.1", "fd00::5"},
+			want: []string{"203.0.113.1"},
+		},
+		{
+			name:   "prefer IPv6 falls back to IPv4",
+			ips:    []string{"10.0.0.1"},
+			policy: IPFamilyPolicyPreferIPv6,
+			want:   []string{"10.0.0.1"},
+		},
+		{
+			name: "link-local only yields no addresses",
+			ips:  []string{"fe80::1", "169.254.1.1"},
+			want: nil,
+		},
+		{
+			name:   "require dual stack",
+			ips:    []string{"10.0.0.1", "203.0.113.1", "fd00::5", "2001:db8::10"},
+			policy: IPFamilyPolicyRequireDualStack,
+			want:   []string{"203.0.113.1", "2001:db8::10"},
+		},
+		{
+			name:    "require dual stack without IPv6",
+			ips:     []string{"203.0.113.1"},
+			policy:  IPFamilyPolicyRequireDualStack,
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy",
+			ips:     []string{"203.0.113.1"},
+			policy:  "IPv4Only",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectIngressIPs(tt.ips, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectIngressIPs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectIngressIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectReplicaIngressIPs(t *testing.T) {
+	lb := &triton.TritonInstance{
+		Name: "web",
+		IPs:  []string{"10.0.0.1", "203.0.113.1"},
+	}
+	lb.Replicas = []*triton.TritonInstance{
+		{Name: "web", IPs: []string{"10.0.0.1", "203.0.113.1"}},
+		{Name: "web-1", IPs: []string{"10.0.0.2", "203.0.113.2"}},
+	}
+
+	got, err := selectReplicaIngressIPs(lb, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"203.0.113.1", "203.0.113.2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Without replicas the instance itself is used
+	single, err := selectReplicaIngressIPs(&triton.TritonInstance{IPs: []string{"203.0.113.9"}}, "")
+	if err != nil || !reflect.DeepEqual(single, []string{"203.0.113.9"}) {
+		t.Errorf("expected single instance IP, got %v (err %v)", single, err)
+	}
+}
+
+func TestSelectIngressHostnames(t *testing.T) {
+	lb := &triton.TritonInstance{Name: "web"}
+	lb.Replicas = []*triton.TritonInstance{
+		{ID: "id-0", Name: "web", DNSNames: []string{
+			"id-0.inst.account.us-east-1.cns.example.com",
+			"web.inst.account.us-east-1.cns.example.com",
+		}},
+		{ID: "id-1", Name: "web-1", DNSNames: []string{
+			"id-1.inst.account.us-east-1.cns.example.com",
+			"web-1.inst.account.us-east-1.cns.example.com",
+		}},
+	}
+	want := []string{"web.inst.account.us-east-1.cns.example.com", "web-1.inst.account.us-east-1.cns.example.com"}
+	if got := selectIngressHostnames(lb); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the instance name hostnames %v, got %v", want, got)
+	}
+
+	// Service names resolve to every replica, so they win
+	for _, replica := range lb.Replicas {
+		replica.DNSNames = append(replica.DNSNames, "shop.svc.account.us-east-1.cns.example.com")
+	}
+	want = []string{"shop.svc.account.us-east-1.cns.example.com"}
+	if got := selectIngressHostnames(lb); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the service hostname %v, got %v", want, got)
+	}
+
+	if got := selectIngressHostnames(&triton.TritonInstance{ID: "id-0", DNSNames: []string{"id-0.inst.account.us-east-1.cns.example.com"}}); got != nil {
+		t.Errorf("expected no hostname without a stable CNS name, got %v", got)
+	}
+}
+
+func TestResizedReplicas(t *testing.T) {
+	before := &triton.TritonInstance{Name: "web", Package: "g4-highcpu-1G"}
+	before.Replicas = []*triton.TritonInstance{
+		{Name: "web", Package: "g4-highcpu-1G"},
+		{Name: "web-1", Package: "g4-highcpu-1G"},
+	}
+	after := &triton.TritonInstance{Name: "web", Package: "g4-highcpu-4G"}
+	after.Replicas = []*triton.TritonInstance{
+		{Name: "web", Package: "g4-highcpu-4G"},
+		{Name: "web-1", Package: "g4-highcpu-1G"},
+		{Name: "web-2", Package: "g4-highcpu-4G"},
+	}
+
+	want := []string{"web=g4-highcpu-1G->g4-highcpu-4G"}
+	if got := resizedReplicas(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := resizedReplicas(nil, after); got != nil {
+		t.Errorf("expected nothing without a previous load balancer, got %v", got)
+	}
+}
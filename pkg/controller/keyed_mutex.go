@@ -0,0 +1,44 @@
+package controller
+
+import "sync"
+
+// keyedMutex serializes work per key while letting different keys proceed in
+// parallel. The zero value is ready to use.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is a mutex shared by every holder and waiter of one key
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock blocks until the lock for key is held and returns the function that
+// releases it. Entries are removed once no goroutine holds or waits for them.
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = map[string]*keyedLock{}
+	}
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		k.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/triton/loadbalancer-controller/api/v1alpha1"
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var k keyedMutex
+
+	unlockA := k.Lock("a")
+
+	// A different key is not blocked
+	done := make(chan struct{})
+	go func() {
+		unlockB := k.Lock("b")
+		unlockB()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("lock on a different key blocked")
+	}
+
+	// The same key waits for the holder
+	acquired := make(chan struct{})
+	go func() {
+		unlock := k.Lock("a")
+		close(acquired)
+		unlock()
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second lock on the same key did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlockA()
+	<-acquired
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.locks) != 0 {
+		t.Errorf("expected released locks to be removed, %d remain", len(k.locks))
+	}
+}
+
+// overlapDetectingClient records whether two calls for the same load balancer overlap
+type overlapDetectingClient struct {
+	*MockTritonClient
+	active  int32
+	overlap int32
+}
+
+func (c *overlapDetectingClient) GetLoadBalancer(ctx context.Context, id, name string) (*triton.LoadBalancerParams, error) {
+	if atomic.AddInt32(&c.active, 1) > 1 {
+		atomic.StoreInt32(&c.overlap, 1)
+	}
+	defer atomic.AddInt32(&c.active, -1)
+
+	time.Sleep(20 * time.Millisecond)
+	return c.MockTritonClient.GetLoadBalancer(ctx, id, name)
+}
+
+func TestReconcileSerializesSameLoadBalancer(t *testing.T) {
+	// With the default template, Services with the same name in two
+	// namespaces map to one load balancer
+	var objects []*corev1.Service
+	for _, namespace := range []string{"team-a", "team-b"} {
+		objects = append(objects, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "web",
+				Namespace:  namespace,
+				Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		})
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, obj := range objects {
+		builder = builder.WithObjects(obj)
+	}
+
+	tritonClient := &overlapDetectingClient{MockTritonClient: NewMockTritonClient()}
+	reconciler := &LoadBalancerReconciler{
+		Client:       builder.Build(),
+		Log:          testr.New(t),
+		Scheme:       scheme.Scheme,
+		TritonClient: tritonClient,
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(objects))
+	for _, obj := range objects {
+		wg.Add(1)
+		go func(key types.NamespacedName) {
+			defer wg.Done()
+			_, err := reconciler.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			errs <- err
+		}(types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name})
+	}
+	wg.Wait()
+	close(errs)
+
+	// Serialized, the second reconcile sees the first one's load balancer
+	// and refuses to take it over
+	var collisions int
+	for err := range errs {
+		if err != nil && strings.Contains(err.Error(), "already belongs to a Service") {
+			collisions++
+		} else if err != nil {
+			t.Errorf("reconcile: %v", err)
+		}
+	}
+	if collisions != 1 {
+		t.Errorf("expected exactly one reconcile to report the name collision, got %d", collisions)
+	}
+	if atomic.LoadInt32(&tritonClient.overlap) != 0 {
+		t.Error("expected reconciles for the same load balancer name not to overlap")
+	}
+}
+
+func TestReconcileSerializesRecordedInstanceName(t *testing.T) {
+	// The template renders different names, but both Services recorded the
+	// same instance, e.g. after the template changed or a manual edit
+	var objects []*corev1.Service
+	for _, name := range []string{"web", "shop"} {
+		objects = append(objects, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Finalizers:  []string{"loadbalancer.triton.io/finalizer"},
+				Annotations: map[string]string{instanceNameAnnotation: "shared-lb"},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		})
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, obj := range objects {
+		builder = builder.WithObjects(obj)
+	}
+
+	tritonClient := &overlapDetectingClient{MockTritonClient: NewMockTritonClient()}
+	reconciler := &LoadBalancerReconciler{
+		Client:       builder.Build(),
+		Log:          testr.New(t),
+		Scheme:       scheme.Scheme,
+		TritonClient: tritonClient,
+	}
+
+	var wg sync.WaitGroup
+	for _, obj := range objects {
+		wg.Add(1)
+		go func(key types.NamespacedName) {
+			defer wg.Done()
+			_, _ = reconciler.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+		}(types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name})
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&tritonClient.overlap) != 0 {
+		t.Error("expected reconciles for the same recorded instance name not to overlap")
+	}
+}
+
+func TestTritonLoadBalancerReconcileSerializesSameLoadBalancer(t *testing.T) {
+	var objects []client.Object
+	for _, namespace := range []string{"team-a", "team-b"} {
+		objects = append(objects, &v1alpha1.TritonLoadBalancer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "web",
+				Namespace:  namespace,
+				Finalizers: []string{DefaultFinalizerName},
+			},
+			Spec: v1alpha1.TritonLoadBalancerSpec{
+				ServiceName:  "web",
+				InstanceName: "web",
+				PortMappings: []v1alpha1.PortMapping{{Type: "http", ListenPort: 80, BackendName: "web", BackendPort: 8080}},
+			},
+		})
+	}
+
+	tritonClient := &overlapDetectingClient{MockTritonClient: NewMockTritonClient()}
+	reconciler := &TritonLoadBalancerReconciler{
+		Client:       newLoadBalancerObjectClient(t, objects...),
+		Log:          testr.New(t),
+		TritonClient: tritonClient,
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(objects))
+	for _, obj := range objects {
+		wg.Add(1)
+		go func(key types.NamespacedName) {
+			defer wg.Done()
+			_, err := reconciler.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			errs <- err
+		}(client.ObjectKeyFromObject(obj))
+	}
+	wg.Wait()
+	close(errs)
+
+	var collisions int
+	for err := range errs {
+		if err != nil && strings.Contains(err.Error(), "already belongs to a Service") {
+			collisions++
+		} else if err != nil {
+			t.Errorf("reconcile: %v", err)
+		}
+	}
+	if collisions != 1 {
+		t.Errorf("expected exactly one reconcile to report the name collision, got %d", collisions)
+	}
+	if atomic.LoadInt32(&tritonClient.overlap) != 0 {
+		t.Error("expected reconciles for the same instance name not to overlap")
+	}
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// DefaultListenerTimeout is how long to wait for a new load balancer to accept
+// connections when no timeout is configured
+const DefaultListenerTimeout = 5 * time.Minute
+
+// listenerDialTimeout bounds each connection attempt to the load balancer
+const listenerDialTimeout = 5 * time.Second
+
+// listenerRetryInterval is how soon an unreachable listener is checked again
+const listenerRetryInterval = 10 * time.Second
+
+// DialFunc opens a network connection, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// listenerWaits tracks when each Service started waiting for its load
+// balancer to accept connections
+type listenerWaits struct {
+	mu    sync.Mutex
+	since map[types.NamespacedName]time.Time
+}
+
+// start returns when key started waiting, recording now if it wasn't
+func (w *listenerWaits) start(key types.NamespacedName, now time.Time) time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if since, ok := w.since[key]; ok {
+		return since
+	}
+	if w.since == nil {
+		w.since = make(map[types.NamespacedName]time.Time)
+	}
+	w.since[key] = now
+	return now
+}
+
+// done forgets that key was waiting
+func (w *listenerWaits) done(key types.NamespacedName) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.since, key)
+}
+
+// checkListener dials the first TCP listen port of the load balancer at each
+// of ips and returns how long to wait before checking again, or zero once the
+// load balancer accepts connections. When it still refuses them after the
+// listener timeout, a warning event is recorded and zero is returned so the
+// status is published anyway rather than withheld forever.
+func (r *LoadBalancerReconciler) checkListener(ctx context.Context, service *corev1.Service, ips []string, mappings []triton.PortMapping) time.Duration {
+	port := 0
+	for _, mapping := range mappings {
+		if mapping.Type != "udp" {
+			port = mapping.ListenPort
+			break
+		}
+	}
+	if port == 0 {
+		// UDP listeners can't be verified with a TCP dial
+		return 0
+	}
+
+	key := types.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	since := r.listenerWaits.start(key, time.Now())
+
+	dial := r.ListenerDialer
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	var dialErr error
+	for _, ip := range ips {
+		dialCtx, cancel := context.WithTimeout(ctx, listenerDialTimeout)
+		conn, err := dial(dialCtx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		cancel()
+		if err != nil {
+			dialErr = err
+			break
+		}
+		conn.Close()
+	}
+	if dialErr == nil {
+		r.listenerWaits.done(key)
+		return 0
+	}
+
+	timeout := r.ListenerTimeout
+	if timeout <= 0 {
+		timeout = DefaultListenerTimeout
+	}
+	log := r.loggerFor(ctx, service)
+	if waited := time.Since(since); waited >= timeout {
+		log.Error(dialErr, "Load balancer listener never came up, publishing status anyway", "port", port, "waited", waited.String())
+		r.recordEvent(service, corev1.EventTypeWarning, "ListenerUnreachable",
+			fmt.Sprintf("load balancer did not accept connections on port %d within %s: %v", port, timeout, dialErr))
+		r.listenerWaits.done(key)
+		return 0
+	}
+
+	log.Info("Load balancer is not accepting connections yet, requeueing", "port", port, "error", dialErr.Error())
+	return listenerRetryInterval
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeDialer accepts connections once listening is set and records the
+// addresses dialed
+type fakeDialer struct {
+	listening bool
+	dialed    []string
+}
+
+func (d *fakeDialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	d.dialed = append(d.dialed, network+"://"+address)
+	if !d.listening {
+		return nil, errors.New("connection refused")
+	}
+	conn, peer := net.Pipe()
+	peer.Close()
+	return conn, nil
+}
+
+func newListenerTestReconciler(t *testing.T, dialer *fakeDialer) (*LoadBalancerReconciler, client.Client, *record.FakeRecorder) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-service",
+			Namespace:   "default",
+			Finalizers:  []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{"cloud.tritoncompute/protocol.dns": "udp"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(service).Build()
+	recorder := record.NewFakeRecorder(10)
+	return &LoadBalancerReconciler{
+		Client:         c,
+		Log:            testr.New(t),
+		Scheme:         scheme.Scheme,
+		TritonClient:   NewMockTritonClient(),
+		Recorder:       recorder,
+		VerifyListener: true,
+		ListenerDialer: dialer.dial,
+	}, c, recorder
+}
+
+func TestReconcileVerifyListener(t *testing.T) {
+	dialer := &fakeDialer{}
+	reconciler, c, _ := newListenerTestReconciler(t, dialer)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter != listenerRetryInterval {
+		t.Errorf("expected a requeue while the listener is down, got %+v", result)
+	}
+	if len(dialer.dialed) != 1 || dialer.dialed[0] != "tcp://203.0.113.1:80" {
+		t.Errorf("expected the first TCP listen port to be dialed, dialed %v", dialer.dialed)
+	}
+
+	var service corev1.Service
+	if err := c.Get(context.Background(), req.NamespacedName, &service); err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if len(service.Status.LoadBalancer.Ingress) != 0 {
+		t.Errorf("expected no status before the listener is up, got %v", service.Status.LoadBalancer.Ingress)
+	}
+
+	dialer.listening = true
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("second reconcile: (%v)", err)
+	}
+	if err := c.Get(context.Background(), req.NamespacedName, &service); err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if len(service.Status.LoadBalancer.Ingress) != 1 || service.Status.LoadBalancer.Ingress[0].IP != "203.0.113.1" {
+		t.Errorf("expected the IP to be published once the listener is up, got %v", service.Status.LoadBalancer.Ingress)
+	}
+}
+
+func TestReconcileVerifyListenerTimeout(t *testing.T) {
+	dialer := &fakeDialer{}
+	reconciler, c, recorder := newListenerTestReconciler(t, dialer)
+	reconciler.ListenerTimeout = time.Nanosecond
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	var found bool
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, "ListenerUnreachable") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected ListenerUnreachable event")
+	}
+
+	var service corev1.Service
+	if err := c.Get(context.Background(), req.NamespacedName, &service); err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if len(service.Status.LoadBalancer.Ingress) != 1 {
+		t.Errorf("expected the IP to be published after the timeout, got %v", service.Status.LoadBalancer.Ingress)
+	}
+}
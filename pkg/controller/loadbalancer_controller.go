@@ -2,30 +2,167 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
+	"github.com/triton/loadbalancer-controller/api/v1alpha1"
+	"github.com/triton/loadbalancer-controller/pkg/dns"
 	"github.com/triton/loadbalancer-controller/pkg/triton"
 )
 
+const (
+	// DefaultFinalizerName is the finalizer used when none is configured
+	DefaultFinalizerName = "loadbalancer.triton.io/finalizer"
+	// DefaultTagLabelPrefix selects the Service labels copied to instance tags
+	DefaultTagLabelPrefix = "cloud.tritoncompute.tag/"
+	// DefaultAnnotationPrefix is the prefix of every Service annotation the
+	// controller reads or writes; the annotation constants below use it
+	DefaultAnnotationPrefix = "cloud.tritoncompute"
+	// DefaultLoadBalancerClass is the spec.loadBalancerClass of the Services
+	// the controller manages when no class is configured
+	DefaultLoadBalancerClass = "tritoncompute.io/loadbalancer"
+
+	// lastErrorAnnotation records the most recent reconcile error on the Service
+	lastErrorAnnotation = "cloud.tritoncompute/last-error"
+	// lastErrorTimeAnnotation records when the most recent reconcile error occurred
+	lastErrorTimeAnnotation = "cloud.tritoncompute/last-error-time"
+	// maxLastErrorLength caps the size of the recorded error message
+	maxLastErrorLength = 1024
+	// instanceIDAnnotation records the ID of the first instance of the load
+	// balancer when it is created or adopted. The load balancer is looked up
+	// by this ID, so renaming the instance or another instance with the same
+	// name doesn't lose it, and a provisioning instance is found before it is
+	// listed by name.
+	instanceIDAnnotation = "cloud.tritoncompute/instance-id"
+	// dnsNamesAnnotation lists the CNS names of the load balancer instances
+	dnsNamesAnnotation = "cloud.tritoncompute/dns-names"
+	// adoptInstanceAnnotation names an existing instance, by ID or name, to
+	// take over as the load balancer instead of creating a new one
+	adoptInstanceAnnotation = "cloud.tritoncompute/adopt-instance"
+	// backendWeightsAnnotation splits traffic between named backends
+	backendWeightsAnnotation = "cloud.tritoncompute/backend-weights"
+	// ignoreAnnotation opts a Service out of management by this controller
+	ignoreAnnotation = "cloud.tritoncompute/ignore"
+	// allocatePublicIPAnnotation attaches a public IP to load balancers on
+	// fabric-only networks
+	allocatePublicIPAnnotation = "cloud.tritoncompute/allocate-public-ip"
+	// timeoutConnectAnnotation, timeoutClientAnnotation and
+	// timeoutServerAnnotation override the HAProxy timeouts, e.g. for
+	// long-lived WebSocket connections
+	timeoutConnectAnnotation = "cloud.tritoncompute/timeout-connect"
+	timeoutClientAnnotation  = "cloud.tritoncompute/timeout-client"
+	timeoutServerAnnotation  = "cloud.tritoncompute/timeout-server"
+	// maxConnectionsAnnotation caps the concurrent connections to each
+	// backend server
+	maxConnectionsAnnotation = "cloud.tritoncompute/max-connections"
+	// healthCheckPathAnnotation and the interval, timeout and
+	// unhealthy-threshold annotations configure HTTP health checks of the
+	// backends
+	healthCheckPathAnnotation               = "cloud.tritoncompute/health-check-path"
+	healthCheckIntervalAnnotation           = "cloud.tritoncompute/health-check-interval"
+	healthCheckTimeoutAnnotation            = "cloud.tritoncompute/health-check-timeout"
+	healthCheckUnhealthyThresholdAnnotation = "cloud.tritoncompute/health-check-unhealthy-threshold"
+	// provisionTimeoutAnnotation and deleteTimeoutAnnotation bound how long
+	// the instances of a Service are waited for to run and to be deleted,
+	// e.g. for a bigger package or image that takes longer to provision
+	provisionTimeoutAnnotation = "cloud.tritoncompute/provision-timeout"
+	deleteTimeoutAnnotation    = "cloud.tritoncompute/delete-timeout"
+	// reloadOnChangeAnnotation reboots load balancers whose boot-time
+	// configuration, such as certificates, changes
+	reloadOnChangeAnnotation = "cloud.tritoncompute/reload-on-change"
+
+	// maxRSAnnotation caps the number of backends
+	maxRSAnnotation = "cloud.tritoncompute/max_rs"
+	// certificateNameAnnotation lists the certificate subjects to request
+	certificateNameAnnotation = "cloud.tritoncompute/certificate_name"
+	// metricsACLAnnotation restricts access to the metrics endpoint
+	metricsACLAnnotation = "cloud.tritoncompute/metrics_acl"
+	// proxyProtocolAnnotation enables PROXY protocol towards the backends
+	proxyProtocolAnnotation = "cloud.tritoncompute/proxy-protocol"
+	// replicasAnnotation sets the number of load balancer instances to run
+	replicasAnnotation = "cloud.tritoncompute/replicas"
+	// affinityAnnotation holds comma-separated Triton affinity rules
+	affinityAnnotation = "cloud.tritoncompute/affinity"
+	// localityNearAnnotation and localityFarAnnotation hold comma-separated
+	// instance UUIDs whose compute nodes load balancer instances are
+	// provisioned on or kept off, and localityStrictAnnotation makes them
+	// mandatory
+	localityNearAnnotation   = "cloud.tritoncompute/locality-near"
+	localityFarAnnotation    = "cloud.tritoncompute/locality-far"
+	localityStrictAnnotation = "cloud.tritoncompute/locality-strict"
+	// spreadReplicasAnnotation keeps the replicas on separate compute nodes
+	spreadReplicasAnnotation = "cloud.tritoncompute/spread-replicas"
+	// packageAnnotation and imageAnnotation override the package and image
+	// load balancer instances are provisioned with
+	packageAnnotation = "cloud.tritoncompute/package"
+	imageAnnotation   = "cloud.tritoncompute/image"
+	// networksAnnotation lists the networks, by name or ID, load balancer
+	// instances are attached to instead of the account defaults
+	networksAnnotation = "cloud.tritoncompute/networks"
+	// firewallSourceRangesAnnotation restricts the firewall rules managed
+	// with --manage-firewall to comma-separated addresses and CIDRs
+	firewallSourceRangesAnnotation = "cloud.tritoncompute/firewall-source-ranges"
+	// datacenterAnnotation names the Triton datacenter the load balancer is
+	// provisioned in, one of those the Triton client reaches
+	datacenterAnnotation = "cloud.tritoncompute/datacenter"
+	// protocolAnnotationPrefix followed by a port name overrides the inferred
+	// listener type of that port
+	protocolAnnotationPrefix = "cloud.tritoncompute/protocol."
+	// portRangeAnnotationPrefix followed by a port name forwards a range of
+	// listen ports, given as <start>-<end>, instead of the single port
+	portRangeAnnotationPrefix = "cloud.tritoncompute/port-range."
+	// maxPortRangeSize caps how many listeners a single port range expands to
+	maxPortRangeSize = 1000
+)
+
+// portTypes are the listener types that can be set with a protocol annotation
+var portTypes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"tcp":   true,
+	"udp":   true,
+}
+
+// proxyProtocolTypes are the listener types HAProxy can send PROXY headers for
+var proxyProtocolTypes = map[string]bool{
+	"tcp":   true,
+	"http":  true,
+	"https": true,
+}
+
 // TritonClientInterface defines the interface for Triton client operations
 type TritonClientInterface interface {
-	CreateLoadBalancer(ctx context.Context, params triton.LoadBalancerParams) error
-	UpdateLoadBalancer(ctx context.Context, name string, params triton.LoadBalancerParams) error
-	DeleteLoadBalancer(ctx context.Context, name string) error
-	GetLoadBalancer(ctx context.Context, name string) (*triton.LoadBalancerParams, error)
+	CreateLoadBalancer(ctx context.Context, params triton.LoadBalancerParams) (*triton.TritonInstance, error)
+	UpdateLoadBalancer(ctx context.Context, id, name string, params triton.LoadBalancerParams) (*triton.TritonInstance, error)
+	DeleteLoadBalancer(ctx context.Context, id, name string) error
+	GetLoadBalancer(ctx context.Context, id, name string) (*triton.LoadBalancerParams, error)
 	GetInstanceByName(ctx context.Context, name string) (*triton.TritonInstance, error)
+	ListLoadBalancers(ctx context.Context) ([]*triton.LoadBalancerParams, error)
+	GetInstanceState(ctx context.Context, id string) (string, error)
+	UpdateTags(ctx context.Context, id, name string, tags map[string]string) error
+	AdoptLoadBalancer(ctx context.Context, ref string, params triton.LoadBalancerParams) (*triton.TritonInstance, error)
+	DeleteInstance(ctx context.Context, id string) error
+	DrainLoadBalancer(ctx context.Context, id, name string) (time.Time, error)
 }
 
 // LoadBalancerReconciler reconciles a Service object with type LoadBalancer
@@ -34,33 +171,197 @@ type LoadBalancerReconciler struct {
 	Log          logr.Logger
 	Scheme       *runtime.Scheme
 	TritonClient TritonClientInterface
+	Recorder     record.EventRecorder
+
+	// FinalizerName overrides DefaultFinalizerName so that several controllers
+	// can manage Services in the same cluster
+	FinalizerName string
+
+	// DefaultMetricsACL is merged into every load balancer's metrics ACL so
+	// the metrics endpoint is locked down even without the annotation
+	DefaultMetricsACL []string
+
+	// TagLabelPrefix overrides DefaultTagLabelPrefix; Service labels with
+	// this prefix become Triton instance tags with the prefix removed
+	TagLabelPrefix string
+
+	// PropagateLabels names Service labels copied to Triton instance tags
+	// under the same key, e.g. team or cost-center for chargeback. A label
+	// with TagLabelPrefix setting the same tag takes precedence.
+	PropagateLabels []string
+
+	// DefaultCertificateName is used for Services with an HTTPS port that
+	// don't set the certificate_name annotation
+	DefaultCertificateName string
+
+	// ReconcileTimeout bounds each Reconcile call so a stuck CloudAPI request
+	// fails and requeues instead of occupying a worker; zero means no limit
+	ReconcileTimeout time.Duration
+
+	// AutoRecreateFailed deletes load balancer instances that reached a
+	// terminal state so the next reconcile provisions them again, unless a
+	// Service sets the recreate-policy annotation
+	AutoRecreateFailed bool
+
+	// ResyncPeriod requeues every successfully reconciled Service so drift
+	// made outside the controller is corrected; zero disables it
+	ResyncPeriod time.Duration
+
+	// PollInterval is how soon a Service whose load balancer is still
+	// provisioning is reconciled again; zero means DefaultPollInterval
+	PollInterval time.Duration
+
+	// VerifyListener withholds the status IPs of a load balancer until its
+	// first TCP listen port accepts connections
+	VerifyListener bool
+
+	// ListenerTimeout bounds how long VerifyListener waits before publishing
+	// the status anyway; zero means DefaultListenerTimeout
+	ListenerTimeout time.Duration
+
+	// ListenerDialer overrides the dialer used by VerifyListener
+	ListenerDialer DialFunc
+
+	// AnnotationPrefix replaces DefaultAnnotationPrefix in the keys of every
+	// annotation, so the controller can run alongside another one
+	AnnotationPrefix string
+
+	// InstanceNameTemplate renders the name of each Service's load balancer
+	// instance; nil means DefaultInstanceNameTemplate
+	InstanceNameTemplate *template.Template
+
+	// ClusterID is the .ClusterID of InstanceNameTemplate
+	ClusterID string
+
+	// LoadBalancerClass overrides DefaultLoadBalancerClass
+	LoadBalancerClass string
+
+	// IgnoreUnclassed leaves Services without a spec.loadBalancerClass to
+	// another load balancer implementation
+	IgnoreUnclassed bool
+
+	// UseLoadBalancerObjects records each Service's load balancer in a
+	// TritonLoadBalancer object, which the TritonLoadBalancerReconciler
+	// provisions, instead of calling the Triton API directly
+	UseLoadBalancerObjects bool
+
+	// ManageFirewall reports that the Triton client manages Cloud Firewall
+	// rules, which enforce source ranges
+	ManageFirewall bool
+
+	// ConcurrentReconciles is how many Services are reconciled in parallel;
+	// zero means DefaultConcurrentReconciles
+	ConcurrentReconciles int
+
+	// DNS registers the addresses of load balancers under the names of
+	// their Services' dns-name annotation; nil disables DNS registration
+	DNS dns.Provider
+
+	// DryRun only logs and records events for the Triton operations each
+	// reconcile would perform, without changing any instance or writing the
+	// finalizer, annotations or status of Services
+	DryRun bool
+
+	// DrainTimeout is how long load balancer instances that are deleted or
+	// replaced keep serving their established connections, unless a Service
+	// sets the drain-timeout annotation; zero deletes them right away
+	DrainTimeout time.Duration
+
+	// Shard limits the controller to the Services in the namespaces of one
+	// shard; the zero value manages every namespace
+	Shard Shard
+
+	// PublishHostname lists the CNS hostnames of load balancers in the
+	// Service status instead of their IPs, unless a Service sets the
+	// publish-hostname annotation
+	PublishHostname bool
+
+	// MaxRetryBackoff caps the exponential backoff between retries of a
+	// Service whose reconciles keep failing; zero means DefaultMaxRetryBackoff
+	MaxRetryBackoff time.Duration
+
+	// lbLocks serializes reconciles per load balancer name. The default
+	// template names load balancers after the Service alone, so Services with
+	// the same name in different namespaces would otherwise race on one instance.
+	lbLocks keyedMutex
+
+	// listenerWaits records when VerifyListener started waiting for each Service
+	listenerWaits listenerWaits
+
+	// retryBackoff tracks the failures in a row of each Degraded Service
+	retryBackoff retryBackoff
+
+	// tagWarnings records the reserved tag labels logged for each Service
+	tagWarnings loggedWarnings
+}
+
+// DefaultPollInterval is how often a provisioning load balancer is checked
+// when no poll interval is configured
+const DefaultPollInterval = 10 * time.Second
+
+// pollInterval returns the configured poll interval or DefaultPollInterval
+func (r *LoadBalancerReconciler) pollInterval() time.Duration {
+	if r.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return r.PollInterval
+}
+
+// DefaultConcurrentReconciles is how many objects a controller reconciles in
+// parallel when no concurrency is configured. Reconciles of the same load
+// balancer are serialized whatever the concurrency.
+const DefaultConcurrentReconciles = 5
+
+// concurrentReconciles returns n, or DefaultConcurrentReconciles when n is not
+// positive
+func concurrentReconciles(n int) int {
+	if n <= 0 {
+		return DefaultConcurrentReconciles
+	}
+	return n
 }
 
 // NewLoadBalancerReconciler creates a new LoadBalancerReconciler
-func NewLoadBalancerReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme, tritonClient TritonClientInterface) *LoadBalancerReconciler {
+func NewLoadBalancerReconciler(client client.Client, log logr.Logger, scheme *runtime.Scheme, tritonClient TritonClientInterface, recorder record.EventRecorder) *LoadBalancerReconciler {
 	return &LoadBalancerReconciler{
 		Client:       client,
 		Log:          log,
 		Scheme:       scheme,
 		TritonClient: tritonClient,
+		Recorder:     recorder,
 	}
 }
 
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=core,resources=services/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+// +kubebuilder:rbac:groups=loadbalancer.triton.io,resources=tritonloadbalancers,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile handles Service updates and creates/updates/deletes Triton load balancers as needed
 func (r *LoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("service", req.NamespacedName)
+	if !r.Shard.Owns(req.Namespace) {
+		// Another replica's shard
+		return ctrl.Result{}, nil
+	}
+	ctx, log := r.reconcileLogger(ctx, req)
+
+	if r.ReconcileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.ReconcileTimeout)
+		defer cancel()
+	}
 
 	// Fetch the Service instance
 	var service corev1.Service
 	if err := r.Get(ctx, req.NamespacedName, &service); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Return and don't requeue
 			log.Info("Service resource not found. Ignoring since object must be deleted")
+			r.retryBackoff.reset(req.NamespacedName)
+			r.tagWarnings.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -68,25 +369,43 @@ func (r *LoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, fmt.Errorf("failed to get service: %w", err)
 	}
 
-	// Only process LoadBalancer type services
-	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
-		return ctrl.Result{}, nil
+	// Lock the instance every Triton operation below acts on, which is the
+	// recorded name if there is one. Names that fail to render are reported
+	// by reconcileNormal.
+	lockKey, err := r.serviceInstanceName(&service)
+	if err != nil {
+		lockKey = req.Name
 	}
+	unlock := r.lbLocks.Lock(lockKey)
+	defer unlock()
 
-	// Check if we need to add finalizer
-	finalizerName := "loadbalancer.triton.io/finalizer"
+	// Send every Triton request of this reconcile to the Service's datacenter
+	ctx = triton.WithDatacenter(ctx, r.serviceDatacenter(&service))
 
-	// Handle deletion
-	if !service.ObjectMeta.DeletionTimestamp.IsZero() {
+	if r.DryRun {
+		return r.planDryRun(ctx, &service)
+	}
+
+	// The finalizer keeps the Service around until its load balancer is
+	// deleted, even if the controller is down when the Service is deleted
+	finalizerName := r.finalizerName()
+
+	// Only process LoadBalancer type services of our class. One changed to
+	// another type or class still holds the finalizer, so release its load
+	// balancer first.
+	if !r.managesService(&service) {
 		if controllerutil.ContainsFinalizer(&service, finalizerName) {
-			// Run finalization logic
-			if err := r.reconcileDelete(ctx, &service); err != nil {
-				// If fail to delete the external dependency here, return with error
-				// so that it can be retried
-				return ctrl.Result{}, err
-			}
+			log.Info("Service is no longer a LoadBalancer of this controller, deleting its load balancer",
+				"type", service.Spec.Type, "loadBalancerClass", service.Spec.LoadBalancerClass)
+			return r.finalize(ctx, &service, finalizerName)
+		}
+		return ctrl.Result{}, nil
+	}
 
-			// Remove finalizer from the list and update it.
+	// Leave ignored Services alone, releasing them if they were managed before
+	if ignored, _ := strconv.ParseBool(service.Annotations[r.annotation(ignoreAnnotation)]); ignored {
+		if controllerutil.ContainsFinalizer(&service, finalizerName) {
+			log.Info("Service is ignored, removing finalizer")
 			controllerutil.RemoveFinalizer(&service, finalizerName)
 			if err := r.Update(ctx, &service); err != nil {
 				return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
@@ -95,7 +414,15 @@ func (r *LoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
-	// Add finalizer if it doesn't exist
+	// Handle deletion
+	if !service.ObjectMeta.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(&service, finalizerName) {
+			return r.finalize(ctx, &service, finalizerName)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Add finalizer before anything is created in Triton
 	if !controllerutil.ContainsFinalizer(&service, finalizerName) {
 		controllerutil.AddFinalizer(&service, finalizerName)
 		if err := r.Update(ctx, &service); err != nil {
@@ -104,208 +431,1237 @@ func (r *LoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	// Handle creation/update
-	return r.reconcileNormal(ctx, &service)
+	result, err := r.reconcileNormal(ctx, &service)
+	if err != nil {
+		r.recordLastError(ctx, &service, err)
+		degraded := r.setState(ctx, &service, StateDegraded, err.Error())
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Info("Reconcile timed out, requeueing", "timeout", r.ReconcileTimeout.String())
+			r.recordEvent(&service, corev1.EventTypeWarning, "TimedOut",
+				fmt.Sprintf("reconcile did not finish within %s and will be retried: %v", r.ReconcileTimeout, err))
+			return degraded, nil
+		}
+	}
+	return result, err
+}
+
+// annotation returns the key of one of the annotation constants under the
+// configured prefix
+func (r *LoadBalancerReconciler) annotation(key string) string {
+	return annotationKey(r.AnnotationPrefix, key)
+}
+
+// annotationKey replaces DefaultAnnotationPrefix in key with prefix
+func annotationKey(prefix, key string) string {
+	if prefix == "" || prefix == DefaultAnnotationPrefix {
+		return key
+	}
+	return prefix + strings.TrimPrefix(key, DefaultAnnotationPrefix)
+}
+
+// finalize deletes the load balancer of service and then removes the
+// finalizer. The finalizer stays in place if the delete fails so that it is
+// retried rather than the instance being orphaned, and while the load
+// balancer drains the Service is requeued for when it has.
+func (r *LoadBalancerReconciler) finalize(ctx context.Context, service *corev1.Service, finalizerName string) (ctrl.Result, error) {
+	draining, err := r.reconcileDelete(ctx, service)
+	if err != nil {
+		r.recordLastError(ctx, service, err)
+		r.recordEvent(service, corev1.EventTypeWarning, "DeleteFailed", err.Error())
+		r.setState(ctx, service, StateDeleteFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+	if draining > 0 {
+		// Deleted once the instances have drained
+		return ctrl.Result{RequeueAfter: draining}, nil
+	}
+	r.recordEvent(service, corev1.EventTypeNormal, "Deleted", "deleted the load balancer")
+
+	controllerutil.RemoveFinalizer(service, finalizerName)
+	if err := r.Update(ctx, service); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// managesService reports whether service is a LoadBalancer Service of the
+// controller's class. Services without a class are managed too unless
+// IgnoreUnclassed is set.
+func (r *LoadBalancerReconciler) managesService(service *corev1.Service) bool {
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return false
+	}
+	if service.Spec.LoadBalancerClass == nil {
+		return !r.IgnoreUnclassed
+	}
+	class := r.LoadBalancerClass
+	if class == "" {
+		class = DefaultLoadBalancerClass
+	}
+	return *service.Spec.LoadBalancerClass == class
+}
+
+// serviceDatacenter returns the datacenter of the load balancer of service,
+// or "" for the default one
+func (r *LoadBalancerReconciler) serviceDatacenter(service *corev1.Service) string {
+	return strings.TrimSpace(service.Annotations[r.annotation(datacenterAnnotation)])
+}
+
+// finalizerName returns the configured finalizer or the default
+func (r *LoadBalancerReconciler) finalizerName() string {
+	if r.FinalizerName == "" {
+		return DefaultFinalizerName
+	}
+	return r.FinalizerName
 }
 
 // reconcileNormal handles the creation and update of load balancers
 func (r *LoadBalancerReconciler) reconcileNormal(ctx context.Context, service *corev1.Service) (ctrl.Result, error) {
-	log := r.Log.WithValues("service", fmt.Sprintf("%s/%s", service.Namespace, service.Name))
+	log := r.loggerFor(ctx, service)
 	log.Info("Reconciling LoadBalancer service",
 		"generation", service.Generation,
 		"resourceVersion", service.ResourceVersion)
 
+	// Resolve the backends when the Service asks for endpoint discovery
+	endpoints, err := r.serviceEndpoints(ctx, service)
+	if err != nil {
+		log.Error(err, "Failed to list service endpoints")
+		return ctrl.Result{}, err
+	}
+
 	// Extract load balancer configuration from service
-	lbParams, err := r.extractLoadBalancerParams(service)
+	lbParams, err := r.extractLoadBalancerParamsWithEndpoints(service, endpoints)
+	r.warnReservedTagLabels(ctx, service)
 	if err != nil {
 		log.Error(err, "Failed to extract load balancer parameters")
+		r.recordEvent(service, corev1.EventTypeWarning, "InvalidConfiguration", err.Error())
 		return ctrl.Result{}, fmt.Errorf("failed to extract LB params: %w", err)
 	}
 
+	// Leave the load balancer alone rather than open it to every source
+	unsupported, err := r.checkSourceRanges(ctx, service, lbParams)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if unsupported != nil {
+		log.Error(unsupported, "Unsupported load balancer source ranges")
+		r.recordEvent(service, corev1.EventTypeWarning, "UnsupportedConfiguration", unsupported.Error())
+		r.recordLastError(ctx, service, unsupported)
+		r.setState(ctx, service, StateDegraded, unsupported.Error())
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.resolveCertificateSecret(ctx, service, &lbParams); err != nil {
+		log.Error(err, "Failed to resolve certificate secret")
+		return ctrl.Result{}, err
+	}
+
 	log.V(1).Info("Extracted load balancer parameters",
 		"portCount", len(lbParams.PortMappings),
 		"maxBackends", lbParams.MaxBackends,
 		"hasCertificate", lbParams.CertificateName != "")
 
+	if err := r.recordInstanceName(ctx, service, lbParams.Name); err != nil {
+		log.Error(err, "Failed to record instance name")
+		return ctrl.Result{}, err
+	}
+
+	if r.UseLoadBalancerObjects {
+		return r.reconcileLoadBalancerObject(ctx, service, lbParams)
+	}
+
+	// Check on an instance that was still provisioning when last seen,
+	// without blocking the worker until it is running
+	instanceID := service.Annotations[r.annotation(instanceIDAnnotation)]
+	if instanceID != "" && len(service.Status.LoadBalancer.Ingress) == 0 {
+		state, err := r.TritonClient.GetInstanceState(ctx, instanceID)
+		if err != nil {
+			log.Error(err, "Failed to check provisioning load balancer instance", "instanceID", instanceID)
+			return ctrl.Result{}, err
+		}
+		switch state {
+		case "running":
+			log.Info("Load balancer instance finished provisioning", "instanceID", instanceID)
+			r.recordEvent(service, corev1.EventTypeNormal, "Provisioned",
+				fmt.Sprintf("provisioned load balancer %s as instance %s", lbParams.Name, instanceID))
+		case "stopped", "failed":
+			return r.handleFailedInstance(ctx, service, &triton.InstanceFailedError{InstanceID: instanceID, State: state})
+		case "deleted", "destroyed":
+			// Gone: provision again below
+			log.Info("Provisioning load balancer instance disappeared", "instanceID", instanceID, "state", state)
+			r.setInstanceIDAnnotation(ctx, service, "")
+			instanceID = ""
+		default:
+			// Tag changes needn't wait for the update once it is running
+			if err := r.TritonClient.UpdateTags(ctx, instanceID, lbParams.Name, lbParams.Tags); err != nil {
+				log.Error(err, "Failed to update tags of provisioning load balancer", "instanceID", instanceID)
+				return ctrl.Result{}, err
+			}
+			log.Info("Load balancer instance is still provisioning, requeueing", "instanceID", instanceID, "state", state)
+			return r.setState(ctx, service, StateProvisioning, fmt.Sprintf("instance %s is %s", instanceID, state)), nil
+		}
+	}
+
 	// Check if the load balancer already exists
-	existingLB, err := r.TritonClient.GetLoadBalancer(ctx, service.Name)
+	existingLB, err := r.TritonClient.GetLoadBalancer(ctx, instanceID, lbParams.Name)
 	if err != nil {
 		log.Error(err, "Failed to check if load balancer exists")
 		return ctrl.Result{}, err
 	}
+	if existingLB != nil && existingLB.Namespace != "" && existingLB.Namespace != service.Namespace {
+		err := fmt.Errorf("load balancer %s already belongs to a Service in namespace %s; include {{.Namespace}} in the instance name template",
+			lbParams.Name, existingLB.Namespace)
+		log.Error(err, "Load balancer name collision")
+		r.recordEvent(service, corev1.EventTypeWarning, "NameCollision", err.Error())
+		return ctrl.Result{}, err
+	}
 
-	if existingLB == nil {
+	var lbInstance *triton.TritonInstance
+	if ref := service.Annotations[r.annotation(adoptInstanceAnnotation)]; existingLB == nil && ref != "" {
+		// Take over a manually created load balancer
+		log.Info("Adopting existing instance as load balancer", "name", lbParams.Name, "instance", ref)
+		lbInstance, err = r.TritonClient.AdoptLoadBalancer(ctx, ref, lbParams)
+		if err != nil {
+			log.Error(err, "Failed to adopt load balancer instance", "instance", ref)
+			if isTransientError(err) {
+				r.recordLastError(ctx, service, err)
+				return r.setState(ctx, service, StateDegraded, err.Error()), nil
+			}
+			r.recordEvent(service, corev1.EventTypeWarning, "AdoptionFailed", err.Error())
+			return ctrl.Result{}, fmt.Errorf("failed to adopt instance %s: %w", ref, err)
+		}
+		log.Info("Successfully adopted load balancer", "name", lbParams.Name, "instance", ref)
+		r.setInstanceIDAnnotation(ctx, service, lbInstance.ID)
+		r.recordEvent(service, corev1.EventTypeNormal, "Adopted",
+			fmt.Sprintf("adopted instance %s as the load balancer", ref))
+	} else if existingLB == nil {
 		// Create new load balancer
-		log.Info("Creating new load balancer", "name", service.Name)
-		if err := r.TritonClient.CreateLoadBalancer(ctx, lbParams); err != nil {
+		log.Info("Creating new load balancer", "name", lbParams.Name)
+		r.recordEvent(service, corev1.EventTypeNormal, "Provisioning",
+			fmt.Sprintf("provisioning load balancer %s with %d replica(s)", lbParams.Name, lbParams.ReplicaCount()))
+		lbInstance, err = r.TritonClient.CreateLoadBalancer(ctx, lbParams)
+		if err != nil {
 			log.Error(err, "Failed to create load balancer")
+			var interrupted *triton.ProvisionInterruptedError
+			if errors.As(err, &interrupted) {
+				// Record the instance so the next reconcile resumes waiting
+				// for it instead of starting over
+				r.setInstanceIDAnnotation(context.WithoutCancel(ctx), service, interrupted.InstanceID)
+				return ctrl.Result{}, err
+			}
+			var failed *triton.InstanceFailedError
+			if errors.As(err, &failed) {
+				return r.handleFailedInstance(ctx, service, failed)
+			}
 			// Check if this is a transient error that should be retried
-			if isTransientError(err) {
-				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			if isTransientError(err) || isPublicIPError(err) {
+				r.recordPublicIPError(service, err)
+				r.recordLastError(ctx, service, err)
+				return r.setState(ctx, service, StateDegraded, err.Error()), nil
+			}
+			reason := "CreateFailed"
+			if errors.Is(err, triton.ErrQuotaExceeded) {
+				// Retries fail too until the account's quota is raised
+				reason = "QuotaExceeded"
 			}
+			r.recordEvent(service, corev1.EventTypeWarning, reason, err.Error())
 			return ctrl.Result{}, fmt.Errorf("failed to create load balancer: %w", err)
 		}
-		log.Info("Successfully created load balancer", "name", service.Name)
-		// Requeue to check status
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		// Record the instance so later reconciles find it by ID, and check on
+		// it instead of creating another one while it provisions
+		r.setInstanceIDAnnotation(ctx, service, lbInstance.ID)
+		if pending := pendingReplicas(lbInstance); len(pending) > 0 {
+			// Provisioning continues in Triton
+			log.Info("Created load balancer, waiting for it to provision", "name", lbParams.Name, "replicas", pending)
+			return r.setState(ctx, service, StateProvisioning, "waiting for replicas "+strings.Join(pending, ", ")), nil
+		}
+		log.Info("Successfully created load balancer", "name", lbParams.Name)
+		r.recordEvent(service, corev1.EventTypeNormal, "Provisioned",
+			fmt.Sprintf("provisioned load balancer %s as instance %s", lbParams.Name, lbInstance.ID))
 	} else {
+		if existingLB.PortMapErr != nil {
+			// The update below rewrites the portmap from the Service spec
+			log.Error(existingLB.PortMapErr, "Load balancer has invalid portmap entries")
+			r.recordEvent(service, corev1.EventTypeWarning, "InvalidPortMap", existingLB.PortMapErr.Error())
+		}
+
+		// Work out what is changing so it can be logged and sanity checked
+		diff := diffPortMappings(existingLB.PortMappings, lbParams.PortMappings)
+		if len(existingLB.PortMappings) > 0 && len(lbParams.PortMappings) == 0 && len(service.Spec.Ports) > 0 {
+			err := fmt.Errorf("refusing to remove all %d port mappings while the service still declares %d ports",
+				len(existingLB.PortMappings), len(service.Spec.Ports))
+			log.Error(err, "Rejecting load balancer update")
+			r.recordEvent(service, corev1.EventTypeWarning, "UpdateRejected", err.Error())
+			return ctrl.Result{}, err
+		}
+		if !diff.empty() {
+			log.Info("Load balancer port mappings changed",
+				"added", diff.added,
+				"removed", diff.removed,
+				"changed", diff.changed)
+		}
+
 		// Update existing load balancer
-		log.Info("Updating existing load balancer", "name", service.Name)
-		if err := r.TritonClient.UpdateLoadBalancer(ctx, service.Name, lbParams); err != nil {
+		log.Info("Updating existing load balancer", "name", lbParams.Name)
+		lbInstance, err = r.TritonClient.UpdateLoadBalancer(ctx, instanceID, lbParams.Name, lbParams)
+		if err != nil {
 			log.Error(err, "Failed to update load balancer")
+			var failed *triton.InstanceFailedError
+			if errors.As(err, &failed) {
+				return r.handleFailedInstance(ctx, service, failed)
+			}
+			if !isPublicIPError(err) {
+				r.recordEvent(service, corev1.EventTypeWarning, "UpdateFailed", err.Error())
+			}
 			// Check if this is a transient error that should be retried
-			if isTransientError(err) {
-				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			if isTransientError(err) || isPublicIPError(err) {
+				r.recordPublicIPError(service, err)
+				r.recordLastError(ctx, service, err)
+				return r.setState(ctx, service, StateDegraded, err.Error()), nil
 			}
 			return ctrl.Result{}, fmt.Errorf("failed to update load balancer: %w", err)
 		}
-		log.Info("Successfully updated load balancer", "name", service.Name)
+		log.Info("Successfully updated load balancer", "name", lbParams.Name)
+		if lbInstance != nil {
+			// Load balancers created before their ID was recorded are found
+			// by ID from now on
+			r.setInstanceIDAnnotation(ctx, service, lbInstance.ID)
+		}
+		if !diff.empty() {
+			r.recordEvent(service, corev1.EventTypeNormal, "PortMappingsChanged", diff.String())
+		}
+		if from, to := existingLB.ReplicaCount(), lbParams.ReplicaCount(); from != to {
+			r.recordEvent(service, corev1.EventTypeNormal, "ReplicasChanged",
+				fmt.Sprintf("scaled load balancer from %d to %d replicas", from, to))
+		}
+		if resized := resizedReplicas(existingLB.Instance, lbInstance); len(resized) > 0 {
+			r.recordEvent(service, corev1.EventTypeNormal, "Resized",
+				"changed the package of "+strings.Join(resized, ", "))
+		}
 	}
 
-	// Get load balancer instance to extract IP information
-	_, err = r.TritonClient.GetLoadBalancer(ctx, service.Name)
-	if err != nil {
-		log.Error(err, "Failed to get load balancer info for status update")
-		return ctrl.Result{}, err
+	// Tag the rest of this reconcile's log lines with the instance
+	if lbInstance != nil {
+		ctx, log = withLogValues(ctx, log, "instanceID", lbInstance.ID)
 	}
 
-	// Get the load balancer IP address
-	lbInstance, err := r.TritonClient.GetInstanceByName(ctx, service.Name)
-	if err != nil {
-		log.Error(err, "Failed to get load balancer instance for IP")
-		return ctrl.Result{}, err
+	// Only publish the load balancer once every replica is serving
+	if lbInstance != nil {
+		if failed := failedReplica(lbInstance); failed != nil {
+			return r.recoverReplica(ctx, service, failed)
+		}
+		if stopped := stoppedReplica(lbInstance); stopped != nil {
+			return r.recoverReplica(ctx, service, stopped)
+		}
+		if pending := pendingReplicas(lbInstance); len(pending) > 0 {
+			log.Info("Load balancer is not running yet, requeueing", "replicas", pending)
+			return r.setState(ctx, service, StateProvisioning, "waiting for replicas "+strings.Join(pending, ", ")), nil
+		}
+		if len(lbInstance.IPs) == 0 {
+			log.Info("Load balancer has no IPs yet, requeueing")
+			return r.setState(ctx, service, StateProvisioning, "waiting for the load balancer IPs"), nil
+		}
 	}
 
 	// Update service status with load balancer information
 	if lbInstance != nil && len(lbInstance.IPs) > 0 {
-		// Copy current status
-		updatedService := service.DeepCopy()
-
-		// Find a public IP address in the list
-		var lbIP string
-		for _, ip := range lbInstance.IPs {
-			// Prefer non-private IP address
-			if !strings.HasPrefix(ip, "10.") && !strings.HasPrefix(ip, "192.168.") && !strings.HasPrefix(ip, "172.") {
-				lbIP = ip
-				break
-			}
+		// Pick the addresses to publish according to the ip family policy
+		lbIPs, err := selectReplicaIngressIPs(lbInstance, service.Annotations[r.annotation(ipFamilyPolicyAnnotation)])
+		if err != nil {
+			log.Error(err, "Failed to select load balancer IP")
+			return ctrl.Result{}, err
 		}
 
-		// Use private IP if no public one is found
-		if lbIP == "" && len(lbInstance.IPs) > 0 {
-			lbIP = lbInstance.IPs[0]
+		// Don't send clients to a load balancer that refuses connections
+		if len(lbIPs) > 0 && r.VerifyListener {
+			if requeue := r.checkListener(ctx, service, lbIPs, lbParams.PortMappings); requeue > 0 {
+				r.setState(ctx, service, StateProvisioning, "waiting for the load balancer to accept connections")
+				return ctrl.Result{RequeueAfter: requeue}, nil
+			}
 		}
 
 		// Update the load balancer status
-		if lbIP != "" {
-			updatedService.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
-				{
-					IP: lbIP,
-				},
+		if len(lbIPs) > 0 {
+			hostnames, err := r.ingressHostnames(ctx, service, lbInstance)
+			if err != nil {
+				log.Error(err, "Failed to select load balancer hostname")
+				return ctrl.Result{}, err
 			}
-
-			// Update status subresource
-			if err := r.Status().Update(ctx, updatedService); err != nil {
-				log.Error(err, "Failed to update Service status with load balancer IP")
+			if err := r.publishIngress(ctx, service, lbIPs, hostnames, lbParams.PortMappings); err != nil {
 				return ctrl.Result{}, err
 			}
+			if err := r.syncDNSRecord(ctx, service, lbParams.Name, lbInstance.ID, lbIPs); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	if lbInstance != nil {
+		r.setDNSNamesAnnotation(ctx, service, replicaDNSNames(lbInstance))
+	}
+
+	r.clearLastError(ctx, service)
+	result := r.setState(ctx, service, StateReady, fmt.Sprintf("load balancer %s is running", lbParams.Name))
+	return r.healthCheckResult(service, result), nil
+}
+
+// publishIngress writes lbIPs, or hostnames instead if there are any, with the
+// status of every port, to the Service's load balancer ingress status if it
+// differs from what is published
+func (r *LoadBalancerReconciler) publishIngress(ctx context.Context, service *corev1.Service, lbIPs, hostnames []string, mappings []triton.PortMapping) error {
+	log := r.loggerFor(ctx, service)
 
-			log.Info("Updated service status with load balancer IP", "ip", lbIP)
+	ports := portStatuses(service, mappings)
+	var ingress []corev1.LoadBalancerIngress
+	addresses := lbIPs
+	if len(hostnames) > 0 {
+		addresses = hostnames
+		for _, hostname := range hostnames {
+			ingress = append(ingress, corev1.LoadBalancerIngress{Hostname: hostname, Ports: ports})
+		}
+	} else {
+		for _, ip := range lbIPs {
+			ingress = append(ingress, corev1.LoadBalancerIngress{IP: ip, Ports: ports})
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// The instance's addresses can change out of band, e.g. when a NIC is
+	// reattached, so compare rather than only filling in an empty status
+	previous := service.Status.LoadBalancer.Ingress
+	if equality.Semantic.DeepEqual(previous, ingress) {
+		return nil
+	}
+
+	updatedService := service.DeepCopy()
+	updatedService.Status.LoadBalancer.Ingress = ingress
+	if err := r.Status().Update(ctx, updatedService); err != nil {
+		log.Error(err, "Failed to update Service status with load balancer IP")
+		return err
+	}
+
+	if len(previous) > 0 {
+		log.Info("Corrected stale load balancer IP in service status",
+			"previous", ingressAddresses(previous), "ips", addresses)
+		r.recordEvent(service, corev1.EventTypeNormal, "IngressChanged",
+			fmt.Sprintf("load balancer IPs changed from %s to %s",
+				strings.Join(ingressAddresses(previous), ","), strings.Join(addresses, ",")))
+	} else {
+		log.Info("Updated service status with load balancer IP", "ips", addresses)
+	}
+	return nil
 }
 
-// reconcileDelete handles the deletion of load balancers
-func (r *LoadBalancerReconciler) reconcileDelete(ctx context.Context, service *corev1.Service) error {
-	log := r.Log.WithValues("service", fmt.Sprintf("%s/%s", service.Namespace, service.Name))
+// reconcileDelete handles the deletion of load balancers. While the load
+// balancer drains it returns how much longer that takes instead.
+func (r *LoadBalancerReconciler) reconcileDelete(ctx context.Context, service *corev1.Service) (time.Duration, error) {
+	log := r.loggerFor(ctx, service)
 	log.Info("Reconciling LoadBalancer service deletion")
 
-	// Delete load balancer
-	if err := r.TritonClient.DeleteLoadBalancer(ctx, service.Name); err != nil {
+	name, err := r.serviceInstanceName(service)
+	if err != nil {
+		return 0, err
+	}
+	instanceID := service.Annotations[r.annotation(instanceIDAnnotation)]
+
+	// Don't leave the DNS name pointing at addresses that are released
+	if err := r.deleteDNSRecord(ctx, service, name, instanceID); err != nil {
+		return 0, err
+	}
+
+	if r.UseLoadBalancerObjects {
+		// The TritonLoadBalancerReconciler drains it
+		return 0, r.deleteLoadBalancerObject(ctx, service)
+	}
+
+	if draining, err := r.drainLoadBalancer(ctx, service, instanceID, name); err != nil || draining > 0 {
+		return draining, err
+	}
+
+	// Delete load balancer, by its recorded ID if there is one
+	if err := r.TritonClient.DeleteLoadBalancer(ctx, instanceID, name); err != nil {
 		log.Error(err, "Failed to delete load balancer")
-		return fmt.Errorf("failed to delete load balancer: %w", err)
+		return 0, fmt.Errorf("failed to delete load balancer: %w", err)
 	}
 
-	log.Info("Successfully deleted load balancer", "name", service.Name)
-	return nil
+	log.Info("Successfully deleted load balancer", "name", name)
+	return 0, nil
+}
+
+// labelTags returns the instance tags set by the labels of service, and the
+// labels ignored because they would set a reserved tag
+func (r *LoadBalancerReconciler) labelTags(service *corev1.Service) (map[string]string, []string) {
+	var tags map[string]string
+	var reserved []string
+	set := func(label, key, value string) {
+		if triton.IsReservedTag(key) {
+			reserved = append(reserved, label)
+			return
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		tags[key] = value
+	}
+
+	prefix := r.TagLabelPrefix
+	if prefix == "" {
+		prefix = DefaultTagLabelPrefix
+	}
+	for k, v := range service.Labels {
+		key, ok := strings.CutPrefix(k, prefix)
+		if !ok || key == "" {
+			continue
+		}
+		set(k, key, v)
+	}
+	for _, key := range r.PropagateLabels {
+		v, ok := service.Labels[key]
+		if !ok {
+			continue
+		}
+		if _, ok := tags[key]; ok {
+			continue
+		}
+		set(key, key, v)
+	}
+	sort.Strings(reserved)
+	return tags, reserved
+}
+
+// warnReservedTagLabels logs the labels of service that are ignored because
+// they would set a reserved instance tag, once until they change
+func (r *LoadBalancerReconciler) warnReservedTagLabels(ctx context.Context, service *corev1.Service) {
+	_, reserved := r.labelTags(service)
+	key := types.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	if !r.tagWarnings.changed(key, strings.Join(reserved, ",")) || len(reserved) == 0 {
+		return
+	}
+	r.loggerFor(ctx, service).Info("Ignoring labels for reserved instance tags", "labels", reserved)
 }
 
 // extractLoadBalancerParams extracts load balancer configuration from a Service
 func (r *LoadBalancerReconciler) extractLoadBalancerParams(service *corev1.Service) (triton.LoadBalancerParams, error) {
+	return r.extractLoadBalancerParamsWithEndpoints(service, nil)
+}
+
+// extractLoadBalancerParamsWithEndpoints is extractLoadBalancerParams with the
+// ready endpoints of the Service as backends instead of the Service name,
+// for the ports that have any
+func (r *LoadBalancerReconciler) extractLoadBalancerParamsWithEndpoints(service *corev1.Service, endpoints endpointsByPort) (triton.LoadBalancerParams, error) {
+	discovery, err := r.backendDiscovery(service)
+	if err != nil {
+		return triton.LoadBalancerParams{}, err
+	}
+	name, err := r.serviceInstanceName(service)
+	if err != nil {
+		return triton.LoadBalancerParams{}, err
+	}
 	params := triton.LoadBalancerParams{
-		Name: service.Name,
+		Name:        name,
+		ServiceName: service.Name,
+		Namespace:   service.Namespace,
 	}
 
-	// Extract port mappings from service ports
-	for _, port := range service.Spec.Ports {
-		// Determine protocol type (http, https, tcp)
+	// Extract port mappings from service ports. TCP and UDP listeners may
+	// share a port number, but two listeners of the same kind may not.
+	// NodePorts are only used in nodeport mode; otherwise the load balancer
+	// reaches the backends by name or endpoint address, so
+	// spec.allocateLoadBalancerNodePorts can be either value.
+	listeners := map[string]int{}
+	for i, port := range service.Spec.Ports {
+		// Determine protocol type (http, https, tcp, udp), unless explicitly set
 		portType := "tcp"
-		if port.Name == "http" || port.Port == 80 {
+		if override, ok := service.Annotations[r.annotation(protocolAnnotationPrefix)+port.Name]; ok && port.Name != "" {
+			if !portTypes[override] {
+				return params, fmt.Errorf("invalid %s%s annotation %q: must be one of http, https, tcp or udp",
+					r.annotation(protocolAnnotationPrefix), port.Name, override)
+			}
+			if port.Protocol == corev1.ProtocolUDP && override != "udp" {
+				return params, fmt.Errorf("invalid %s%s annotation %q: port %s is UDP",
+					r.annotation(protocolAnnotationPrefix), port.Name, override, portLabel(port, i))
+			}
+			portType = override
+		} else if port.Protocol == corev1.ProtocolUDP {
+			portType = "udp"
+		} else if port.Name == "http" || port.Port == 80 {
 			portType = "http"
 		} else if port.Name == "https" || port.Port == 443 {
 			portType = "https"
 		}
 
-		mapping := triton.PortMapping{
-			Type:        portType,
-			ListenPort:  int(port.Port),
-			BackendName: service.Name,
-			BackendPort: int(port.TargetPort.IntVal),
+		// Kubernetes defaults an unset TargetPort to the service port
+		backendPort := int(port.TargetPort.IntVal)
+		if port.TargetPort.IntVal == 0 && port.TargetPort.StrVal == "" {
+			backendPort = int(port.Port)
+		}
+		if !validPort(int(port.Port)) {
+			return params, fmt.Errorf("port %s has invalid port %d: must be between 1 and 65535", portLabel(port, i), port.Port)
+		}
+		if port.TargetPort.StrVal == "" && !validPort(backendPort) {
+			return params, fmt.Errorf("port %s has invalid target port %d: must be between 1 and 65535", portLabel(port, i), backendPort)
+		}
+
+		if discovery == backendDiscoveryNodePort && port.NodePort == 0 {
+			return params, fmt.Errorf("port %s has no NodePort: %s %s requires spec.allocateLoadBalancerNodePorts",
+				portLabel(port, i), r.annotation(backendDiscoveryAnnotation), backendDiscoveryNodePort)
+		}
+
+		start, end := int(port.Port), int(port.Port)
+		if spec, ok := service.Annotations[r.annotation(portRangeAnnotationPrefix)+port.Name]; ok && port.Name != "" {
+			if discovery == backendDiscoveryNodePort {
+				// A NodePort is a single port, so there's nothing to offset
+				return params, fmt.Errorf("invalid %s%s annotation: port ranges can't be used with %s %s",
+					r.annotation(portRangeAnnotationPrefix), port.Name, r.annotation(backendDiscoveryAnnotation), backendDiscoveryNodePort)
+			}
+			var err error
+			if start, end, err = parsePortRange(spec, port, portType); err != nil {
+				return params, fmt.Errorf("invalid %s%s annotation: %w", r.annotation(portRangeAnnotationPrefix), port.Name, err)
+			}
+		}
+
+		for listenPort := start; listenPort <= end; listenPort++ {
+			listener := fmt.Sprintf("%d/%t", listenPort, portType == "udp")
+			if previous, ok := listeners[listener]; ok {
+				return params, fmt.Errorf("duplicate listen port %d: ports %s and %s both use it",
+					listenPort, portLabel(service.Spec.Ports[previous], previous), portLabel(port, i))
+			}
+			listeners[listener] = i
+
+			// Discovered endpoints each get an entry of their own
+			if backends := endpoints[port.Name]; len(backends) > 0 {
+				for _, backend := range backends {
+					mapping := triton.PortMapping{
+						Type:        portType,
+						ListenPort:  listenPort,
+						BackendName: backend.Address,
+						BackendPort: backend.Port + listenPort - int(port.Port),
+					}
+					if !validPort(mapping.BackendPort) {
+						return params, fmt.Errorf("port %s forwards listen port %d to invalid endpoint port %d: must be between 1 and 65535",
+							portLabel(port, i), listenPort, mapping.BackendPort)
+					}
+					params.PortMappings = append(params.PortMappings, mapping)
+				}
+				continue
+			}
+
+			// Ports in a range keep the offset between the port and its target
+			mapping := triton.PortMapping{
+				Type:        portType,
+				ListenPort:  listenPort,
+				BackendName: service.Name,
+			}
+			if backendPort > 0 {
+				mapping.BackendPort = backendPort + listenPort - int(port.Port)
+				if !validPort(mapping.BackendPort) {
+					return params, fmt.Errorf("port %s forwards listen port %d to invalid target port %d: must be between 1 and 65535",
+						portLabel(port, i), listenPort, mapping.BackendPort)
+				}
+			}
+			params.PortMappings = append(params.PortMappings, mapping)
 		}
-		params.PortMappings = append(params.PortMappings, mapping)
 	}
 
+	// The portmap usually addresses backends by a single name, so Local policy
+	// can only be enforced by filtering backends in nodeport mode; pass it on
+	// as a hint to the image
+	if service.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyLocal {
+		params.ExternalTrafficPolicy = string(corev1.ServiceExternalTrafficPolicyLocal)
+	}
+
+	// ClientIP session affinity pins each client to one backend
+	params.Sticky = service.Spec.SessionAffinity == corev1.ServiceAffinityClientIP
+
+	// Copy prefixed and propagated labels to instance tags
+	params.Tags, _ = r.labelTags(service)
+
 	// Extract additional configuration from annotations
 	annotations := service.Annotations
 
 	// Check for max_rs
-	if maxRS, ok := annotations["cloud.tritoncompute/max_rs"]; ok {
+	if maxRS, ok := annotations[r.annotation(maxRSAnnotation)]; ok {
 		if maxRSInt, err := strconv.Atoi(maxRS); err == nil {
 			params.MaxBackends = maxRSInt
 		}
 	}
 
+	// Check for max-connections
+	if maxConn, ok := annotations[r.annotation(maxConnectionsAnnotation)]; ok {
+		count, err := strconv.Atoi(strings.TrimSpace(maxConn))
+		if err != nil || count < 1 {
+			return params, fmt.Errorf("invalid %s annotation %q: must be a positive integer", r.annotation(maxConnectionsAnnotation), maxConn)
+		}
+		params.MaxConnections = count
+	}
+
 	// Check for certificate_name
-	if certName, ok := annotations["cloud.tritoncompute/certificate_name"]; ok {
+	if certName, ok := annotations[r.annotation(certificateNameAnnotation)]; ok {
 		params.CertificateName = certName
+	} else if r.DefaultCertificateName != "" && hasHTTPSPort(params.PortMappings) {
+		params.CertificateName = r.DefaultCertificateName
+	}
+
+	// Check for metrics_acl, merged with the controller-wide default
+	params.MetricsACL = mergeMetricsACL(r.DefaultMetricsACL, splitMetricsACL(annotations[r.annotation(metricsACLAnnotation)]))
+
+	// Check for proxy-protocol, either for every port or for a list of
+	// listen ports
+	if proxyProtocol, ok := annotations[r.annotation(proxyProtocolAnnotation)]; ok {
+		enabled, err := strconv.ParseBool(proxyProtocol)
+		if err != nil {
+			if err := setProxyProtocolPorts(params.PortMappings, proxyProtocol); err != nil {
+				return params, fmt.Errorf("invalid %s annotation %q: %w", r.annotation(proxyProtocolAnnotation), proxyProtocol, err)
+			}
+		} else if enabled {
+			for _, mapping := range params.PortMappings {
+				if !proxyProtocolTypes[mapping.Type] {
+					return params, fmt.Errorf("%s is not supported for %s port %d",
+						r.annotation(proxyProtocolAnnotation), mapping.Type, mapping.ListenPort)
+				}
+			}
+		}
+		params.ProxyProtocol = enabled
+	}
+
+	// Check for replicas
+	if replicas, ok := annotations[r.annotation(replicasAnnotation)]; ok {
+		count, err := strconv.Atoi(replicas)
+		if err != nil || count < 1 {
+			return params, fmt.Errorf("invalid %s annotation %q: must be a positive integer", r.annotation(replicasAnnotation), replicas)
+		}
+		params.Replicas = count
 	}
 
-	// Check for metrics_acl
-	if metricsACL, ok := annotations["cloud.tritoncompute/metrics_acl"]; ok {
-		// Split by commas or spaces
-		var aclList []string
-		for _, acl := range strings.FieldsFunc(metricsACL, func(r rune) bool {
-			return r == ',' || r == ' '
-		}) {
-			if acl != "" {
-				aclList = append(aclList, acl)
+	// Check for affinity
+	if affinity, ok := annotations[r.annotation(affinityAnnotation)]; ok {
+		for _, rule := range strings.Split(affinity, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
 			}
+			if err := triton.ValidateAffinityRule(rule); err != nil {
+				return params, err
+			}
+			params.Affinity = append(params.Affinity, rule)
+		}
+	}
+
+	// Check for locality hints
+	for annotation, hints := range map[string]*[]string{
+		localityNearAnnotation: &params.LocalityNear,
+		localityFarAnnotation:  &params.LocalityFar,
+	} {
+		value, ok := annotations[r.annotation(annotation)]
+		if !ok {
+			continue
+		}
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				*hints = append(*hints, id)
+			}
+		}
+	}
+	for annotation, enabled := range map[string]*bool{
+		localityStrictAnnotation: &params.LocalityStrict,
+		spreadReplicasAnnotation: &params.SpreadReplicas,
+	} {
+		value, ok := annotations[r.annotation(annotation)]
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return params, fmt.Errorf("invalid %s annotation %q: must be true or false", r.annotation(annotation), value)
+		}
+		*enabled = parsed
+	}
+	if err := triton.ValidateLocality(params); err != nil {
+		return params, err
+	}
+
+	// Check for package and image overrides, which are looked up when the
+	// instances are provisioned
+	params.Package = strings.TrimSpace(annotations[r.annotation(packageAnnotation)])
+	params.Image = strings.TrimSpace(annotations[r.annotation(imageAnnotation)])
+	params.Datacenter = r.serviceDatacenter(service)
+	for _, name := range strings.Split(annotations[r.annotation(networksAnnotation)], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			params.Networks = append(params.Networks, name)
+		}
+	}
+
+	// Check for source ranges
+	if params.FirewallSourceRanges, err = r.sourceRanges(service); err != nil {
+		return params, err
+	}
+
+	// Check for allocate-public-ip
+	if allocate, ok := annotations[r.annotation(allocatePublicIPAnnotation)]; ok {
+		enabled, err := strconv.ParseBool(allocate)
+		if err != nil {
+			return params, fmt.Errorf("invalid %s annotation %q: must be true or false", r.annotation(allocatePublicIPAnnotation), allocate)
+		}
+		params.AllocatePublicIP = enabled
+	}
+
+	// Check for reload-on-change
+	if reload, ok := annotations[r.annotation(reloadOnChangeAnnotation)]; ok {
+		enabled, err := strconv.ParseBool(reload)
+		if err != nil {
+			return params, fmt.Errorf("invalid %s annotation %q: must be true or false", r.annotation(reloadOnChangeAnnotation), reload)
+		}
+		params.ReloadOnChange = enabled
+	}
+
+	// Check for backend-weights
+	if weights, ok := annotations[r.annotation(backendWeightsAnnotation)]; ok {
+		parsed, err := triton.ParseBackendWeights(weights)
+		if err != nil {
+			return params, fmt.Errorf("invalid %s annotation: %w", r.annotation(backendWeightsAnnotation), err)
+		}
+		params.BackendWeights = parsed
+	}
+
+	// Check for HAProxy timeouts
+	for _, t := range []struct {
+		annotation string
+		timeout    *time.Duration
+	}{
+		{r.annotation(timeoutConnectAnnotation), &params.TimeoutConnect},
+		{r.annotation(timeoutClientAnnotation), &params.TimeoutClient},
+		{r.annotation(timeoutServerAnnotation), &params.TimeoutServer},
+	} {
+		annotation := t.annotation
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
 		}
-		params.MetricsACL = aclList
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < time.Millisecond {
+			return params, fmt.Errorf("invalid %s annotation %q: must be a duration of at least 1ms, e.g. 30s or 1h", annotation, value)
+		}
+		*t.timeout = d
+	}
+
+	if err := r.extractHealthCheck(annotations, &params); err != nil {
+		return params, err
+	}
+
+	if _, err := r.recreatePolicy(service); err != nil {
+		return params, err
+	}
+
+	// Check for provision and delete timeouts
+	for _, t := range []struct {
+		annotation string
+		timeout    *time.Duration
+	}{
+		{r.annotation(provisionTimeoutAnnotation), &params.ProvisionTimeout},
+		{r.annotation(deleteTimeoutAnnotation), &params.DeleteTimeout},
+	} {
+		value, ok := annotations[t.annotation]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < time.Second {
+			return params, fmt.Errorf("invalid %s annotation %q: must be a duration of at least 1s, e.g. 15m", t.annotation, value)
+		}
+		*t.timeout = d
+	}
+
+	// Check for drain-timeout
+	if params.DrainTimeout, err = r.drainTimeout(service); err != nil {
+		return params, err
 	}
 
 	return params, nil
 }
 
+// extractHealthCheck reads the health check annotations into params
+func (r *LoadBalancerReconciler) extractHealthCheck(annotations map[string]string, params *triton.LoadBalancerParams) error {
+	if path, ok := annotations[r.annotation(healthCheckPathAnnotation)]; ok {
+		path = strings.TrimSpace(path)
+		if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " \t\r\n,") {
+			return fmt.Errorf("invalid %s annotation %q: must be an absolute path, e.g. /healthz", r.annotation(healthCheckPathAnnotation), path)
+		}
+		params.HealthCheckPath = path
+	}
+
+	for _, t := range []struct {
+		annotation string
+		duration   *time.Duration
+	}{
+		{r.annotation(healthCheckIntervalAnnotation), &params.HealthCheckInterval},
+		{r.annotation(healthCheckTimeoutAnnotation), &params.HealthCheckTimeout},
+	} {
+		value, ok := annotations[t.annotation]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < time.Millisecond {
+			return fmt.Errorf("invalid %s annotation %q: must be a duration of at least 1ms, e.g. 5s", t.annotation, value)
+		}
+		*t.duration = d
+	}
+	if params.HealthCheckInterval > 0 && params.HealthCheckTimeout > params.HealthCheckInterval {
+		return fmt.Errorf("%s %s must not exceed %s %s", r.annotation(healthCheckTimeoutAnnotation), params.HealthCheckTimeout,
+			r.annotation(healthCheckIntervalAnnotation), params.HealthCheckInterval)
+	}
+
+	if threshold, ok := annotations[r.annotation(healthCheckUnhealthyThresholdAnnotation)]; ok {
+		count, err := strconv.Atoi(strings.TrimSpace(threshold))
+		if err != nil || count < 1 {
+			return fmt.Errorf("invalid %s annotation %q: must be a positive integer", r.annotation(healthCheckUnhealthyThresholdAnnotation), threshold)
+		}
+		params.HealthCheckUnhealthyThreshold = count
+	}
+	return nil
+}
+
+// hasHTTPSPort reports whether any port terminates TLS on the load balancer
+func hasHTTPSPort(mappings []triton.PortMapping) bool {
+	for _, mapping := range mappings {
+		if mapping.Type == "https" {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortRange parses a port-range annotation value of the form
+// <start>-<end>. The range must contain the Service port, fit in the valid
+// port numbers and only apply to tcp or udp passthrough listeners.
+func parsePortRange(spec string, port corev1.ServicePort, portType string) (int, int, error) {
+	if portType != "tcp" && portType != "udp" {
+		return 0, 0, fmt.Errorf("port ranges are only supported for tcp and udp ports, not %s", portType)
+	}
+
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not of the form <start>-<end>", spec)
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startStr))
+	if err != nil || !validPort(start) {
+		return 0, 0, fmt.Errorf("invalid start port %q", startStr)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endStr))
+	if err != nil || !validPort(end) {
+		return 0, 0, fmt.Errorf("invalid end port %q", endStr)
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("start port %d is greater than end port %d", start, end)
+	}
+	if size := end - start + 1; size > maxPortRangeSize {
+		return 0, 0, fmt.Errorf("range of %d ports exceeds the limit of %d", size, maxPortRangeSize)
+	}
+	if int(port.Port) < start || int(port.Port) > end {
+		return 0, 0, fmt.Errorf("range %d-%d does not contain port %d", start, end, port.Port)
+	}
+	if target := int(port.TargetPort.IntVal); target > 0 && target+end-int(port.Port) > 65535 {
+		return 0, 0, fmt.Errorf("range %d-%d maps past the last backend port", start, end)
+	}
+	return start, end, nil
+}
+
+// portLabel names a Service port for error messages, falling back to its
+// position when the port is unnamed
+func portLabel(port corev1.ServicePort, index int) string {
+	if port.Name != "" {
+		return fmt.Sprintf("%q", port.Name)
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// setProxyProtocolPorts enables PROXY protocol on the listeners of mappings
+// whose listen port is in ports, a comma-separated list
+func setProxyProtocolPorts(mappings []triton.PortMapping, ports string) error {
+	for _, entry := range strings.Split(ports, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		port, err := strconv.Atoi(entry)
+		if err != nil || !validPort(port) {
+			return fmt.Errorf("must be true, false or comma-separated listen ports")
+		}
+
+		found := false
+		for i := range mappings {
+			if mappings[i].ListenPort == port && proxyProtocolTypes[mappings[i].Type] {
+				mappings[i].ProxyProtocol = true
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("no tcp, http or https listener on port %d", port)
+		}
+	}
+	return nil
+}
+
+// validPort reports whether p is a usable TCP or UDP port number
+func validPort(p int) bool {
+	return p >= 1 && p <= 65535
+}
+
+// splitMetricsACL splits a metrics ACL by commas or spaces
+func splitMetricsACL(metricsACL string) []string {
+	var aclList []string
+	for _, acl := range strings.FieldsFunc(metricsACL, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		if acl != "" {
+			aclList = append(aclList, acl)
+		}
+	}
+	return aclList
+}
+
+// mergeMetricsACL combines ACL lists in order, dropping duplicates
+func mergeMetricsACL(lists ...[]string) []string {
+	var merged []string
+	seen := map[string]bool{}
+	for _, list := range lists {
+		for _, acl := range list {
+			if !seen[acl] {
+				seen[acl] = true
+				merged = append(merged, acl)
+			}
+		}
+	}
+	return merged
+}
+
+// portMappingDiff describes how a load balancer's port mappings change on update
+type portMappingDiff struct {
+	added   []string
+	removed []string
+	changed []string
+}
+
+// empty returns true if the diff contains no changes
+func (d portMappingDiff) empty() bool {
+	return len(d.added) == 0 && len(d.removed) == 0 && len(d.changed) == 0
+}
+
+// String summarizes the diff for events and logs
+func (d portMappingDiff) String() string {
+	var parts []string
+	if len(d.added) > 0 {
+		parts = append(parts, "added "+strings.Join(d.added, ","))
+	}
+	if len(d.removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(d.removed, ","))
+	}
+	if len(d.changed) > 0 {
+		parts = append(parts, "changed "+strings.Join(d.changed, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// diffPortMappings compares port mappings keyed by listen port. With
+// endpoint discovery a listen port has a mapping per endpoint, and those are
+// compared as a group.
+func diffPortMappings(existing, desired []triton.PortMapping) portMappingDiff {
+	var diff portMappingDiff
+
+	existingPorts, existingByPort := groupPortMappings(existing)
+	desiredPorts, desiredByPort := groupPortMappings(desired)
+
+	for _, port := range desiredPorts {
+		old, ok := existingByPort[port]
+		if !ok {
+			diff.added = append(diff.added, desiredByPort[port])
+		} else if old != desiredByPort[port] {
+			diff.changed = append(diff.changed, old+" -> "+desiredByPort[port])
+		}
+	}
+
+	for _, port := range existingPorts {
+		if _, ok := desiredByPort[port]; !ok {
+			diff.removed = append(diff.removed, existingByPort[port])
+		}
+	}
+
+	return diff
+}
+
+// groupPortMappings returns the listen ports of mappings in order of first
+// appearance, and the mappings of each joined by spaces
+func groupPortMappings(mappings []triton.PortMapping) ([]int, map[int]string) {
+	var ports []int
+	byPort := make(map[int]string, len(mappings))
+	for _, mapping := range mappings {
+		if group, ok := byPort[mapping.ListenPort]; ok {
+			byPort[mapping.ListenPort] = group + " " + mapping.String()
+			continue
+		}
+		ports = append(ports, mapping.ListenPort)
+		byPort[mapping.ListenPort] = mapping.String()
+	}
+	return ports, byPort
+}
+
+// recordEvent emits an event on the Service if an event recorder is configured
+func (r *LoadBalancerReconciler) recordEvent(service *corev1.Service, eventType, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Event(service, eventType, reason, message)
+}
+
+// recordLastError writes the error message and timestamp to the Service annotations
+// so users can see why provisioning failed without access to controller logs
+func (r *LoadBalancerReconciler) recordLastError(ctx context.Context, service *corev1.Service, reconcileErr error) {
+	// The error may be the reconcile deadline itself, so don't inherit it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	msg := truncateMessage(reconcileErr.Error())
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if service.Annotations == nil {
+		service.Annotations = map[string]string{}
+	}
+	service.Annotations[r.annotation(lastErrorAnnotation)] = msg
+	service.Annotations[r.annotation(lastErrorTimeAnnotation)] = time.Now().UTC().Format(time.RFC3339)
+
+	if err := r.Patch(ctx, service, patch); err != nil {
+		r.loggerFor(ctx, service).Error(err, "Failed to record last error on Service")
+	}
+}
+
+// setInstanceIDAnnotation records the ID of the first instance of the load
+// balancer, or removes the record when id is empty
+func (r *LoadBalancerReconciler) setInstanceIDAnnotation(ctx context.Context, service *corev1.Service, id string) {
+	if service.Annotations[r.annotation(instanceIDAnnotation)] == id {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if id == "" {
+		delete(service.Annotations, r.annotation(instanceIDAnnotation))
+	} else {
+		if service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		service.Annotations[r.annotation(instanceIDAnnotation)] = id
+	}
+
+	if err := r.Patch(ctx, service, patch); err != nil {
+		r.loggerFor(ctx, service).Error(err, "Failed to record load balancer instance on Service")
+	}
+}
+
+// handleFailedInstance reports a load balancer instance stuck in a terminal
+// state and, if its recreate policy allows, deletes it so the next reconcile
+// provisions a replacement
+func (r *LoadBalancerReconciler) handleFailedInstance(ctx context.Context, service *corev1.Service, failed *triton.InstanceFailedError) (ctrl.Result, error) {
+	log := r.loggerFor(ctx, service)
+	log.Error(failed, "Load balancer instance failed", "instanceID", failed.InstanceID, "state", failed.State)
+	r.recordEvent(service, corev1.EventTypeWarning, "ProvisioningFailed", failed.Error())
+
+	if policy, err := r.recreatePolicy(service); err != nil || policy == RecreatePolicyNever {
+		return ctrl.Result{}, failed
+	}
+	return r.recreateInstance(ctx, service, failed)
+}
+
+// recreateInstance deletes an unhealthy load balancer instance so the next
+// reconcile provisions it again
+func (r *LoadBalancerReconciler) recreateInstance(ctx context.Context, service *corev1.Service, failed *triton.InstanceFailedError) (ctrl.Result, error) {
+	log := r.loggerFor(ctx, service)
+	if err := r.TritonClient.DeleteInstance(ctx, failed.InstanceID); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to delete failed load balancer instance %s: %w", failed.InstanceID, err)
+	}
+	log.Info("Deleted failed load balancer instance, it will be recreated", "instanceID", failed.InstanceID)
+	if service.Annotations[r.annotation(instanceIDAnnotation)] == failed.InstanceID {
+		r.setInstanceIDAnnotation(ctx, service, "")
+	}
+	r.recordEvent(service, corev1.EventTypeNormal, "FailedInstanceDeleted",
+		fmt.Sprintf("deleted instance %s in state %s so it can be recreated", failed.InstanceID, failed.State))
+	return r.setState(ctx, service, StateProvisioning, fmt.Sprintf("recreating failed instance %s", failed.InstanceID)), nil
+}
+
+// setDNSNamesAnnotation records the CNS names of the load balancer on the
+// Service, or removes the record when there are none
+func (r *LoadBalancerReconciler) setDNSNamesAnnotation(ctx context.Context, service *corev1.Service, names []string) {
+	value := strings.Join(names, ",")
+	if current, ok := service.Annotations[r.annotation(dnsNamesAnnotation)]; current == value && (ok || value == "") {
+		return
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if value == "" {
+		delete(service.Annotations, r.annotation(dnsNamesAnnotation))
+	} else {
+		if service.Annotations == nil {
+			service.Annotations = map[string]string{}
+		}
+		service.Annotations[r.annotation(dnsNamesAnnotation)] = value
+	}
+
+	if err := r.Patch(ctx, service, patch); err != nil {
+		r.loggerFor(ctx, service).Error(err, "Failed to record DNS names on Service")
+	}
+}
+
+// clearLastError removes any previously recorded error annotations from the Service
+func (r *LoadBalancerReconciler) clearLastError(ctx context.Context, service *corev1.Service) {
+	_, hasError := service.Annotations[r.annotation(lastErrorAnnotation)]
+	_, hasTime := service.Annotations[r.annotation(lastErrorTimeAnnotation)]
+	if !hasError && !hasTime {
+		return
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	delete(service.Annotations, r.annotation(lastErrorAnnotation))
+	delete(service.Annotations, r.annotation(lastErrorTimeAnnotation))
+
+	if err := r.Patch(ctx, service, patch); err != nil {
+		r.loggerFor(ctx, service).Error(err, "Failed to clear last error on Service")
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *LoadBalancerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Service{}).
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Service{}, builder.WithPredicates(
+			loadBalancerServicePredicate(r.finalizerName()), serviceChangedPredicate(r.AnnotationPrefix))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.servicesForSecret)).
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(r.serviceForEndpointSlice)).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.servicesForNode), builder.WithPredicates(nodeChangedPredicate()))
+	if r.UseLoadBalancerObjects {
+		b = b.Owns(&v1alpha1.TritonLoadBalancer{})
+	}
+	return b.
 		WithOptions(controller.Options{
-			MaxConcurrentReconciles: 5,
+			MaxConcurrentReconciles: concurrentReconciles(r.ConcurrentReconciles),
+			RateLimiter:             errorRateLimiter(r.maxRetryBackoff()),
 		}).
 		Complete(r)
 }
 
+// isPublicIPError reports whether err is a failure to attach or release a
+// public IP, which leaves the instance intact and is retried on requeue
+func isPublicIPError(err error) bool {
+	var publicIPErr *triton.PublicIPError
+	return errors.As(err, &publicIPErr)
+}
+
+// recordPublicIPError emits a warning event if err is a public IP failure
+func (r *LoadBalancerReconciler) recordPublicIPError(service *corev1.Service, err error) {
+	if isPublicIPError(err) {
+		r.recordEvent(service, corev1.EventTypeWarning, "PublicIPAllocationFailed", err.Error())
+	}
+}
+
 // isTransientError checks if the error is transient and should be retried
 func isTransientError(err error) bool {
-	// Add logic to detect transient errors like network timeouts, rate limits, etc.
-	if err == nil {
-		return false
-	}
-	errStr := err.Error()
-	return strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "connection refused") ||
-		strings.Contains(errStr, "rate limit")
+	return errors.Is(err, context.DeadlineExceeded) || triton.IsTransientError(err)
 }
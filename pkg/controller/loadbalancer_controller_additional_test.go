@@ -3,18 +3,28 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr/testr"
+	tritonerrors "github.com/joyent/triton-go/v2/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/triton/loadbalancer-controller/pkg/dns"
 	"github.com/triton/loadbalancer-controller/pkg/triton"
 )
 
@@ -24,66 +34,183 @@ type MockTritonClient struct {
 	updateErr     error
 	deleteErr     error
 	getErr        error
+	stateErr      error
+	adoptErr      error
+	createState   string
 	loadBalancers map[string]*triton.LoadBalancerParams
 	instances     map[string]*triton.TritonInstance
 	createCalled  int
 	updateCalled  int
 	deleteCalled  int
 	getCalled     int
+	stateCalled   int
+	adoptCalled   int
+	// deleteID is the instance ID the last DeleteLoadBalancer was given
+	deleteID string
+	// createDatacenter is the datacenter the last CreateLoadBalancer was sent to
+	createDatacenter string
+	// drainStarted records when each load balancer started draining
+	drainStarted map[string]time.Time
+
+	deletedInstances []string
+	// updatedTags are the tags the last UpdateTags was given
+	updatedTags map[string]string
 }
 
 func NewMockTritonClient() *MockTritonClient {
 	return &MockTritonClient{
 		loadBalancers: make(map[string]*triton.LoadBalancerParams),
 		instances:     make(map[string]*triton.TritonInstance),
+		drainStarted:  make(map[string]time.Time),
 	}
 }
 
-func (m *MockTritonClient) CreateLoadBalancer(ctx context.Context, params triton.LoadBalancerParams) error {
+func (m *MockTritonClient) CreateLoadBalancer(ctx context.Context, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
 	m.createCalled++
+	m.createDatacenter = triton.DatacenterFromContext(ctx)
 	if m.createErr != nil {
-		return m.createErr
+		return nil, m.createErr
 	}
 	m.loadBalancers[params.Name] = &params
+	state := m.createState
+	if state == "" {
+		state = "running"
+	}
 	m.instances[params.Name] = &triton.TritonInstance{
-		ID:   "test-id",
-		Name: params.Name,
-		IPs:  []string{"203.0.113.1", "10.0.0.1"},
+		ID:    "test-id",
+		Name:  params.Name,
+		State: state,
+		IPs:   []string{"203.0.113.1", "10.0.0.1"},
 	}
-	return nil
+	return m.instances[params.Name], nil
+}
+
+// instanceName returns the name of the instance with ID id, if there is one,
+// or else name, as the Triton client looks load balancers up by ID first
+func (m *MockTritonClient) instanceName(id, name string) string {
+	// Created instances share an ID, so prefer the named one
+	if instance, ok := m.instances[name]; ok && instance.ID == id {
+		return name
+	}
+	for instanceName, instance := range m.instances {
+		if id != "" && instance.ID == id {
+			return instanceName
+		}
+	}
+	return name
 }
 
-func (m *MockTritonClient) UpdateLoadBalancer(ctx context.Context, name string, params triton.LoadBalancerParams) error {
+func (m *MockTritonClient) UpdateLoadBalancer(ctx context.Context, id, name string, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
 	m.updateCalled++
 	if m.updateErr != nil {
-		return m.updateErr
+		return nil, m.updateErr
 	}
+	name = m.instanceName(id, name)
 	m.loadBalancers[name] = &params
-	return nil
+	return m.instances[name], nil
 }
 
-func (m *MockTritonClient) DeleteLoadBalancer(ctx context.Context, name string) error {
+func (m *MockTritonClient) DeleteLoadBalancer(ctx context.Context, id, name string) error {
 	m.deleteCalled++
+	m.deleteID = id
 	if m.deleteErr != nil {
 		return m.deleteErr
 	}
+	name = m.instanceName(id, name)
 	delete(m.loadBalancers, name)
 	delete(m.instances, name)
 	return nil
 }
 
-func (m *MockTritonClient) GetLoadBalancer(ctx context.Context, name string) (*triton.LoadBalancerParams, error) {
+func (m *MockTritonClient) GetLoadBalancer(ctx context.Context, id, name string) (*triton.LoadBalancerParams, error) {
 	m.getCalled++
 	if m.getErr != nil {
 		return nil, m.getErr
 	}
-	return m.loadBalancers[name], nil
+	return m.loadBalancers[m.instanceName(id, name)], nil
 }
 
 func (m *MockTritonClient) GetInstanceByName(ctx context.Context, name string) (*triton.TritonInstance, error) {
 	return m.instances[name], nil
 }
 
+func (m *MockTritonClient) GetInstanceState(ctx context.Context, id string) (string, error) {
+	m.stateCalled++
+	if m.stateErr != nil {
+		return "", m.stateErr
+	}
+	for _, instance := range m.instances {
+		if instance.ID == id {
+			return instance.State, nil
+		}
+	}
+	return "deleted", nil
+}
+
+func (m *MockTritonClient) UpdateTags(ctx context.Context, id, name string, tags map[string]string) error {
+	m.updatedTags = tags
+	return nil
+}
+
+func (m *MockTritonClient) AdoptLoadBalancer(ctx context.Context, ref string, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
+	m.adoptCalled++
+	if m.adoptErr != nil {
+		return nil, m.adoptErr
+	}
+	for name, instance := range m.instances {
+		if instance.ID == ref || instance.Name == ref {
+			delete(m.instances, name)
+			instance.Name = params.Name
+			m.instances[params.Name] = instance
+			m.loadBalancers[params.Name] = &params
+			return instance, nil
+		}
+	}
+	return nil, fmt.Errorf("no instance named %s", ref)
+}
+
+func (m *MockTritonClient) DrainLoadBalancer(ctx context.Context, id, name string) (time.Time, error) {
+	name = m.instanceName(id, name)
+	if _, ok := m.instances[name]; !ok {
+		return time.Time{}, nil
+	}
+	if _, ok := m.drainStarted[name]; !ok {
+		m.drainStarted[name] = time.Now()
+	}
+	return m.drainStarted[name], nil
+}
+
+func (m *MockTritonClient) DeleteInstance(ctx context.Context, id string) error {
+	m.deletedInstances = append(m.deletedInstances, id)
+	for name, instance := range m.instances {
+		if instance.ID == id {
+			delete(m.instances, name)
+			delete(m.loadBalancers, name)
+		}
+	}
+	return nil
+}
+
+func (m *MockTritonClient) ListLoadBalancers(ctx context.Context) ([]*triton.LoadBalancerParams, error) {
+	var loadBalancers []*triton.LoadBalancerParams
+	for name, instance := range m.instances {
+		lb := triton.LoadBalancerParams{Name: name}
+		if params, ok := m.loadBalancers[name]; ok {
+			lb = *params
+		}
+		if service, ok := instance.Tags["k8s-service"].(string); ok && service != name {
+			lb.ServiceName = service
+		}
+		if namespace, ok := instance.Tags["k8s-namespace"].(string); ok {
+			lb.Namespace = namespace
+		}
+		lb.Instance = instance
+		loadBalancers = append(loadBalancers, &lb)
+	}
+	sort.Slice(loadBalancers, func(i, j int) bool { return loadBalancers[i].Name < loadBalancers[j].Name })
+	return loadBalancers, nil
+}
+
 // TestReconcileDeleteLoadBalancer tests deletion of load balancers
 func TestReconcileDeleteLoadBalancer(t *testing.T) {
 	// Create a service with deletion timestamp
@@ -152,6 +279,415 @@ func TestReconcileDeleteLoadBalancer(t *testing.T) {
 	// The service might have been garbage collected after finalizer removal
 }
 
+// TestReconcileDeleteFailureKeepsFinalizer tests that a Service whose load
+// balancer could not be deleted keeps its finalizer so the delete is retried
+func TestReconcileDeleteFailureKeepsFinalizer(t *testing.T) {
+	deletionTime := metav1.NewTime(time.Now())
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-service",
+			Namespace:         "default",
+			DeletionTimestamp: &deletionTime,
+			Finalizers:        []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{Name: "test-service"}
+	mockClient.deleteErr = errors.New("cloudapi unavailable")
+	recorder := record.NewFakeRecorder(10)
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err == nil {
+		t.Fatal("expected the failed delete to be returned for a retry")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("expected the Service to still exist: %v", err)
+	}
+	if !reflect.DeepEqual(updatedService.Finalizers, []string{"loadbalancer.triton.io/finalizer"}) {
+		t.Errorf("expected the finalizer to be kept, got %v", updatedService.Finalizers)
+	}
+	if reasons := eventReasons(recorder); !reflect.DeepEqual(reasons, []string{"DeleteFailed"}) {
+		t.Errorf("expected a DeleteFailed event, got %v", reasons)
+	}
+	if ready := meta.FindStatusCondition(updatedService.Status.Conditions, LoadBalancerReadyCondition); ready == nil || ready.Reason != StateDeleteFailed {
+		t.Errorf("expected a DeleteFailed state, got %+v", ready)
+	}
+}
+
+// eventReasons drains the events recorded so far and returns their reasons
+func eventReasons(recorder *record.FakeRecorder) []string {
+	var reasons []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			fields := strings.SplitN(event, " ", 3)
+			if len(fields) > 1 {
+				reasons = append(reasons, fields[1])
+			}
+		default:
+			return reasons
+		}
+	}
+}
+
+// TestReconcileLifecycleEvents tests that provisioning and deleting a load
+// balancer are reported as events on its Service
+func TestReconcileLifecycleEvents(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: NewMockTritonClient(),
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	if reasons := eventReasons(recorder); !reflect.DeepEqual(reasons, []string{"Provisioning", "Provisioned"}) {
+		t.Errorf("expected Provisioning and Provisioned events, got %v", reasons)
+	}
+
+	current := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, current); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if err := client.Delete(ctx, current); err != nil {
+		t.Fatalf("failed to delete service: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("failed to reconcile the deletion: %v", err)
+	}
+	if reasons := eventReasons(recorder); !reflect.DeepEqual(reasons, []string{"Deleted"}) {
+		t.Errorf("expected a Deleted event, got %v", reasons)
+	}
+}
+
+// TestReconcileTypeChangeReleasesLoadBalancer tests that a Service changed
+// away from type LoadBalancer has its load balancer deleted and finalizer removed
+func TestReconcileTypeChangeReleasesLoadBalancer(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{Name: "test-service"}
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	if mockClient.deleteCalled != 1 {
+		t.Errorf("expected delete to be called once, got %d", mockClient.deleteCalled)
+	}
+	if _, exists := mockClient.loadBalancers["test-service"]; exists {
+		t.Error("expected load balancer to be deleted")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if len(updatedService.Finalizers) != 0 {
+		t.Errorf("expected the finalizer to be removed, got %v", updatedService.Finalizers)
+	}
+}
+
+func TestManagesServiceLoadBalancerClass(t *testing.T) {
+	class := func(name string) *string { return &name }
+	tests := []struct {
+		name            string
+		serviceType     corev1.ServiceType
+		class           *string
+		configured      string
+		ignoreUnclassed bool
+		expected        bool
+	}{
+		{name: "no class", serviceType: corev1.ServiceTypeLoadBalancer, expected: true},
+		{name: "no class ignored", serviceType: corev1.ServiceTypeLoadBalancer, ignoreUnclassed: true},
+		{name: "default class", serviceType: corev1.ServiceTypeLoadBalancer, class: class(DefaultLoadBalancerClass), expected: true},
+		{name: "other class", serviceType: corev1.ServiceTypeLoadBalancer, class: class("metallb.io/metallb")},
+		{name: "configured class", serviceType: corev1.ServiceTypeLoadBalancer, class: class("example.com/lb"), configured: "example.com/lb", expected: true},
+		{name: "default class when another is configured", serviceType: corev1.ServiceTypeLoadBalancer, class: class(DefaultLoadBalancerClass), configured: "example.com/lb"},
+		{name: "not a load balancer", serviceType: corev1.ServiceTypeClusterIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &LoadBalancerReconciler{LoadBalancerClass: tt.configured, IgnoreUnclassed: tt.ignoreUnclassed}
+			service := &corev1.Service{Spec: corev1.ServiceSpec{Type: tt.serviceType, LoadBalancerClass: tt.class}}
+			if got := r.managesService(service); got != tt.expected {
+				t.Errorf("managesService() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReconcileOtherLoadBalancerClass tests that Services of another load
+// balancer class are left alone
+func TestReconcileOtherLoadBalancerClass(t *testing.T) {
+	class := "metallb.io/metallb"
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type:              corev1.ServiceTypeLoadBalancer,
+			LoadBalancerClass: &class,
+			Ports:             []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.createCalled != 0 || mockClient.getCalled != 0 {
+		t.Error("expected a Service of another class not to get a load balancer")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if len(updatedService.Finalizers) != 0 {
+		t.Errorf("expected no finalizer on a Service of another class, got %v", updatedService.Finalizers)
+	}
+}
+
+// TestReconcileSourceRanges tests that spec.loadBalancerSourceRanges are
+// only applied when the controller manages firewall rules, and reported in a
+// Service condition either way
+func TestReconcileSourceRanges(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{DefaultFinalizerName},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                     corev1.ServiceTypeLoadBalancer,
+			LoadBalancerSourceRanges: []string{"203.0.113.0/24"},
+			Ports:                    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+	client := newLoadBalancerObjectClient(t, service)
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       scheme.Scheme,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.createCalled != 0 {
+		t.Error("expected no load balancer while the source ranges can't be enforced")
+	}
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	condition := meta.FindStatusCondition(updatedService.Status.Conditions, SourceRangesCondition)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "FirewallNotManaged" {
+		t.Errorf("expected an unenforced source ranges condition, got %+v", condition)
+	}
+
+	reconciler.ManageFirewall = true
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	lb := mockClient.loadBalancers["test-service"]
+	if lb == nil || !reflect.DeepEqual(lb.FirewallSourceRanges, []string{"203.0.113.0/24"}) {
+		t.Fatalf("expected the source ranges to be passed to the load balancer, got %+v", lb)
+	}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(updatedService.Status.Conditions, SourceRangesCondition) {
+		t.Errorf("expected the source ranges to be reported as enforced, got %+v", updatedService.Status.Conditions)
+	}
+
+	updatedService.Spec.LoadBalancerSourceRanges = nil
+	if err := client.Update(ctx, updatedService); err != nil {
+		t.Fatalf("failed to update service: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if condition := meta.FindStatusCondition(updatedService.Status.Conditions, SourceRangesCondition); condition != nil {
+		t.Errorf("expected the condition to be removed without source ranges, got %+v", condition)
+	}
+}
+
+// TestReconcileLoadBalancerState tests that the LoadBalancerReady condition
+// follows the load balancer through its states and sets the requeue
+func TestReconcileLoadBalancerState(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{DefaultFinalizerName},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+	client := newLoadBalancerObjectClient(t, service)
+
+	mockClient := NewMockTritonClient()
+	mockClient.createState = "provisioning"
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       scheme.Scheme,
+		TritonClient: mockClient,
+		PollInterval: 3 * time.Second,
+		ResyncPeriod: time.Hour,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	reconcileState := func(wantState string, wantRequeue time.Duration) *metav1.Condition {
+		t.Helper()
+		result, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("reconcile: (%v)", err)
+		}
+		if result.RequeueAfter != wantRequeue {
+			t.Errorf("expected a requeue after %v in state %s, got %v", wantRequeue, wantState, result)
+		}
+		updatedService := &corev1.Service{}
+		if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+			t.Fatalf("failed to get service: %v", err)
+		}
+		ready := meta.FindStatusCondition(updatedService.Status.Conditions, LoadBalancerReadyCondition)
+		if ready == nil || ready.Reason != wantState {
+			t.Fatalf("expected state %s, got %+v", wantState, ready)
+		}
+		wantStatus := metav1.ConditionFalse
+		if wantState == StateReady {
+			wantStatus = metav1.ConditionTrue
+		}
+		if ready.Status != wantStatus {
+			t.Errorf("expected status %s in state %s, got %s", wantStatus, wantState, ready.Status)
+		}
+		return ready
+	}
+
+	reconcileState(StateProvisioning, 3*time.Second)
+
+	mockClient.instances["test-service"].State = "running"
+	ready := reconcileState(StateReady, time.Hour)
+
+	mockClient.updateErr = &triton.Error{Class: triton.ErrTransient, Err: errors.New("connection timeout")}
+	degraded := reconcileState(StateDegraded, 5*time.Second)
+	if degraded.Message != "connection timeout" {
+		t.Errorf("expected the error as the cause, got %q", degraded.Message)
+	}
+	if degraded.LastTransitionTime.Before(&ready.LastTransitionTime) {
+		t.Errorf("expected the transition to be timestamped, got %v before %v", degraded.LastTransitionTime, ready.LastTransitionTime)
+	}
+
+	mockClient.updateErr = nil
+	reconcileState(StateReady, time.Hour)
+}
+
 // TestReconcileUpdateLoadBalancer tests updating existing load balancers
 func TestReconcileUpdateLoadBalancer(t *testing.T) {
 	service := &corev1.Service{
@@ -187,9 +723,10 @@ func TestReconcileUpdateLoadBalancer(t *testing.T) {
 		MaxBackends: 64,
 	}
 	mockClient.instances["test-service"] = &triton.TritonInstance{
-		ID:   "existing-id",
-		Name: "test-service",
-		IPs:  []string{"203.0.113.1"},
+		ID:    "existing-id",
+		Name:  "test-service",
+		State: "running",
+		IPs:   []string{"203.0.113.1"},
 	}
 
 	// Create reconciler
@@ -310,7 +847,7 @@ func TestReconcileTransientError(t *testing.T) {
 
 	// Create mock Triton client that returns timeout error
 	mockClient := NewMockTritonClient()
-	mockClient.createErr = errors.New("connection timeout")
+	mockClient.createErr = &triton.Error{Class: triton.ErrTransient, Err: errors.New("connection timeout")}
 
 	// Create reconciler
 	reconciler := &LoadBalancerReconciler{
@@ -318,6 +855,8 @@ func TestReconcileTransientError(t *testing.T) {
 		Log:          testr.New(t),
 		Scheme:       s,
 		TritonClient: mockClient,
+
+		MaxRetryBackoff: 15 * time.Second,
 	}
 
 	// Call Reconcile
@@ -329,20 +868,133 @@ func TestReconcileTransientError(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	result, err := reconciler.Reconcile(ctx, req)
 
-	// Should not return error for transient failures
-	if err != nil {
-		t.Fatalf("expected no error for transient failure, got: %v", err)
-	}
+	// Retries back off exponentially up to MaxRetryBackoff
+	for _, want := range []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second, 15 * time.Second} {
+		result, err := reconciler.Reconcile(ctx, req)
 
-	// Should request requeue after delay
-	if result.RequeueAfter != 30*time.Second {
-		t.Errorf("expected requeue after 30s, got %v", result.RequeueAfter)
+		// Should not return error for transient failures
+		if err != nil {
+			t.Fatalf("expected no error for transient failure, got: %v", err)
+		}
+		if result.RequeueAfter != want {
+			t.Errorf("expected requeue after %v, got %v", want, result.RequeueAfter)
+		}
 	}
-}
 
-// TestIsTransientError tests the transient error detection
+	// A successful reconcile starts the backoff over
+	mockClient.createErr = nil
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	mockClient.updateErr = &triton.Error{Class: triton.ErrTransient, Err: errors.New("connection timeout")}
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("expected no error for transient failure, got: %v", err)
+	}
+	if result.RequeueAfter != 5*time.Second {
+		t.Errorf("expected requeue after 5s once the backoff was reset, got %v", result.RequeueAfter)
+	}
+}
+
+// TestIsTransientError tests the transient error detection
+// hangingTritonClient blocks every lookup until the context is done
+type hangingTritonClient struct {
+	*MockTritonClient
+}
+
+func (c *hangingTritonClient) GetLoadBalancer(ctx context.Context, id, name string) (*triton.LoadBalancerParams, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestReconcileTimeoutRequeues(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:           client,
+		Log:              testr.New(t),
+		Scheme:           s,
+		TritonClient:     &hangingTritonClient{MockTritonClient: NewMockTritonClient()},
+		ReconcileTimeout: 10 * time.Millisecond,
+		Recorder:         recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("expected a timed out reconcile to requeue without error, got %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a timed out reconcile to be requeued")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if !strings.Contains(updatedService.Annotations[lastErrorAnnotation], context.DeadlineExceeded.Error()) {
+		t.Errorf("expected the deadline to be recorded as the last error, got %q", updatedService.Annotations[lastErrorAnnotation])
+	}
+	reasons := eventReasons(recorder)
+	if len(reasons) == 0 || reasons[len(reasons)-1] != "TimedOut" {
+		t.Errorf("expected a TimedOut event last, got %v", reasons)
+	}
+}
+
+// TestReconcileQuotaExceeded tests that a create refused for the account's
+// quota is reported as such
+func TestReconcileQuotaExceeded(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+	mockClient := NewMockTritonClient()
+	mockClient.createErr = &triton.Error{Class: triton.ErrQuotaExceeded, Err: errors.New("memory quota exceeded")}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       scheme.Scheme,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); !errors.Is(err, triton.ErrQuotaExceeded) {
+		t.Fatalf("expected the quota error to be returned, got %v", err)
+	}
+	if reasons := eventReasons(recorder); reasons[len(reasons)-1] != "QuotaExceeded" {
+		t.Errorf("expected a QuotaExceeded event, got %v", reasons)
+	}
+}
+
 func TestIsTransientError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -355,25 +1007,51 @@ func TestIsTransientError(t *testing.T) {
 			expected: false,
 		},
 		{
-			name:     "timeout error",
+			// Messages are never matched, only error classes
+			name:     "untyped timeout message",
 			err:      errors.New("connection timeout"),
-			expected: true,
+			expected: false,
 		},
 		{
 			name:     "connection refused",
-			err:      errors.New("connection refused"),
+			err:      fmt.Errorf("failed to list machines: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}),
 			expected: true,
 		},
 		{
-			name:     "rate limit error",
-			err:      errors.New("rate limit exceeded"),
+			name:     "throttled by CloudAPI",
+			err:      &tritonerrors.APIError{StatusCode: 429, Code: "RequestThrottled"},
 			expected: true,
 		},
+		{
+			name:     "typed quota error",
+			err:      fmt.Errorf("failed to create load balancer: %w", triton.ErrQuotaExceeded),
+			expected: false,
+		},
 		{
 			name:     "permanent error",
 			err:      errors.New("invalid credentials"),
 			expected: false,
 		},
+		{
+			name:     "typed transient error",
+			err:      fmt.Errorf("failed to update load balancer: %w", triton.ErrTransient),
+			expected: true,
+		},
+		{
+			name:     "typed rate limit error",
+			err:      fmt.Errorf("failed to list instances: %w", triton.ErrRateLimited),
+			expected: true,
+		},
+		{
+			name:     "typed auth error with misleading message",
+			err:      fmt.Errorf("request timeout signature rejected: %w", triton.ErrAuth),
+			expected: false,
+		},
+		{
+			name:     "reconcile deadline",
+			err:      fmt.Errorf("failed to get machine: %w", context.DeadlineExceeded),
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -437,6 +1115,56 @@ func TestExtractLoadBalancerParamsEdgeCases(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:        "unset target port defaults to listen port",
+			annotations: nil,
+			ports: []corev1.ServicePort{
+				{Name: "tcp", Port: 6379},
+			},
+			validate: func(t *testing.T, params triton.LoadBalancerParams) {
+				if len(params.PortMappings) != 1 {
+					t.Fatalf("expected 1 port mapping, got %d", len(params.PortMappings))
+				}
+				if params.PortMappings[0].BackendPort != 6379 {
+					t.Errorf("expected backend port 6379, got %d", params.PortMappings[0].BackendPort)
+				}
+			},
+		},
+		{
+			name: "proxy protocol enabled",
+			annotations: map[string]string{
+				"cloud.tritoncompute/proxy-protocol": "true",
+			},
+			ports: []corev1.ServicePort{
+				{Name: "tcp", Port: 6379, TargetPort: intstr.FromInt(6379)},
+				{Port: 443, TargetPort: intstr.FromInt(8443)},
+			},
+			validate: func(t *testing.T, params triton.LoadBalancerParams) {
+				if !params.ProxyProtocol {
+					t.Error("expected proxy protocol to be enabled")
+				}
+			},
+		},
+		{
+			name: "protocol annotation overrides port heuristics",
+			annotations: map[string]string{
+				"cloud.tritoncompute/protocol.tls-passthrough": "tcp",
+				"cloud.tritoncompute/protocol.admin":           "http",
+			},
+			ports: []corev1.ServicePort{
+				{Name: "tls-passthrough", Port: 443, TargetPort: intstr.FromInt(8443)},
+				{Name: "admin", Port: 9000, TargetPort: intstr.FromInt(9000)},
+				{Name: "web", Port: 80, TargetPort: intstr.FromInt(8080)},
+			},
+			validate: func(t *testing.T, params triton.LoadBalancerParams) {
+				want := []string{"tcp", "http", "http"}
+				for i, pm := range params.PortMappings {
+					if pm.Type != want[i] {
+						t.Errorf("port %d: expected type %s, got %s", pm.ListenPort, want[i], pm.Type)
+					}
+				}
+			},
+		},
 		{
 			name:        "TCP port detection",
 			annotations: nil,
@@ -479,3 +1207,3029 @@ func TestExtractLoadBalancerParamsEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractLoadBalancerParamsInvalidProtocolOverride(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/protocol.web": "quic",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "web", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Fatal("expected an error for an unknown protocol override")
+	}
+}
+
+func TestExtractLoadBalancerParamsDuplicatePorts(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Name: "http-alt", Port: 80, TargetPort: intstr.FromInt(8081)},
+			},
+		},
+	}
+
+	_, err := reconciler.extractLoadBalancerParams(service)
+	if err == nil {
+		t.Fatal("expected an error for a duplicate listen port")
+	}
+	if !strings.Contains(err.Error(), `"http"`) || !strings.Contains(err.Error(), `"http-alt"`) {
+		t.Errorf("expected the error to name both ports, got %v", err)
+	}
+
+	// TCP and UDP listeners on the same port do not conflict
+	service.Spec.Ports = []corev1.ServicePort{
+		{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(53)},
+		{Name: "dns-udp", Port: 53, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromInt(53)},
+	}
+	service.Annotations = map[string]string{"cloud.tritoncompute/protocol.dns-udp": "udp"}
+	if _, err := reconciler.extractLoadBalancerParams(service); err != nil {
+		t.Errorf("expected tcp and udp listeners on one port to be accepted, got %v", err)
+	}
+}
+
+func TestExtractLoadBalancerParamsUDPPorts(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(5353)},
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromInt(5353)},
+				{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(8443)},
+				{Name: "quic", Port: 443, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromInt(8443)},
+			},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	var entries []string
+	for _, mapping := range params.PortMappings {
+		entries = append(entries, mapping.String())
+	}
+	want := []string{
+		"tcp://53:test-service:5353",
+		"udp://53:test-service:5353",
+		"https://443:test-service:8443",
+		"udp://443:test-service:8443",
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected port mappings %v, got %v", want, entries)
+	}
+
+	service.Annotations = map[string]string{"cloud.tritoncompute/protocol.quic": "https"}
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Error("expected a non-udp protocol override of a UDP port to be rejected")
+	}
+}
+
+func TestReconcileDuplicatePortsEmitsWarning(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Port: 80, TargetPort: intstr.FromInt(8081)},
+			},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err == nil {
+		t.Fatal("expected reconcile to fail for duplicate listen ports")
+	}
+	if mockClient.createCalled != 0 {
+		t.Errorf("expected no load balancer to be created, got %d creates", mockClient.createCalled)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning InvalidConfiguration") {
+			t.Errorf("expected InvalidConfiguration warning, got %q", event)
+		}
+	default:
+		t.Error("expected a warning event")
+	}
+}
+
+func TestExtractLoadBalancerParamsPortRange(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "ftp",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/port-range.passive": "30000-30002",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "control", Port: 21, TargetPort: intstr.FromInt(2121)},
+				{Name: "passive", Port: 30000, TargetPort: intstr.FromInt(40000)},
+			},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	expected := []triton.PortMapping{
+		{Type: "tcp", ListenPort: 21, BackendName: "ftp", BackendPort: 2121},
+		{Type: "tcp", ListenPort: 30000, BackendName: "ftp", BackendPort: 40000},
+		{Type: "tcp", ListenPort: 30001, BackendName: "ftp", BackendPort: 40001},
+		{Type: "tcp", ListenPort: 30002, BackendName: "ftp", BackendPort: 40002},
+	}
+	if !reflect.DeepEqual(params.PortMappings, expected) {
+		t.Errorf("expected port mappings %v, got %v", expected, params.PortMappings)
+	}
+
+	for _, spec := range []string{"30002-30000", "30000", "29000-29999", "30000-40000", "0-30000"} {
+		service.Annotations["cloud.tritoncompute/port-range.passive"] = spec
+		if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+			t.Errorf("expected an error for port range %q", spec)
+		}
+	}
+
+	// Ranges overlapping another port are duplicates
+	service.Annotations["cloud.tritoncompute/port-range.passive"] = "30000-30002"
+	service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{Name: "extra", Port: 30001})
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Error("expected an error for a port inside the range")
+	}
+}
+
+func TestExtractLoadBalancerParamsInvalidProxyProtocol(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/proxy-protocol": "sometimes",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Fatal("expected an error for a non-boolean proxy-protocol annotation")
+	}
+}
+
+func TestExtractLoadBalancerParamsProxyProtocolPorts(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/proxy-protocol": "443, 8443",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Name: "https", Port: 443, TargetPort: intstr.FromInt(8443)},
+				{Name: "alt", Port: 8443, TargetPort: intstr.FromInt(8443)},
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromInt(53)},
+			},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if params.ProxyProtocol {
+		t.Error("expected PROXY protocol to be enabled per listener only")
+	}
+	var entries []string
+	for _, mapping := range params.PortMappings {
+		entries = append(entries, mapping.String())
+	}
+	want := []string{
+		"http://80:test-service:8080",
+		"proxy-https://443:test-service:8443",
+		"proxy-tcp://8443:test-service:8443",
+		"udp://53:test-service:53",
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("expected port mappings %v, got %v", want, entries)
+	}
+
+	for _, ports := range []string{"53", "9000", "443,https"} {
+		service.Annotations["cloud.tritoncompute/proxy-protocol"] = ports
+		if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+			t.Errorf("expected an error for proxy-protocol ports %q", ports)
+		}
+	}
+}
+
+func TestExtractLoadBalancerParamsExternalTrafficPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   corev1.ServiceExternalTrafficPolicy
+		expected string
+	}{
+		{name: "unset", policy: "", expected: ""},
+		{name: "cluster", policy: corev1.ServiceExternalTrafficPolicyCluster, expected: ""},
+		{name: "local", policy: corev1.ServiceExternalTrafficPolicyLocal, expected: "Local"},
+	}
+
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+				Spec: corev1.ServiceSpec{
+					ExternalTrafficPolicy: tt.policy,
+					Ports:                 []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if params.ExternalTrafficPolicy != tt.expected {
+				t.Errorf("expected external traffic policy %q, got %q", tt.expected, params.ExternalTrafficPolicy)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsSessionAffinity(t *testing.T) {
+	tests := []struct {
+		name     string
+		affinity corev1.ServiceAffinity
+		expected bool
+	}{
+		{name: "unset", affinity: "", expected: false},
+		{name: "none", affinity: corev1.ServiceAffinityNone, expected: false},
+		{name: "client ip", affinity: corev1.ServiceAffinityClientIP, expected: true},
+	}
+
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+				Spec: corev1.ServiceSpec{
+					SessionAffinity: tt.affinity,
+					Ports:           []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if params.Sticky != tt.expected {
+				t.Errorf("expected sticky %v, got %v", tt.expected, params.Sticky)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsDefaultMetricsACL(t *testing.T) {
+	tests := []struct {
+		name       string
+		defaults   []string
+		annotation string
+		want       []string
+	}{
+		{
+			name:     "default only",
+			defaults: []string{"10.0.0.0/8"},
+			want:     []string{"10.0.0.0/8"},
+		},
+		{
+			name:       "service only",
+			annotation: "192.168.0.0/16",
+			want:       []string{"192.168.0.0/16"},
+		},
+		{
+			name:       "combined and de-duplicated",
+			defaults:   []string{"10.0.0.0/8", "172.16.0.0/12"},
+			annotation: "192.168.0.0/16, 10.0.0.0/8",
+			want:       []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &LoadBalancerReconciler{
+				Log:               testr.New(t),
+				DefaultMetricsACL: tt.defaults,
+			}
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+			if tt.annotation != "" {
+				service.Annotations = map[string]string{"cloud.tritoncompute/metrics_acl": tt.annotation}
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(params.MetricsACL, tt.want) {
+				t.Errorf("expected metrics ACL %v, got %v", tt.want, params.MetricsACL)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsDefaultCertificate(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log:                    testr.New(t),
+		DefaultCertificateName: "*.example.com",
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		ports       []corev1.ServicePort
+		expected    string
+	}{
+		{
+			name:     "default applied to https",
+			ports:    []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt(8443)}},
+			expected: "*.example.com",
+		},
+		{
+			name:     "default skipped without https",
+			ports:    []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+			expected: "",
+		},
+		{
+			name:        "annotation overrides default",
+			annotations: map[string]string{"cloud.tritoncompute/certificate_name": "shop.example.org"},
+			ports:       []corev1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt(8443)}},
+			expected:    "shop.example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-service", Annotations: tt.annotations},
+				Spec:       corev1.ServiceSpec{Ports: tt.ports},
+			}
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if err != nil {
+				t.Fatalf("extractLoadBalancerParams: %v", err)
+			}
+			if params.CertificateName != tt.expected {
+				t.Errorf("expected certificate name %q, got %q", tt.expected, params.CertificateName)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsBackendWeights(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/backend-weights": "web-v1=80,web-v2=20",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if expected := map[string]int{"web-v1": 80, "web-v2": 20}; !reflect.DeepEqual(params.BackendWeights, expected) {
+		t.Errorf("expected backend weights %v, got %v", expected, params.BackendWeights)
+	}
+
+	service.Annotations["cloud.tritoncompute/backend-weights"] = "web-v1=-1"
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Error("expected an error for a negative backend weight")
+	}
+}
+
+func TestExtractLoadBalancerParamsTimeouts(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        [3]time.Duration
+		wantErr     bool
+	}{
+		{name: "unset"},
+		{
+			name: "all set",
+			annotations: map[string]string{
+				"cloud.tritoncompute/timeout-connect": "5s",
+				"cloud.tritoncompute/timeout-client":  "1h",
+				"cloud.tritoncompute/timeout-server":  "90m",
+			},
+			want: [3]time.Duration{5 * time.Second, time.Hour, 90 * time.Minute},
+		},
+		{
+			name:        "not a duration",
+			annotations: map[string]string{"cloud.tritoncompute/timeout-client": "forever"},
+			wantErr:     true,
+		},
+		{
+			name:        "missing unit",
+			annotations: map[string]string{"cloud.tritoncompute/timeout-server": "30"},
+			wantErr:     true,
+		},
+		{
+			name:        "negative",
+			annotations: map[string]string{"cloud.tritoncompute/timeout-connect": "-5s"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-service",
+					Annotations: tt.annotations,
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractLoadBalancerParams: %v", err)
+			}
+			got := [3]time.Duration{params.TimeoutConnect, params.TimeoutClient, params.TimeoutServer}
+			if got != tt.want {
+				t.Errorf("expected connect/client/server timeouts %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsHealthCheck(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        triton.LoadBalancerParams
+		wantErr     bool
+	}{
+		{name: "unset"},
+		{
+			name: "all set",
+			annotations: map[string]string{
+				"cloud.tritoncompute/health-check-path":                "/healthz",
+				"cloud.tritoncompute/health-check-interval":            "10s",
+				"cloud.tritoncompute/health-check-timeout":             "2s",
+				"cloud.tritoncompute/health-check-unhealthy-threshold": "3",
+			},
+			want: triton.LoadBalancerParams{
+				HealthCheckPath:               "/healthz",
+				HealthCheckInterval:           10 * time.Second,
+				HealthCheckTimeout:            2 * time.Second,
+				HealthCheckUnhealthyThreshold: 3,
+			},
+		},
+		{
+			name:        "relative path",
+			annotations: map[string]string{"cloud.tritoncompute/health-check-path": "healthz"},
+			wantErr:     true,
+		},
+		{
+			name:        "interval without unit",
+			annotations: map[string]string{"cloud.tritoncompute/health-check-interval": "10"},
+			wantErr:     true,
+		},
+		{
+			name: "timeout longer than interval",
+			annotations: map[string]string{
+				"cloud.tritoncompute/health-check-interval": "2s",
+				"cloud.tritoncompute/health-check-timeout":  "5s",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "zero threshold",
+			annotations: map[string]string{"cloud.tritoncompute/health-check-unhealthy-threshold": "0"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-service",
+					Annotations: tt.annotations,
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractLoadBalancerParams: %v", err)
+			}
+			if params.HealthCheckPath != tt.want.HealthCheckPath ||
+				params.HealthCheckInterval != tt.want.HealthCheckInterval ||
+				params.HealthCheckTimeout != tt.want.HealthCheckTimeout ||
+				params.HealthCheckUnhealthyThreshold != tt.want.HealthCheckUnhealthyThreshold {
+				t.Errorf("expected health check %+v, got %+v", tt.want, params)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsWaitTimeouts(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		wantProvision time.Duration
+		wantDelete    time.Duration
+		wantErr       bool
+	}{
+		{name: "unset"},
+		{
+			name: "both set",
+			annotations: map[string]string{
+				"cloud.tritoncompute/provision-timeout": "15m",
+				"cloud.tritoncompute/delete-timeout":    " 10m ",
+			},
+			wantProvision: 15 * time.Minute,
+			wantDelete:    10 * time.Minute,
+		},
+		{name: "seconds without unit", annotations: map[string]string{"cloud.tritoncompute/provision-timeout": "900"}, wantErr: true},
+		{name: "too short", annotations: map[string]string{"cloud.tritoncompute/delete-timeout": "500ms"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-service",
+					Annotations: tt.annotations,
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractLoadBalancerParams: expected error %v, got %v", tt.wantErr, err)
+			}
+			if params.ProvisionTimeout != tt.wantProvision || params.DeleteTimeout != tt.wantDelete {
+				t.Errorf("expected provision timeout %s and delete timeout %s, got %s and %s",
+					tt.wantProvision, tt.wantDelete, params.ProvisionTimeout, params.DeleteTimeout)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsMaxConnections(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "set", value: "500", want: 500},
+		{name: "zero", value: "0", wantErr: true},
+		{name: "negative", value: "-10", wantErr: true},
+		{name: "not a number", value: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-service",
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+			if tt.value != "" {
+				service.Annotations = map[string]string{"cloud.tritoncompute/max-connections": tt.value}
+			}
+
+			params, err := reconciler.extractLoadBalancerParams(service)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractLoadBalancerParams: %v", err)
+			}
+			if params.MaxConnections != tt.want {
+				t.Errorf("expected MaxConnections %d, got %d", tt.want, params.MaxConnections)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsPackageAndImage(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/package": " g4-highcpu-4G ",
+				"cloud.tritoncompute/image":   "haproxy@2.0",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if params.Package != "g4-highcpu-4G" || params.Image != "haproxy@2.0" {
+		t.Errorf("expected the package and image overrides, got %q and %q", params.Package, params.Image)
+	}
+}
+
+func TestExtractLoadBalancerParamsNetworks(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/networks": "external, my-fabric,",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if want := []string{"external", "my-fabric"}; !reflect.DeepEqual(params.Networks, want) {
+		t.Errorf("expected networks %v, got %v", want, params.Networks)
+	}
+}
+
+func TestExtractLoadBalancerParamsFirewallSourceRanges(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/firewall-source-ranges": "203.0.113.0/24, 198.51.100.7",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if want := []string{"203.0.113.0/24", "198.51.100.7"}; !reflect.DeepEqual(params.FirewallSourceRanges, want) {
+		t.Errorf("expected source ranges %v, got %v", want, params.FirewallSourceRanges)
+	}
+
+	service.Annotations["cloud.tritoncompute/firewall-source-ranges"] = "10.0.0.0/33"
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Error("expected an invalid source range to be rejected")
+	}
+}
+
+func TestExtractLoadBalancerParamsReloadOnChange(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/reload-on-change": "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if !params.ReloadOnChange {
+		t.Error("expected ReloadOnChange to be set")
+	}
+
+	service.Annotations["cloud.tritoncompute/reload-on-change"] = "sometimes"
+	if _, err := reconciler.extractLoadBalancerParams(service); err == nil {
+		t.Error("expected an error for an invalid reload-on-change value")
+	}
+}
+
+func TestExtractLoadBalancerParamsIgnoresNodePorts(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	for _, allocate := range []bool{true, false} {
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-service",
+			},
+			Spec: corev1.ServiceSpec{
+				Type:                          corev1.ServiceTypeLoadBalancer,
+				AllocateLoadBalancerNodePorts: &allocate,
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+					{Name: "metrics", Port: 9090, TargetPort: intstr.FromInt(9100)},
+				},
+			},
+		}
+		if allocate {
+			service.Spec.Ports[0].NodePort = 30080
+			service.Spec.Ports[1].NodePort = 30090
+		}
+
+		params, err := reconciler.extractLoadBalancerParams(service)
+		if err != nil {
+			t.Fatalf("allocateLoadBalancerNodePorts=%t: unexpected error: %v", allocate, err)
+		}
+		// The load balancer reaches backends by name, never through NodePorts
+		want := []triton.PortMapping{
+			{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080},
+			{Type: "tcp", ListenPort: 9090, BackendName: "test-service", BackendPort: 9100},
+		}
+		if !reflect.DeepEqual(params.PortMappings, want) {
+			t.Errorf("allocateLoadBalancerNodePorts=%t: expected port mappings %+v, got %+v",
+				allocate, want, params.PortMappings)
+		}
+	}
+}
+
+func TestExtractLoadBalancerParamsPortBounds(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+
+	tests := []struct {
+		name    string
+		port    corev1.ServicePort
+		wantErr string
+	}{
+		{
+			name: "valid",
+			port: corev1.ServicePort{Name: "web", Port: 8443, TargetPort: intstr.FromInt(65535)},
+		},
+		{
+			name:    "zero listen port",
+			port:    corev1.ServicePort{Name: "web", Port: 0, TargetPort: intstr.FromInt(8080)},
+			wantErr: `port "web" has invalid port 0`,
+		},
+		{
+			name:    "target port above range",
+			port:    corev1.ServicePort{Name: "web", Port: 8080, TargetPort: intstr.FromInt(70000)},
+			wantErr: `port "web" has invalid target port 70000`,
+		},
+		{
+			name:    "listen port above range",
+			port:    corev1.ServicePort{Port: 70000, TargetPort: intstr.FromInt(8080)},
+			wantErr: "port #0 has invalid port 70000",
+		},
+		{
+			name: "named target port",
+			port: corev1.ServicePort{Name: "web", Port: 8080, TargetPort: intstr.FromString("http")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{tt.port}},
+			}
+
+			_, err := reconciler.extractLoadBalancerParams(service)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtractLoadBalancerParamsAnnotationPrefix(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log:              testr.New(t),
+		AnnotationPrefix: "lb.example.com",
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Annotations: map[string]string{
+				"lb.example.com/max_rs":           "64",
+				"lb.example.com/replicas":         "2",
+				"lb.example.com/protocol.web":     "https",
+				"lb.example.com/certificate_name": "web.example.com",
+				// Annotations under the default prefix belong to another controller
+				"cloud.tritoncompute/max_rs":         "8",
+				"cloud.tritoncompute/proxy-protocol": "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "web", Port: 8443, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("extractLoadBalancerParams: %v", err)
+	}
+	if params.MaxBackends != 64 || params.Replicas != 2 || params.CertificateName != "web.example.com" {
+		t.Errorf("expected annotations under the custom prefix to be read, got %+v", params)
+	}
+	if len(params.PortMappings) != 1 || params.PortMappings[0].Type != "https" {
+		t.Errorf("expected the protocol override under the custom prefix, got %+v", params.PortMappings)
+	}
+	if params.ProxyProtocol {
+		t.Error("expected annotations under the default prefix to be ignored")
+	}
+
+	service.Annotations["lb.example.com/replicas"] = "none"
+	_, err = reconciler.extractLoadBalancerParams(service)
+	if err == nil || !strings.Contains(err.Error(), "lb.example.com/replicas") {
+		t.Errorf("expected the error to name the prefixed annotation, got %v", err)
+	}
+}
+
+func TestExtractLoadBalancerParamsTagLabels(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Labels: map[string]string{
+				"cloud.tritoncompute.tag/cost-center": "eng",
+				"cloud.tritoncompute.tag/managed-by":  "someone-else",
+				"app":                                 "web",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"cost-center": "eng"}
+	if !reflect.DeepEqual(params.Tags, want) {
+		t.Errorf("expected tags %v, got %v", want, params.Tags)
+	}
+}
+
+func TestExtractLoadBalancerParamsLocality(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log: testr.New(t),
+	}
+	newService := func(annotations map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-service", Annotations: annotations},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		}
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(newService(map[string]string{
+		"cloud.tritoncompute/locality-far":    "0bd8a5e6-7f4c-4b2e-9c51-3f6f1a2d7e90, 5c1e2f8a-3b7d-4c6e-8f9a-1d2b3c4e5f60",
+		"cloud.tritoncompute/locality-strict": "true",
+		"cloud.tritoncompute/spread-replicas": "true",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"0bd8a5e6-7f4c-4b2e-9c51-3f6f1a2d7e90", "5c1e2f8a-3b7d-4c6e-8f9a-1d2b3c4e5f60"}
+	if !reflect.DeepEqual(params.LocalityFar, want) || !params.LocalityStrict || !params.SpreadReplicas {
+		t.Errorf("expected strict far hints %v with spread replicas, got %+v", want, params)
+	}
+
+	for _, annotations := range []map[string]string{
+		{"cloud.tritoncompute/locality-near": "backend-1"},
+		{"cloud.tritoncompute/spread-replicas": "yes please"},
+		{
+			"cloud.tritoncompute/locality-far": "0bd8a5e6-7f4c-4b2e-9c51-3f6f1a2d7e90",
+			"cloud.tritoncompute/affinity":     "instance!=db-*",
+		},
+	} {
+		if _, err := reconciler.extractLoadBalancerParams(newService(annotations)); err == nil {
+			t.Errorf("expected an error for %v", annotations)
+		}
+	}
+}
+
+func TestExtractLoadBalancerParamsPropagateLabels(t *testing.T) {
+	reconciler := &LoadBalancerReconciler{
+		Log:             testr.New(t),
+		PropagateLabels: []string{"team", "cost-center", "loadbalancer", "missing"},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-service",
+			Labels: map[string]string{
+				"team":                                "payments",
+				"cost-center":                         "ops",
+				"cloud.tritoncompute.tag/cost-center": "eng",
+				"loadbalancer":                        "false",
+				"app":                                 "web",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The prefixed label wins and reserved tags are never set
+	want := map[string]string{"team": "payments", "cost-center": "eng"}
+	if !reflect.DeepEqual(params.Tags, want) {
+		t.Errorf("expected tags %v, got %v", want, params.Tags)
+	}
+}
+
+// TestReconcileRecordsLastError tests that reconcile errors are surfaced on the Service
+func TestReconcileRecordsLastError(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				},
+			},
+		},
+	}
+
+	// Create runtime scheme and client
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	// Create mock Triton client that returns a permanent error
+	mockClient := NewMockTritonClient()
+	mockClient.createErr = errors.New("invalid credentials")
+
+	// Create reconciler
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err == nil {
+		t.Fatal("expected error from reconcile")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+
+	if msg := updatedService.Annotations[lastErrorAnnotation]; msg != "failed to create load balancer: invalid credentials" {
+		t.Errorf("unexpected last error annotation: %q", msg)
+	}
+	if _, err := time.Parse(time.RFC3339, updatedService.Annotations[lastErrorTimeAnnotation]); err != nil {
+		t.Errorf("expected RFC3339 last error time, got %q", updatedService.Annotations[lastErrorTimeAnnotation])
+	}
+
+	// A successful reconcile should clear the recorded error
+	mockClient.createErr = nil
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+
+	if _, exists := updatedService.Annotations[lastErrorAnnotation]; exists {
+		t.Error("expected last error annotation to be cleared")
+	}
+	if _, exists := updatedService.Annotations[lastErrorTimeAnnotation]; exists {
+		t.Error("expected last error time annotation to be cleared")
+	}
+}
+
+// TestRecordLastErrorTruncates tests that long error messages are truncated
+func TestRecordLastErrorTruncates(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	reconciler := &LoadBalancerReconciler{
+		Client: client,
+		Log:    testr.New(t),
+		Scheme: s,
+	}
+
+	longErr := errors.New(strings.Repeat("x", 2*maxLastErrorLength))
+	reconciler.recordLastError(context.Background(), service, longErr)
+
+	msg := service.Annotations[lastErrorAnnotation]
+	if len(msg) != maxLastErrorLength {
+		t.Errorf("expected message of length %d, got %d", maxLastErrorLength, len(msg))
+	}
+	if !strings.HasSuffix(msg, "...") {
+		t.Errorf("expected truncated message to end with ellipsis, got %q", msg[len(msg)-10:])
+	}
+}
+
+// TestDiffPortMappings tests port mapping diff computation
+func TestDiffPortMappings(t *testing.T) {
+	httpMapping := triton.PortMapping{Type: "http", ListenPort: 80, BackendName: "web", BackendPort: 8080}
+	httpsMapping := triton.PortMapping{Type: "https", ListenPort: 443, BackendName: "web", BackendPort: 8443}
+	tcpMapping := triton.PortMapping{Type: "tcp", ListenPort: 5432, BackendName: "db", BackendPort: 5432}
+
+	tests := []struct {
+		name     string
+		existing []triton.PortMapping
+		desired  []triton.PortMapping
+		added    int
+		removed  int
+		changed  int
+	}{
+		{
+			name:     "no changes",
+			existing: []triton.PortMapping{httpMapping},
+			desired:  []triton.PortMapping{httpMapping},
+		},
+		{
+			name:     "add only",
+			existing: []triton.PortMapping{httpMapping},
+			desired:  []triton.PortMapping{httpMapping, httpsMapping},
+			added:    1,
+		},
+		{
+			name:     "remove only",
+			existing: []triton.PortMapping{httpMapping, httpsMapping},
+			desired:  []triton.PortMapping{httpMapping},
+			removed:  1,
+		},
+		{
+			name:     "full replace",
+			existing: []triton.PortMapping{httpMapping, httpsMapping},
+			desired:  []triton.PortMapping{tcpMapping},
+			added:    1,
+			removed:  2,
+		},
+		{
+			name:     "backend port changed",
+			existing: []triton.PortMapping{httpMapping},
+			desired:  []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "web", BackendPort: 9090}},
+			changed:  1,
+		},
+		{
+			name:     "endpoint added",
+			existing: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "10.0.0.1", BackendPort: 8080}},
+			desired: []triton.PortMapping{
+				{Type: "http", ListenPort: 80, BackendName: "10.0.0.1", BackendPort: 8080},
+				{Type: "http", ListenPort: 80, BackendName: "10.0.0.2", BackendPort: 8080},
+			},
+			changed: 1,
+		},
+		{
+			name: "endpoints unchanged",
+			existing: []triton.PortMapping{
+				{Type: "http", ListenPort: 80, BackendName: "10.0.0.1", BackendPort: 8080},
+				{Type: "http", ListenPort: 80, BackendName: "10.0.0.2", BackendPort: 8080},
+			},
+			desired: []triton.PortMapping{
+				{Type: "http", ListenPort: 80, BackendName: "10.0.0.1", BackendPort: 8080},
+				{Type: "http", ListenPort: 80, BackendName: "10.0.0.2", BackendPort: 8080},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffPortMappings(tt.existing, tt.desired)
+			if len(diff.added) != tt.added {
+				t.Errorf("expected %d added, got %v", tt.added, diff.added)
+			}
+			if len(diff.removed) != tt.removed {
+				t.Errorf("expected %d removed, got %v", tt.removed, diff.removed)
+			}
+			if len(diff.changed) != tt.changed {
+				t.Errorf("expected %d changed, got %v", tt.changed, diff.changed)
+			}
+			if diff.empty() != (tt.added+tt.removed+tt.changed == 0) {
+				t.Errorf("unexpected empty() result for %v", diff)
+			}
+		})
+	}
+}
+
+// TestReconcileUpdateEmitsPortMappingEvent tests that port changes are reported as events
+func TestReconcileUpdateEmitsPortMappingEvent(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				},
+				{
+					Name:       "https",
+					Port:       443,
+					TargetPort: intstr.FromInt(8443),
+				},
+			},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	// Existing load balancer only knows about the http port
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+		Name: "test-service",
+		PortMappings: []triton.PortMapping{
+			{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "PortMappingsChanged") || !strings.Contains(event, "added https://443:test-service:8443") {
+			t.Errorf("unexpected event: %s", event)
+		}
+	default:
+		t.Error("expected a PortMappingsChanged event")
+	}
+}
+
+// TestReconcileCreatePublishesStatus tests that status is set from the created instance
+func TestReconcileCreatePublishesStatus(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				},
+			},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	// Only the existence check should query Triton
+	if mockClient.getCalled != 1 {
+		t.Errorf("expected GetLoadBalancer to be called once, got %d", mockClient.getCalled)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+
+	ingress := updatedService.Status.LoadBalancer.Ingress
+	if len(ingress) != 1 || ingress[0].IP != "203.0.113.1" {
+		t.Errorf("expected ingress IP 203.0.113.1, got %v", ingress)
+	}
+}
+
+// TestReconcilePublishesPortStatus tests that ingress port status mirrors the service ports
+func TestReconcilePublishesPortStatus(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(8443)},
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP, TargetPort: intstr.FromInt(53)},
+				{Name: "signaling", Port: 3868, Protocol: corev1.ProtocolSCTP, TargetPort: intstr.FromInt(3868)},
+			},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: NewMockTritonClient(),
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+
+	ingress := updatedService.Status.LoadBalancer.Ingress
+	if len(ingress) != 1 {
+		t.Fatalf("expected 1 ingress entry, got %v", ingress)
+	}
+	ports := ingress[0].Ports
+	if len(ports) != 4 {
+		t.Fatalf("expected 4 port statuses, got %v", ports)
+	}
+	for i, port := range ports {
+		if port.Port != service.Spec.Ports[i].Port {
+			t.Errorf("port %d: expected %d, got %d", i, service.Spec.Ports[i].Port, port.Port)
+		}
+	}
+	if ports[0].Protocol != corev1.ProtocolTCP || ports[0].Error != nil {
+		t.Errorf("expected healthy TCP status for port 80, got %+v", ports[0])
+	}
+	if ports[2].Protocol != corev1.ProtocolUDP || ports[2].Error != nil {
+		t.Errorf("expected healthy UDP status for port 53, got %+v", ports[2])
+	}
+	if ports[3].Protocol != corev1.ProtocolSCTP || ports[3].Error == nil || *ports[3].Error != portErrorUnsupportedProtocol {
+		t.Errorf("expected SCTP port to be marked unsupported, got %+v", ports[3])
+	}
+}
+
+// TestReconcileResumesInterruptedProvisioning tests that an instance whose
+// provisioning was cut off by shutdown is recorded and waited on instead of recreated
+func TestReconcileResumesInterruptedProvisioning(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.createErr = &triton.ProvisionInterruptedError{InstanceID: "provisioning-id", Err: context.Canceled}
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	// Cancel the context as the manager does on SIGTERM
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := reconciler.Reconcile(ctx, req); err == nil {
+		t.Fatal("expected the interrupted create to return an error")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(context.Background(), req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := updatedService.Annotations[instanceIDAnnotation]; got != "provisioning-id" {
+		t.Fatalf("expected instance ID annotation provisioning-id, got %q", got)
+	}
+
+	// The instance finished provisioning while the controller was down
+	mockClient.createErr = nil
+	mockClient.instances["test-service"] = &triton.TritonInstance{
+		ID:    "provisioning-id",
+		Name:  "test-service",
+		State: "running",
+		IPs:   []string{"203.0.113.1"},
+	}
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+		Name:         "test-service",
+		PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+	}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.stateCalled != 1 {
+		t.Errorf("expected GetInstanceState to be called once, got %d", mockClient.stateCalled)
+	}
+	if mockClient.createCalled != 1 {
+		t.Errorf("expected no second create, got %d create calls", mockClient.createCalled)
+	}
+
+	if err := client.Get(context.Background(), req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := updatedService.Annotations[instanceIDAnnotation]; got != "provisioning-id" {
+		t.Errorf("expected the instance ID annotation to be kept after resuming, got %q", got)
+	}
+}
+
+// TestReconcileCustomFinalizerName tests that a configured finalizer name is used
+func TestReconcileCustomFinalizerName(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       80,
+					TargetPort: intstr.FromInt(8080),
+				},
+			},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	reconciler := &LoadBalancerReconciler{
+		Client:        client,
+		Log:           testr.New(t),
+		Scheme:        s,
+		TritonClient:  NewMockTritonClient(),
+		FinalizerName: "staging.triton.io/finalizer",
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+
+	if len(updatedService.Finalizers) != 1 || updatedService.Finalizers[0] != "staging.triton.io/finalizer" {
+		t.Errorf("expected custom finalizer, got %v", updatedService.Finalizers)
+	}
+}
+
+func TestReconcileRecordsDNSNames(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+		Name:         "test-service",
+		PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+	}
+	mockClient.instances["test-service"] = &triton.TritonInstance{
+		ID:    "test-id",
+		Name:  "test-service",
+		State: "running",
+		IPs:   []string{"203.0.113.1"},
+		DNSNames: []string{
+			"test-service.svc.account.us-east-1.cns.example.com",
+			"test-id.inst.account.us-east-1.cns.example.com",
+		},
+	}
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+
+	expected := "test-service.svc.account.us-east-1.cns.example.com,test-id.inst.account.us-east-1.cns.example.com"
+	if got := updatedService.Annotations[dnsNamesAnnotation]; got != expected {
+		t.Errorf("expected %s annotation %q, got %q", dnsNamesAnnotation, expected, got)
+	}
+}
+
+func TestReconcilePublishesHostname(t *testing.T) {
+	tests := []struct {
+		name            string
+		publishHostname bool
+		annotations     map[string]string
+		dnsNames        []string
+		want            corev1.LoadBalancerIngress
+	}{
+		{
+			name:            "flag",
+			publishHostname: true,
+			dnsNames:        []string{"test-id.inst.account.us-east-1.cns.example.com", "test-service.inst.account.us-east-1.cns.example.com"},
+			want:            corev1.LoadBalancerIngress{Hostname: "test-service.inst.account.us-east-1.cns.example.com"},
+		},
+		{
+			name:        "annotation",
+			annotations: map[string]string{publishHostnameAnnotation: "true"},
+			dnsNames:    []string{"test-service.inst.account.us-east-1.cns.example.com"},
+			want:        corev1.LoadBalancerIngress{Hostname: "test-service.inst.account.us-east-1.cns.example.com"},
+		},
+		{
+			name:            "annotation opts out",
+			publishHostname: true,
+			annotations:     map[string]string{publishHostnameAnnotation: "false"},
+			dnsNames:        []string{"test-service.inst.account.us-east-1.cns.example.com"},
+			want:            corev1.LoadBalancerIngress{IP: "203.0.113.1"},
+		},
+		{
+			name:            "no CNS",
+			publishHostname: true,
+			want:            corev1.LoadBalancerIngress{IP: "203.0.113.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-service",
+					Namespace:   "default",
+					Annotations: tt.annotations,
+				},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+			s := scheme.Scheme
+			s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+			client := fake.NewClientBuilder().WithRuntimeObjects(service).WithStatusSubresource(service).Build()
+
+			mockClient := NewMockTritonClient()
+			mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+				Name:         "test-service",
+				PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+			}
+			mockClient.instances["test-service"] = &triton.TritonInstance{
+				ID:       "test-id",
+				Name:     "test-service",
+				State:    "running",
+				IPs:      []string{"203.0.113.1"},
+				DNSNames: tt.dnsNames,
+			}
+
+			reconciler := &LoadBalancerReconciler{
+				Client:          client,
+				Log:             testr.New(t),
+				Scheme:          s,
+				TritonClient:    mockClient,
+				PublishHostname: tt.publishHostname,
+			}
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+			ctx := context.Background()
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("reconcile: (%v)", err)
+			}
+
+			updatedService := &corev1.Service{}
+			if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+				t.Fatalf("failed to get service: %v", err)
+			}
+			ingress := updatedService.Status.LoadBalancer.Ingress
+			if len(ingress) != 1 || ingress[0].IP != tt.want.IP || ingress[0].Hostname != tt.want.Hostname {
+				t.Errorf("expected ingress %+v, got %+v", tt.want, ingress)
+			}
+		})
+	}
+}
+
+func TestReconcileRequeuesProvisioningInstance(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+		Name:         "test-service",
+		PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+	}
+	mockClient.instances["test-service"] = &triton.TritonInstance{
+		ID:    "test-id",
+		Name:  "test-service",
+		State: "provisioning",
+		IPs:   []string{"203.0.113.1"},
+	}
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue while the instance is provisioning")
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if len(updatedService.Status.LoadBalancer.Ingress) != 0 {
+		t.Errorf("expected no ingress until the instance is running, got %v", updatedService.Status.LoadBalancer.Ingress)
+	}
+}
+
+// TestReconcileAsyncProvisioning tests that a load balancer still provisioning
+// after it was created is checked on by later reconciles instead of blocking
+func TestReconcileAsyncProvisioning(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.createState = "provisioning"
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+		PollInterval: 3 * time.Second,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+
+	updatedService := &corev1.Service{}
+	for i := 0; i < 2; i++ {
+		result, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("reconcile: (%v)", err)
+		}
+		if result.RequeueAfter != 3*time.Second {
+			t.Errorf("expected a requeue after the poll interval while provisioning, got %v", result)
+		}
+		if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+			t.Fatalf("failed to get service: %v", err)
+		}
+		if got := updatedService.Annotations[instanceIDAnnotation]; got != "test-id" {
+			t.Fatalf("expected the provisioning instance to be recorded, got %q", got)
+		}
+	}
+	if mockClient.createCalled != 1 || mockClient.updateCalled != 0 {
+		t.Errorf("expected one create and no update while provisioning, got %d creates and %d updates",
+			mockClient.createCalled, mockClient.updateCalled)
+	}
+
+	mockClient.instances["test-service"].State = "running"
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := updatedService.Annotations[instanceIDAnnotation]; got != "test-id" {
+		t.Errorf("expected the instance ID annotation to be kept once running, got %q", got)
+	}
+	if len(updatedService.Status.LoadBalancer.Ingress) == 0 {
+		t.Error("expected the load balancer IP to be published once running")
+	}
+	if reasons := eventReasons(recorder); !reflect.DeepEqual(reasons, []string{"Provisioning", "Provisioned"}) {
+		t.Errorf("expected Provisioning and Provisioned events, got %v", reasons)
+	}
+}
+
+// TestReconcilePollInterval tests that a provisioning load balancer is polled
+// at the configured interval and not requeued once its status is published
+func TestReconcilePollInterval(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+		Name:         "test-service",
+		PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+	}
+	instance := &triton.TritonInstance{
+		ID:    "test-id",
+		Name:  "test-service",
+		State: "provisioning",
+	}
+	mockClient.instances["test-service"] = instance
+
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		PollInterval: 3 * time.Second,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter != 3*time.Second {
+		t.Errorf("expected a requeue after the poll interval while provisioning, got %v", result)
+	}
+
+	// Running but without addresses is still waiting
+	instance.State = "running"
+	result, err = reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter != 3*time.Second {
+		t.Errorf("expected a requeue after the poll interval until the instance has IPs, got %v", result)
+	}
+
+	instance.IPs = []string{"203.0.113.1"}
+	result, err = reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.Requeue || result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue once the status is published, got %v", result)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if ingress := updatedService.Status.LoadBalancer.Ingress; len(ingress) != 1 || ingress[0].IP != "203.0.113.1" {
+		t.Errorf("expected ingress IP 203.0.113.1, got %v", ingress)
+	}
+}
+
+func TestReconcileAdoptsExistingInstance(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{
+				"cloud.tritoncompute/adopt-instance": "legacy-lb",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.instances["legacy-lb"] = &triton.TritonInstance{
+		ID:    "legacy-id",
+		Name:  "legacy-lb",
+		State: "running",
+		IPs:   []string{"203.0.113.7"},
+	}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.adoptCalled != 1 || mockClient.createCalled != 0 {
+		t.Errorf("expected adoption instead of creation, got %d adopts and %d creates",
+			mockClient.adoptCalled, mockClient.createCalled)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	ingress := updatedService.Status.LoadBalancer.Ingress
+	if len(ingress) != 1 || ingress[0].IP != "203.0.113.7" {
+		t.Errorf("expected the adopted instance's address in the status, got %v", ingress)
+	}
+
+	// Once adopted the load balancer is updated like any other
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("second reconcile: (%v)", err)
+	}
+	if mockClient.adoptCalled != 1 || mockClient.updateCalled != 1 {
+		t.Errorf("expected the second reconcile to update, got %d adopts and %d updates",
+			mockClient.adoptCalled, mockClient.updateCalled)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Normal Adopted") {
+			t.Errorf("expected Adopted event, got %q", event)
+		}
+	default:
+		t.Error("expected an Adopted event")
+	}
+}
+
+func TestReconcileFailedInstance(t *testing.T) {
+	for _, autoRecreate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("autoRecreate=%t", autoRecreate), func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test-service",
+					Namespace:  "default",
+					Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			s := scheme.Scheme
+			s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+			client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+			mockClient := NewMockTritonClient()
+			mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+				Name:         "test-service",
+				PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+			}
+			mockClient.instances["test-service"] = &triton.TritonInstance{
+				ID:    "failed-id",
+				Name:  "test-service",
+				State: "failed",
+			}
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &LoadBalancerReconciler{
+				Client:             client,
+				Log:                testr.New(t),
+				Scheme:             s,
+				TritonClient:       mockClient,
+				Recorder:           recorder,
+				AutoRecreateFailed: autoRecreate,
+			}
+
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "test-service",
+					Namespace: "default",
+				},
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), req)
+			select {
+			case event := <-recorder.Events:
+				if !strings.HasPrefix(event, "Warning ProvisioningFailed") {
+					t.Errorf("expected ProvisioningFailed warning, got %q", event)
+				}
+			default:
+				t.Error("expected a ProvisioningFailed warning")
+			}
+
+			if !autoRecreate {
+				if err == nil {
+					t.Error("expected an error for a failed instance")
+				}
+				if len(mockClient.deletedInstances) != 0 {
+					t.Errorf("expected the failed instance to be kept, deleted %v", mockClient.deletedInstances)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("reconcile: (%v)", err)
+			}
+			if result.RequeueAfter == 0 {
+				t.Error("expected a requeue to recreate the instance")
+			}
+			if !reflect.DeepEqual(mockClient.deletedInstances, []string{"failed-id"}) {
+				t.Errorf("expected the failed instance to be deleted, deleted %v", mockClient.deletedInstances)
+			}
+
+			// The next reconcile provisions a replacement
+			if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+				t.Fatalf("second reconcile: (%v)", err)
+			}
+			if mockClient.createCalled != 1 {
+				t.Errorf("expected the load balancer to be recreated, got %d creates", mockClient.createCalled)
+			}
+		})
+	}
+}
+
+func TestReconcileRecreatePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		state       string
+		wantDeleted bool
+		wantEvent   string
+		wantErr     bool
+	}{
+		{name: "stopped, default policy", state: "stopped", wantEvent: "Warning InstanceStopped"},
+		{name: "stopped, OnFailure", policy: "OnFailure", state: "stopped", wantEvent: "Warning InstanceStopped"},
+		{name: "stopped, Always", policy: "Always", state: "stopped", wantDeleted: true, wantEvent: "Warning InstanceStopped"},
+		{name: "deleted, OnFailure", policy: "OnFailure", state: "deleted", wantDeleted: true, wantEvent: "Warning ProvisioningFailed"},
+		{name: "failed, Never", policy: "Never", state: "failed", wantEvent: "Warning ProvisioningFailed", wantErr: true},
+		{name: "invalid policy", policy: "Sometimes", state: "running", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test-service",
+					Namespace:  "default",
+					Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+				Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+					Ingress: []corev1.LoadBalancerIngress{{IP: "203.0.113.1"}, {IP: "203.0.113.2"}},
+				}},
+			}
+			if tt.policy != "" {
+				service.Annotations = map[string]string{recreatePolicyAnnotation: tt.policy}
+			}
+
+			s := scheme.Scheme
+			s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+			client := fake.NewClientBuilder().WithRuntimeObjects(service).WithStatusSubresource(service).Build()
+
+			mockClient := NewMockTritonClient()
+			mockClient.loadBalancers["test-service"] = &triton.LoadBalancerParams{
+				Name:         "test-service",
+				PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+			}
+			lb := &triton.TritonInstance{ID: "lb-id", Name: "test-service", State: "running", IPs: []string{"203.0.113.1"}}
+			lb.Replicas = []*triton.TritonInstance{
+				lb,
+				{ID: "replica-id", Name: "test-service-1", State: tt.state, IPs: []string{"203.0.113.2"}},
+			}
+			mockClient.instances["test-service"] = lb
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &LoadBalancerReconciler{
+				Client:       client,
+				Log:          testr.New(t),
+				Scheme:       s,
+				TritonClient: mockClient,
+				Recorder:     recorder,
+			}
+
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+			ctx := context.Background()
+			_, err := reconciler.Reconcile(ctx, req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcile: expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantEvent != "" {
+				select {
+				case event := <-recorder.Events:
+					if !strings.HasPrefix(event, tt.wantEvent) {
+						t.Errorf("expected a %s event, got %q", tt.wantEvent, event)
+					}
+				default:
+					t.Errorf("expected a %s event", tt.wantEvent)
+				}
+			}
+
+			deleted := len(mockClient.deletedInstances) > 0
+			if deleted != tt.wantDeleted {
+				t.Fatalf("expected the replica to be deleted %v, deleted %v", tt.wantDeleted, mockClient.deletedInstances)
+			}
+			updatedService := &corev1.Service{}
+			if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+				t.Fatalf("failed to get service: %v", err)
+			}
+			wantIngress := []string{"203.0.113.1", "203.0.113.2"}
+			if deleted {
+				if !reflect.DeepEqual(mockClient.deletedInstances, []string{"replica-id"}) {
+					t.Errorf("expected only the unhealthy replica to be deleted, deleted %v", mockClient.deletedInstances)
+				}
+				wantIngress = []string{"203.0.113.1"}
+			}
+			if got := ingressAddresses(updatedService.Status.LoadBalancer.Ingress); !reflect.DeepEqual(got, wantIngress) {
+				t.Errorf("expected ingress %v, got %v", wantIngress, got)
+			}
+		})
+	}
+}
+
+func TestReconcileHealthCheckPeriod(t *testing.T) {
+	for _, tt := range []struct {
+		policy string
+		want   time.Duration
+	}{
+		{policy: "", want: 0},
+		{policy: "OnFailure", want: healthCheckPeriod},
+	} {
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "test-service",
+				Namespace:  "default",
+				Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		}
+		if tt.policy != "" {
+			service.Annotations = map[string]string{recreatePolicyAnnotation: tt.policy}
+		}
+		s := scheme.Scheme
+		s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+		reconciler := &LoadBalancerReconciler{
+			Client:       fake.NewClientBuilder().WithRuntimeObjects(service).Build(),
+			Log:          testr.New(t),
+			Scheme:       s,
+			TritonClient: NewMockTritonClient(),
+		}
+
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+		result, err := reconciler.Reconcile(context.Background(), req)
+		if err != nil {
+			t.Fatalf("reconcile: (%v)", err)
+		}
+		if result.RequeueAfter != tt.want {
+			t.Errorf("policy %q: expected a requeue after %s, got %s", tt.policy, tt.want, result.RequeueAfter)
+		}
+	}
+}
+
+func TestReconcileResyncPeriod(t *testing.T) {
+	for _, period := range []time.Duration{0, 5 * time.Minute} {
+		t.Run(period.String(), func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test-service",
+					Namespace:  "default",
+					Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+
+			s := scheme.Scheme
+			s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+			client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+			reconciler := &LoadBalancerReconciler{
+				Client:       client,
+				Log:          testr.New(t),
+				Scheme:       s,
+				TritonClient: NewMockTritonClient(),
+				ResyncPeriod: period,
+			}
+
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "test-service",
+					Namespace: "default",
+				},
+			}
+
+			result, err := reconciler.Reconcile(context.Background(), req)
+			if err != nil {
+				t.Fatalf("reconcile: (%v)", err)
+			}
+			if result.RequeueAfter != period {
+				t.Errorf("expected RequeueAfter %v, got %v", period, result.RequeueAfter)
+			}
+		})
+	}
+}
+
+func TestReconcileDeleteTargetsRecordedInstance(t *testing.T) {
+	deletionTime := metav1.NewTime(time.Now())
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-service",
+			Namespace:         "default",
+			DeletionTimestamp: &deletionTime,
+			Finalizers:        []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{
+				"cloud.tritoncompute/instance-id": "provisioning-id",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.deleteCalled != 1 || mockClient.deleteID != "provisioning-id" {
+		t.Errorf("expected the load balancer to be deleted by its recorded ID, got %d deletes with ID %q",
+			mockClient.deleteCalled, mockClient.deleteID)
+	}
+}
+
+// TestReconcileUpdatesRecordedInstance tests that a load balancer is found
+// by its recorded instance ID after its instance was renamed
+func TestReconcileUpdatesRecordedInstance(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{
+				"cloud.tritoncompute/instance-id": "test-id",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "203.0.113.1"}}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.instances["renamed"] = &triton.TritonInstance{
+		ID:    "test-id",
+		Name:  "renamed",
+		State: "running",
+		IPs:   []string{"203.0.113.1"},
+	}
+	mockClient.loadBalancers["renamed"] = &triton.LoadBalancerParams{
+		Name:         "renamed",
+		PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8080}},
+	}
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.createCalled != 0 || mockClient.updateCalled != 1 {
+		t.Errorf("expected the recorded instance to be updated, got %d creates and %d updates",
+			mockClient.createCalled, mockClient.updateCalled)
+	}
+	if mockClient.stateCalled != 0 {
+		t.Errorf("expected no state check for a published load balancer, got %d", mockClient.stateCalled)
+	}
+}
+
+func TestReconcileIgnoredService(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{
+				"cloud.tritoncompute/ignore": "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	calls := mockClient.createCalled + mockClient.updateCalled + mockClient.deleteCalled +
+		mockClient.getCalled + mockClient.stateCalled + mockClient.adoptCalled + len(mockClient.deletedInstances)
+	if calls != 0 {
+		t.Errorf("expected no Triton operations for an ignored Service, got %d", calls)
+	}
+
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if len(updatedService.Finalizers) != 0 {
+		t.Errorf("expected the finalizer to be removed, got %v", updatedService.Finalizers)
+	}
+}
+
+func TestReconcilePublicIPFailureRequeues(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{
+				"cloud.tritoncompute/allocate-public-ip": "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.createErr = &triton.PublicIPError{InstanceID: "test-id", Err: errors.New("no public network available to the account")}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	params, err := reconciler.extractLoadBalancerParams(service)
+	if err != nil || !params.AllocatePublicIP {
+		t.Fatalf("expected AllocatePublicIP to be set, got %v, %v", params.AllocatePublicIP, err)
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+
+	result, err := reconciler.Reconcile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected a public IP failure to requeue without error, got %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Error("expected a requeue after a public IP failure")
+	}
+
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Normal Provisioning") {
+		t.Errorf("expected a Provisioning event first, got %q", event)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning PublicIPAllocationFailed") {
+			t.Errorf("expected PublicIPAllocationFailed warning, got %q", event)
+		}
+	default:
+		t.Error("expected a PublicIPAllocationFailed warning")
+	}
+}
+
+func TestReconcileInstanceNameTemplate(t *testing.T) {
+	newService := func(namespace string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "web",
+				Namespace:  namespace,
+				Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		}
+	}
+	prod, staging := newService("prod"), newService("staging")
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, prod)
+	client := fake.NewClientBuilder().WithRuntimeObjects(prod, staging).Build()
+
+	tmpl, err := ParseInstanceNameTemplate("{{.Namespace}}-{{.Name}}-lb")
+	if err != nil {
+		t.Fatalf("ParseInstanceNameTemplate: %v", err)
+	}
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:               client,
+		Log:                  testr.New(t),
+		Scheme:               s,
+		TritonClient:         mockClient,
+		InstanceNameTemplate: tmpl,
+	}
+
+	for _, namespace := range []string{"prod", "staging"} {
+		req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: namespace}}
+		if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("reconcile %s: (%v)", namespace, err)
+		}
+	}
+
+	if mockClient.createCalled != 2 {
+		t.Fatalf("expected a load balancer per namespace, got %d creates", mockClient.createCalled)
+	}
+	for _, name := range []string{"prod-web-lb", "staging-web-lb"} {
+		lb, ok := mockClient.loadBalancers[name]
+		if !ok {
+			t.Fatalf("expected load balancer %s, got %v", name, mockClient.loadBalancers)
+		}
+		if lb.ServiceName != "web" {
+			t.Errorf("expected %s to record Service web, got %q", name, lb.ServiceName)
+		}
+	}
+
+	// The recorded name survives a change of template
+	reconciler.InstanceNameTemplate = nil
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "prod"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	updatedService := &corev1.Service{}
+	if err := client.Get(context.Background(), req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := updatedService.Annotations[instanceNameAnnotation]; got != "prod-web-lb" {
+		t.Errorf("expected the instance name to be recorded, got %q", got)
+	}
+	if mockClient.createCalled != 2 {
+		t.Errorf("expected the load balancer to keep its recorded name, got %d creates", mockClient.createCalled)
+	}
+}
+
+func TestReconcileInstanceNameCollision(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "web",
+			Namespace:  "staging",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	// The default template gives Services named web the same load balancer
+	mockClient := NewMockTritonClient()
+	mockClient.loadBalancers["web"] = &triton.LoadBalancerParams{Name: "web", Namespace: "prod"}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "staging"}}
+	_, err := reconciler.Reconcile(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "namespace prod") {
+		t.Fatalf("expected name collision error, got %v", err)
+	}
+	if mockClient.updateCalled != 0 || mockClient.createCalled != 0 {
+		t.Errorf("expected the other namespace's load balancer to be left alone, got %d updates and %d creates",
+			mockClient.updateCalled, mockClient.createCalled)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "NameCollision") {
+			t.Errorf("expected NameCollision event, got %q", event)
+		}
+	default:
+		t.Error("expected NameCollision event")
+	}
+}
+
+func TestReconcileCorrectsStaleIngressIP(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Recorder:     recorder,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      "test-service",
+			Namespace: "default",
+		},
+	}
+	ingressIP := func() string {
+		var updated corev1.Service
+		if err := client.Get(context.Background(), req.NamespacedName, &updated); err != nil {
+			t.Fatalf("get service: %v", err)
+		}
+		if len(updated.Status.LoadBalancer.Ingress) != 1 {
+			t.Fatalf("expected one ingress entry, got %v", updated.Status.LoadBalancer.Ingress)
+		}
+		return updated.Status.LoadBalancer.Ingress[0].IP
+	}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("first reconcile: (%v)", err)
+	}
+	if ip := ingressIP(); ip != "203.0.113.1" {
+		t.Fatalf("expected initial ingress IP 203.0.113.1, got %s", ip)
+	}
+
+	// The instance comes back with a new address, e.g. after a NIC reattach
+	mockClient.instances["test-service"].IPs = []string{"203.0.113.7", "10.0.0.1"}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("second reconcile: (%v)", err)
+	}
+	if ip := ingressIP(); ip != "203.0.113.7" {
+		t.Errorf("expected stale ingress IP to be corrected to 203.0.113.7, got %s", ip)
+	}
+
+	var found bool
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, "IngressChanged") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected IngressChanged event")
+	}
+}
+
+// TestReconcileDryRun tests that a dry run reports the operation each
+// reconcile would perform without performing it
+func TestReconcileDryRun(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name     string
+		deleted  bool
+		existing *triton.LoadBalancerParams
+		want     string
+	}{
+		{
+			name: "create",
+			want: "would create load balancer test-service: 1 replica(s), ports http://80:test-service:8080",
+		},
+		{
+			name: "update",
+			existing: &triton.LoadBalancerParams{
+				Name:         "test-service",
+				PortMappings: []triton.PortMapping{{Type: "http", ListenPort: 80, BackendName: "test-service", BackendPort: 8000}},
+				MaxBackends:  32,
+			},
+			want: "would update load balancer test-service: ports changed http://80:test-service:8000 -> http://80:test-service:8080; max_rs 32 -> 0",
+		},
+		{
+			name:     "delete",
+			deleted:  true,
+			existing: &triton.LoadBalancerParams{Name: "test-service"},
+			want:     "would delete load balancer test-service: 1 replica(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test-service",
+					Namespace:  "default",
+					Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+				},
+			}
+			if tt.deleted {
+				service.DeletionTimestamp = &now
+			}
+
+			s := scheme.Scheme
+			s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+			client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+			mockClient := NewMockTritonClient()
+			if tt.existing != nil {
+				mockClient.loadBalancers["test-service"] = tt.existing
+			}
+			recorder := record.NewFakeRecorder(10)
+			reconciler := &LoadBalancerReconciler{
+				Client:       client,
+				Log:          testr.New(t),
+				Scheme:       s,
+				TritonClient: mockClient,
+				Recorder:     recorder,
+				DryRun:       true,
+			}
+
+			req := reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+			}
+			ctx := context.Background()
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("reconcile: (%v)", err)
+			}
+
+			if mockClient.createCalled+mockClient.updateCalled+mockClient.deleteCalled != 0 {
+				t.Errorf("expected no changes in Triton, got %d creates, %d updates and %d deletes",
+					mockClient.createCalled, mockClient.updateCalled, mockClient.deleteCalled)
+			}
+			select {
+			case event := <-recorder.Events:
+				if event != "Normal DryRun "+tt.want {
+					t.Errorf("expected a DryRun event %q, got %q", tt.want, event)
+				}
+			default:
+				t.Error("expected a DryRun event")
+			}
+
+			updatedService := &corev1.Service{}
+			if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+				t.Fatalf("expected the Service to still exist: %v", err)
+			}
+			if len(updatedService.Annotations) != 0 || len(updatedService.Status.Conditions) != 0 {
+				t.Errorf("expected no annotations or conditions, got %v and %v",
+					updatedService.Annotations, updatedService.Status.Conditions)
+			}
+		})
+	}
+}
+
+func TestReconcileDatacenter(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-service",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"cloud.tritoncompute/datacenter": " us-west-1 ",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.createDatacenter != "us-west-1" {
+		t.Errorf("expected the load balancer to be created in us-west-1, got %q", mockClient.createDatacenter)
+	}
+	if lb := mockClient.loadBalancers["test-service"]; lb == nil || lb.Datacenter != "us-west-1" {
+		t.Errorf("expected the datacenter in the load balancer parameters, got %+v", lb)
+	}
+}
+
+// fakeDNS records the names registered through it
+type fakeDNS struct {
+	records map[string][]string
+	deleted []string
+}
+
+func (f *fakeDNS) Ensure(ctx context.Context, record dns.Record) error {
+	f.records[record.Name] = record.IPs
+	return nil
+}
+
+func (f *fakeDNS) Delete(ctx context.Context, record dns.Record) error {
+	delete(f.records, record.Name)
+	f.deleted = append(f.deleted, record.Name)
+	return nil
+}
+
+func TestReconcileRegistersDNSName(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-service",
+			Namespace:   "default",
+			Finalizers:  []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{"cloud.tritoncompute/dns-name": "shop.example.com"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	provider := &fakeDNS{records: map[string][]string{}}
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: NewMockTritonClient(),
+		DNS:          provider,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if ips := provider.records["shop.example.com"]; !reflect.DeepEqual(ips, []string{"203.0.113.1"}) {
+		t.Errorf("expected shop.example.com to be registered for the published IP, got %v", provider.records)
+	}
+
+	// A new name replaces the registered one
+	updated := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := updated.Annotations[dnsRecordAnnotation]; got != "shop.example.com" {
+		t.Errorf("expected the registered name to be recorded, got %q", got)
+	}
+	updated.Annotations[dnsNameAnnotation] = "store.example.com"
+	if err := client.Update(ctx, updated); err != nil {
+		t.Fatalf("failed to update service: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if _, ok := provider.records["store.example.com"]; !ok || len(provider.records) != 1 {
+		t.Errorf("expected only store.example.com to be registered, got %v", provider.records)
+	}
+
+	// Deleting the Service deletes its name
+	if err := client.Delete(ctx, updated); err != nil {
+		t.Fatalf("failed to delete service: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if want := []string{"shop.example.com", "store.example.com"}; !reflect.DeepEqual(provider.deleted, want) {
+		t.Errorf("expected %v to be deleted, got %v", want, provider.deleted)
+	}
+}
+
+func TestReconcileDeleteDrains(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-service",
+			Namespace:   "default",
+			Finalizers:  []string{"loadbalancer.triton.io/finalizer"},
+			Annotations: map[string]string{"cloud.tritoncompute/drain-timeout": "30"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+	}
+
+	req := reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"},
+	}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+
+	deleted := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, deleted); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if len(deleted.Status.LoadBalancer.Ingress) == 0 {
+		t.Fatal("expected the load balancer to be published")
+	}
+	if err := client.Delete(ctx, deleted); err != nil {
+		t.Fatalf("failed to delete service: %v", err)
+	}
+
+	// The load balancer drains first, unpublished
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > 30*time.Second {
+		t.Errorf("expected a requeue within the drain timeout, got %v", result.RequeueAfter)
+	}
+	if mockClient.deleteCalled != 0 {
+		t.Fatal("expected the load balancer not to be deleted while it drains")
+	}
+	if err := client.Get(ctx, req.NamespacedName, deleted); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if len(deleted.Status.LoadBalancer.Ingress) != 0 {
+		t.Errorf("expected the load balancer IPs to be removed, got %v", deleted.Status.LoadBalancer.Ingress)
+	}
+
+	// Deleted once drained
+	mockClient.drainStarted["test-service"] = time.Now().Add(-time.Minute)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.deleteCalled != 1 {
+		t.Errorf("expected the drained load balancer to be deleted, got %d deletes", mockClient.deleteCalled)
+	}
+}
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr/testr"
 	corev1 "k8s.io/api/core/v1"
@@ -77,7 +79,7 @@ func NewTritonClientWrapper(realClient *triton.Client) *TritonClientWrapper {
 	}
 }
 
-func (w *TritonClientWrapper) CreateLoadBalancer(ctx context.Context, params triton.LoadBalancerParams) error {
+func (w *TritonClientWrapper) CreateLoadBalancer(ctx context.Context, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
 	if !w.simulated {
 		return w.RealClient.CreateLoadBalancer(ctx, params)
 	}
@@ -85,30 +87,31 @@ func (w *TritonClientWrapper) CreateLoadBalancer(ctx context.Context, params tri
 	// Simulated mode
 	w.loadBalancers[params.Name] = &params
 	w.instances[params.Name] = &triton.TritonInstance{
-		ID:   "test-instance-id",
-		Name: params.Name,
-		IPs:  []string{"192.0.2.1", "10.0.0.1"},
+		ID:    "test-instance-id",
+		Name:  params.Name,
+		State: "running",
+		IPs:   []string{"192.0.2.1", "10.0.0.1"},
 		Tags: map[string]interface{}{
 			"loadbalancer": "true",
 			"managed-by":   "triton-loadbalancer-controller",
 		},
 	}
-	return nil
+	return w.instances[params.Name], nil
 }
 
-func (w *TritonClientWrapper) UpdateLoadBalancer(ctx context.Context, name string, params triton.LoadBalancerParams) error {
+func (w *TritonClientWrapper) UpdateLoadBalancer(ctx context.Context, id, name string, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
 	if !w.simulated {
-		return w.RealClient.UpdateLoadBalancer(ctx, name, params)
+		return w.RealClient.UpdateLoadBalancer(ctx, id, name, params)
 	}
 
 	// Simulated mode
 	w.loadBalancers[name] = &params
-	return nil
+	return w.instances[name], nil
 }
 
-func (w *TritonClientWrapper) DeleteLoadBalancer(ctx context.Context, name string) error {
+func (w *TritonClientWrapper) DeleteLoadBalancer(ctx context.Context, id, name string) error {
 	if !w.simulated {
-		return w.RealClient.DeleteLoadBalancer(ctx, name)
+		return w.RealClient.DeleteLoadBalancer(ctx, id, name)
 	}
 
 	// Simulated mode
@@ -117,9 +120,9 @@ func (w *TritonClientWrapper) DeleteLoadBalancer(ctx context.Context, name strin
 	return nil
 }
 
-func (w *TritonClientWrapper) GetLoadBalancer(ctx context.Context, name string) (*triton.LoadBalancerParams, error) {
+func (w *TritonClientWrapper) GetLoadBalancer(ctx context.Context, id, name string) (*triton.LoadBalancerParams, error) {
 	if !w.simulated {
-		return w.RealClient.GetLoadBalancer(ctx, name)
+		return w.RealClient.GetLoadBalancer(ctx, id, name)
 	}
 
 	// Simulated mode
@@ -143,6 +146,86 @@ func (w *TritonClientWrapper) GetInstanceByName(ctx context.Context, name string
 	return instance, nil
 }
 
+func (w *TritonClientWrapper) ListLoadBalancers(ctx context.Context) ([]*triton.LoadBalancerParams, error) {
+	if !w.simulated {
+		return w.RealClient.ListLoadBalancers(ctx)
+	}
+
+	// Simulated mode
+	var loadBalancers []*triton.LoadBalancerParams
+	for name, lb := range w.loadBalancers {
+		listed := *lb
+		listed.Instance = w.instances[name]
+		loadBalancers = append(loadBalancers, &listed)
+	}
+	return loadBalancers, nil
+}
+
+func (w *TritonClientWrapper) GetInstanceState(ctx context.Context, id string) (string, error) {
+	if !w.simulated {
+		return w.RealClient.GetInstanceState(ctx, id)
+	}
+
+	// Simulated mode: instances are running as soon as they are created
+	for _, instance := range w.instances {
+		if instance.ID == id {
+			return instance.State, nil
+		}
+	}
+	return "deleted", nil
+}
+
+func (w *TritonClientWrapper) UpdateTags(ctx context.Context, id, name string, tags map[string]string) error {
+	if !w.simulated {
+		return w.RealClient.UpdateTags(ctx, id, name, tags)
+	}
+
+	// Simulated mode: instances carry no tags
+	return nil
+}
+
+func (w *TritonClientWrapper) AdoptLoadBalancer(ctx context.Context, ref string, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
+	if !w.simulated {
+		return w.RealClient.AdoptLoadBalancer(ctx, ref, params)
+	}
+
+	// Simulated mode
+	for name, instance := range w.instances {
+		if instance.ID == ref || instance.Name == ref {
+			delete(w.instances, name)
+			instance.Name = params.Name
+			w.instances[params.Name] = instance
+			w.loadBalancers[params.Name] = &params
+			return instance, nil
+		}
+	}
+	return nil, fmt.Errorf("no instance named %s", ref)
+}
+
+func (w *TritonClientWrapper) DrainLoadBalancer(ctx context.Context, id, name string) (time.Time, error) {
+	if !w.simulated {
+		return w.RealClient.DrainLoadBalancer(ctx, id, name)
+	}
+
+	// Simulated mode: nothing to drain
+	return time.Time{}, nil
+}
+
+func (w *TritonClientWrapper) DeleteInstance(ctx context.Context, id string) error {
+	if !w.simulated {
+		return w.RealClient.DeleteInstance(ctx, id)
+	}
+
+	// Simulated mode
+	for name, instance := range w.instances {
+		if instance.ID == id {
+			delete(w.instances, name)
+			delete(w.loadBalancers, name)
+		}
+	}
+	return nil
+}
+
 func TestReconcileCreateLoadBalancer(t *testing.T) {
 	// Check if we should use real Triton client for integration testing
 	realClient := getRealTritonClient(t)
@@ -155,7 +238,7 @@ func TestReconcileCreateLoadBalancer(t *testing.T) {
 		// Make sure to clean up after the test
 		defer func() {
 			ctx := context.Background()
-			_ = realClient.DeleteLoadBalancer(ctx, serviceName)
+			_ = realClient.DeleteLoadBalancer(ctx, "", serviceName)
 		}()
 	}
 
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileIDKey is the log field correlating every line of one reconcile
+const reconcileIDKey = "reconcileID"
+
+// reconcileLoggerKey carries the reconcile logger in a context
+type reconcileLoggerKey struct{}
+
+// reconcileLogger returns a logger for one reconcile of req, tagged with the
+// Service and a fresh correlation ID, and a context carrying it so that
+// helpers log with the same fields
+func (r *LoadBalancerReconciler) reconcileLogger(ctx context.Context, req ctrl.Request) (context.Context, logr.Logger) {
+	log := r.Log.WithValues("service", req.NamespacedName, reconcileIDKey, string(uuid.NewUUID()))
+	return context.WithValue(ctx, reconcileLoggerKey{}, log), log
+}
+
+// withLogValues adds key/value pairs, such as the Triton instance ID once it
+// is known, to the reconcile logger carried by ctx
+func withLogValues(ctx context.Context, log logr.Logger, keysAndValues ...interface{}) (context.Context, logr.Logger) {
+	log = log.WithValues(keysAndValues...)
+	return context.WithValue(ctx, reconcileLoggerKey{}, log), log
+}
+
+// loggerFor returns the reconcile logger carried by ctx, or a logger tagged
+// with the Service when called outside Reconcile
+func (r *LoadBalancerReconciler) loggerFor(ctx context.Context, service *corev1.Service) logr.Logger {
+	if log, ok := ctx.Value(reconcileLoggerKey{}).(logr.Logger); ok {
+		return log
+	}
+	return r.Log.WithValues("service", fmt.Sprintf("%s/%s", service.Namespace, service.Name))
+}
+
+// loggedWarnings remembers the last warning logged for each Service, so a
+// warning that holds on every reconcile is only logged when it changes. The
+// zero value is ready to use.
+type loggedWarnings struct {
+	mu   sync.Mutex
+	last map[types.NamespacedName]string
+}
+
+// changed records warning as the last one of key and reports whether it
+// differs from the one before; an empty warning clears it
+func (w *loggedWarnings) changed(key types.NamespacedName, warning string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.last[key] == warning {
+		return false
+	}
+	if warning == "" {
+		delete(w.last, key)
+		return true
+	}
+	if w.last == nil {
+		w.last = make(map[types.NamespacedName]string)
+	}
+	w.last[key] = warning
+	return true
+}
+
+// forget drops the warning recorded for key
+func (w *loggedWarnings) forget(key types.NamespacedName) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.last, key)
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileLogsCorrelationID(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(service).Build()
+
+	var lines []string
+	reconciler := &LoadBalancerReconciler{
+		Client: client,
+		Log: funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{}),
+		Scheme:       scheme.Scheme,
+		TritonClient: NewMockTritonClient(),
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+	reconcileIDs := func() map[string]bool {
+		ids := map[string]bool{}
+		pattern := regexp.MustCompile(`"reconcileID"="([^"]+)"`)
+		for _, line := range lines {
+			match := pattern.FindStringSubmatch(line)
+			if match == nil {
+				t.Errorf("expected every log line to carry a reconcileID, got %s", line)
+				continue
+			}
+			ids[match[1]] = true
+		}
+		return ids
+	}
+
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if len(lines) == 0 {
+		t.Fatal("expected reconcile to log")
+	}
+	first := reconcileIDs()
+	if len(first) != 1 {
+		t.Errorf("expected one reconcileID for a reconcile, got %v", first)
+	}
+	if last := lines[len(lines)-1]; !strings.Contains(last, `"instanceID"="test-id"`) {
+		t.Errorf("expected log lines after provisioning to carry the instance ID, got %s", last)
+	}
+
+	lines = nil
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("second reconcile: (%v)", err)
+	}
+	for id := range reconcileIDs() {
+		if first[id] {
+			t.Errorf("expected a fresh reconcileID per reconcile, %s was reused", id)
+		}
+	}
+}
+
+func TestReconcileLogsReservedTagLabelsOnce(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-service",
+			Namespace:  "default",
+			Finalizers: []string{"loadbalancer.triton.io/finalizer"},
+			Labels:     map[string]string{DefaultTagLabelPrefix + "triton.cns.services": "web"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	client := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(service).Build()
+
+	var lines []string
+	reconciler := &LoadBalancerReconciler{
+		Client: client,
+		Log: funcr.New(func(prefix, args string) {
+			lines = append(lines, args)
+		}, funcr.Options{}),
+		Scheme:          scheme.Scheme,
+		TritonClient:    NewMockTritonClient(),
+		PropagateLabels: []string{"triton.placement"},
+	}
+	warnings := func() []string {
+		var found []string
+		for _, line := range lines {
+			if strings.Contains(line, "Ignoring labels for reserved instance tags") {
+				found = append(found, line)
+			}
+		}
+		return found
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("reconcile: (%v)", err)
+		}
+	}
+	found := warnings()
+	if len(found) != 1 {
+		t.Fatalf("expected the reserved label to be logged once, got %v", found)
+	}
+	if !strings.Contains(found[0], `"service"={"name"="test-service" "namespace"="default"}`) || !strings.Contains(found[0], `"reconcileID"=`) {
+		t.Errorf("expected the warning to carry the reconcile fields, got %s", found[0])
+	}
+
+	// A new reserved label is reported again
+	updated := &corev1.Service{}
+	if err := client.Get(context.Background(), req.NamespacedName, updated); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	updated.Labels["triton.placement"] = "rack-1"
+	if err := client.Update(context.Background(), updated); err != nil {
+		t.Fatalf("failed to update service: %v", err)
+	}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if found := warnings(); len(found) != 2 || !strings.Contains(found[1], "triton.placement") {
+		t.Errorf("expected the changed labels to be logged, got %v", found)
+	}
+}
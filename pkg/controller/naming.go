@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultInstanceNameTemplate names load balancer instances after their
+// Service alone, as earlier versions did
+const DefaultInstanceNameTemplate = "{{.Name}}"
+
+// maxInstanceNameLength keeps instance names usable as the DNS label CNS
+// derives from them
+const maxInstanceNameLength = 63
+
+// instanceNamePattern is the character set Triton accepts in instance names
+var instanceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// defaultInstanceNameTemplate is used when no template is configured
+var defaultInstanceNameTemplate = template.Must(template.New("instance-name").Parse(DefaultInstanceNameTemplate))
+
+// instanceNameAnnotation records the instance name a Service's load balancer
+// was given, so later changes to the template don't rename it
+const instanceNameAnnotation = "cloud.tritoncompute/instance-name"
+
+// instanceNameData is what instance name templates are rendered with
+type instanceNameData struct {
+	ClusterID string
+	Namespace string
+	Name      string
+}
+
+// ParseInstanceNameTemplate parses a template naming load balancer instances
+// from the .Namespace and .Name of their Service and the .ClusterID of the
+// controller, e.g. "{{.ClusterID}}-{{.Namespace}}-{{.Name}}". The template is
+// rendered once with sample values so that unknown fields are reported at
+// startup.
+func ParseInstanceNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("instance-name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance name template: %w", err)
+	}
+	if _, err := renderInstanceName(tmpl, instanceNameData{ClusterID: "cluster", Namespace: "default", Name: "example"}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// renderInstanceName renders and validates the instance name of a Service
+func renderInstanceName(tmpl *template.Template, data instanceNameData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid instance name template: %w", err)
+	}
+	return validateInstanceName(b.String(), data.Namespace, data.Name)
+}
+
+// validateInstanceName checks that name is usable as the instance name of the
+// Service namespace/service
+func validateInstanceName(name, namespace, service string) (string, error) {
+	if len(name) > maxInstanceNameLength {
+		return "", fmt.Errorf("instance name %q for Service %s/%s is longer than %d characters",
+			name, namespace, service, maxInstanceNameLength)
+	}
+	if !instanceNamePattern.MatchString(name) {
+		return "", fmt.Errorf("instance name %q for Service %s/%s must start with a letter or digit and contain only letters, digits, '.', '-' and '_'",
+			name, namespace, service)
+	}
+	return name, nil
+}
+
+// instanceName renders the name of the load balancer instance of a Service
+func (r *LoadBalancerReconciler) instanceName(namespace, name string) (string, error) {
+	tmpl := r.InstanceNameTemplate
+	if tmpl == nil {
+		tmpl = defaultInstanceNameTemplate
+	}
+	return renderInstanceName(tmpl, instanceNameData{ClusterID: r.ClusterID, Namespace: namespace, Name: name})
+}
+
+// serviceInstanceName returns the instance name recorded on service, or
+// renders one if none has been recorded yet
+func (r *LoadBalancerReconciler) serviceInstanceName(service *corev1.Service) (string, error) {
+	if name, ok := service.Annotations[r.annotation(instanceNameAnnotation)]; ok {
+		return validateInstanceName(name, service.Namespace, service.Name)
+	}
+	return r.instanceName(service.Namespace, service.Name)
+}
+
+// recordInstanceName records name on service before its load balancer is
+// created, so the instance keeps the name even if the template changes
+func (r *LoadBalancerReconciler) recordInstanceName(ctx context.Context, service *corev1.Service, name string) error {
+	if service.Annotations[r.annotation(instanceNameAnnotation)] == name {
+		return nil
+	}
+
+	patch := client.MergeFrom(service.DeepCopy())
+	if service.Annotations == nil {
+		service.Annotations = map[string]string{}
+	}
+	service.Annotations[r.annotation(instanceNameAnnotation)] = name
+	if err := r.Patch(ctx, service, patch); err != nil {
+		return fmt.Errorf("failed to record instance name on Service: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseInstanceNameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "default", template: DefaultInstanceNameTemplate},
+		{name: "namespaced", template: "{{.Namespace}}-{{.Name}}-lb"},
+		{name: "cluster", template: "{{.ClusterID}}-{{.Namespace}}-{{.Name}}"},
+		{name: "syntax error", template: "{{.Name", wantErr: "invalid instance name template"},
+		{name: "unknown field", template: "{{.Cluster}}-{{.Name}}", wantErr: "invalid instance name template"},
+		{name: "invalid characters", template: "{{.Namespace}}/{{.Name}}", wantErr: "must start with a letter or digit"},
+		{name: "empty", template: "", wantErr: "must start with a letter or digit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseInstanceNameTemplate(tt.template)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInstanceName(t *testing.T) {
+	tmpl, err := ParseInstanceNameTemplate("{{.Namespace}}-{{.Name}}-lb")
+	if err != nil {
+		t.Fatalf("ParseInstanceNameTemplate: %v", err)
+	}
+
+	r := &LoadBalancerReconciler{}
+	if got, err := r.instanceName("prod", "web"); err != nil || got != "web" {
+		t.Errorf("expected default template to render web, got %q (%v)", got, err)
+	}
+
+	r.InstanceNameTemplate = tmpl
+	if got, err := r.instanceName("prod", "web"); err != nil || got != "prod-web-lb" {
+		t.Errorf("expected prod-web-lb, got %q (%v)", got, err)
+	}
+
+	long := strings.Repeat("a", 60)
+	if _, err := r.instanceName(long, "web"); err == nil || !strings.Contains(err.Error(), "longer than 63") {
+		t.Errorf("expected length error, got %v", err)
+	}
+
+	r.InstanceNameTemplate, err = ParseInstanceNameTemplate("{{.ClusterID}}-{{.Namespace}}-{{.Name}}")
+	if err != nil {
+		t.Fatalf("ParseInstanceNameTemplate: %v", err)
+	}
+	r.ClusterID = "east"
+	if got, err := r.instanceName("prod", "web"); err != nil || got != "east-prod-web" {
+		t.Errorf("expected east-prod-web, got %q (%v)", got, err)
+	}
+}
+
+func TestServiceInstanceName(t *testing.T) {
+	tmpl, err := ParseInstanceNameTemplate("{{.Namespace}}-{{.Name}}-lb")
+	if err != nil {
+		t.Fatalf("ParseInstanceNameTemplate: %v", err)
+	}
+	r := &LoadBalancerReconciler{InstanceNameTemplate: tmpl}
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"}}
+	if got, err := r.serviceInstanceName(service); err != nil || got != "prod-web-lb" {
+		t.Errorf("expected the template to be rendered without a recorded name, got %q (%v)", got, err)
+	}
+
+	service.Annotations = map[string]string{instanceNameAnnotation: "web"}
+	if got, err := r.serviceInstanceName(service); err != nil || got != "web" {
+		t.Errorf("expected the recorded name, got %q (%v)", got, err)
+	}
+
+	service.Annotations[instanceNameAnnotation] = "prod/web"
+	if _, err := r.serviceInstanceName(service); err == nil {
+		t.Error("expected an invalid recorded name to be rejected")
+	}
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// DefaultOrphanGCInterval is how often the orphan collector runs when no interval is configured
+const DefaultOrphanGCInterval = 10 * time.Minute
+
+// OrphanCollector periodically deletes Triton load balancers that are no
+// longer backed by a LoadBalancer Service, which happens when a Service is
+// force-deleted while the controller is down and the finalizer never runs
+type OrphanCollector struct {
+	Client       client.Reader
+	TritonClient TritonClientInterface
+	Log          logr.Logger
+	Recorder     record.EventRecorder
+
+	// Interval between collection runs
+	Interval time.Duration
+	// DryRun logs orphans without deleting them
+	DryRun bool
+	// Shard limits collection to the load balancers of Services in the
+	// namespaces of one shard
+	Shard Shard
+}
+
+// Start runs the collector until the context is cancelled. It implements
+// manager.Runnable so it can be added to the controller manager.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultOrphanGCInterval
+	}
+
+	c.Log.Info("Starting orphaned load balancer collector", "interval", interval, "dryRun", c.DryRun)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.collect(ctx); err != nil {
+			c.Log.Error(err, "Orphaned load balancer collection failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection ensures only the elected leader deletes instances
+func (c *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// collect performs a single pass, deleting every managed instance that has
+// no corresponding LoadBalancer Service
+func (c *OrphanCollector) collect(ctx context.Context) error {
+	// List instances before Services so that any instance we see was created
+	// for a Service that is guaranteed to show up in the Service list
+	loadBalancers, err := c.TritonClient.ListLoadBalancers(ctx)
+	if err != nil {
+		return err
+	}
+
+	var services corev1.ServiceList
+	if err := c.Client.List(ctx, &services); err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+
+	// Track Services both by namespaced name and by bare name, since instances
+	// created by older versions carry no namespace tag
+	namespaced := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, service := range services.Items {
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		namespaced[service.Namespace+"/"+service.Name] = true
+		names[service.Name] = true
+	}
+
+	var orphans, deleted int
+	for _, lb := range loadBalancers {
+		serviceName, namespace := loadBalancerOwner(lb)
+		if !c.Shard.Owns(namespace) {
+			// Collected by the replica of that shard
+			continue
+		}
+
+		if namespace != "" {
+			if namespaced[namespace+"/"+serviceName] {
+				continue
+			}
+		} else if names[serviceName] {
+			continue
+		}
+
+		instanceID := ""
+		if lb.Instance != nil {
+			instanceID = lb.Instance.ID
+		}
+		log := c.Log.WithValues("instance", instanceID, "name", lb.Name,
+			"service", fmt.Sprintf("%s/%s", namespace, serviceName))
+		orphans++
+
+		if c.DryRun {
+			log.Info("Found orphaned load balancer (dry run, not deleting)")
+			c.recordEvent(namespace, serviceName, corev1.EventTypeWarning, "OrphanDetected",
+				fmt.Sprintf("Load balancer instance %s has no Service and would be deleted", instanceID))
+			continue
+		}
+
+		log.Info("Deleting orphaned load balancer")
+		if err := c.TritonClient.DeleteLoadBalancer(triton.WithDatacenter(ctx, lb.Datacenter), instanceID, lb.Name); err != nil {
+			log.Error(err, "Failed to delete orphaned load balancer")
+			continue
+		}
+		deleted++
+		c.recordEvent(namespace, serviceName, corev1.EventTypeNormal, "OrphanDeleted",
+			fmt.Sprintf("Deleted load balancer instance %s which had no Service", instanceID))
+	}
+
+	c.Log.Info("Finished orphaned load balancer collection",
+		"loadBalancers", len(loadBalancers), "orphans", orphans, "deleted", deleted)
+	return nil
+}
+
+// recordEvent emits an event referencing the Service the orphan belonged to
+func (c *OrphanCollector) recordEvent(namespace, name, eventType, reason, message string) {
+	if c.Recorder == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{
+		Kind:       "Service",
+		APIVersion: "v1",
+		Namespace:  namespace,
+		Name:       name,
+	}
+	c.Recorder.Event(ref, eventType, reason, message)
+}
+
+// loadBalancerOwner returns the Service name and namespace recorded on a
+// load balancer; instances created by older versions only carry the name
+func loadBalancerOwner(lb *triton.LoadBalancerParams) (name, namespace string) {
+	name = lb.Name
+	if lb.ServiceName != "" {
+		name = lb.ServiceName
+	}
+	return name, lb.Namespace
+}
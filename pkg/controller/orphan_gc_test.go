@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// newOrphanTestCollector builds a collector with one live Service and one orphaned instance
+func newOrphanTestCollector(t *testing.T, dryRun bool) (*OrphanCollector, *MockTritonClient, *record.FakeRecorder) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "live-service",
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+		},
+	}
+
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	mockClient.instances["live-service"] = &triton.TritonInstance{
+		ID:   "live-id",
+		Name: "live-service",
+		Tags: map[string]interface{}{"k8s-service": "live-service", "k8s-namespace": "default"},
+	}
+	mockClient.instances["orphan-service"] = &triton.TritonInstance{
+		ID:   "orphan-id",
+		Name: "orphan-service",
+		Tags: map[string]interface{}{"k8s-service": "orphan-service", "k8s-namespace": "default"},
+	}
+	// Same Service name in another namespace is still an orphan
+	mockClient.instances["live-service-other"] = &triton.TritonInstance{
+		ID:   "other-ns-id",
+		Name: "live-service-other",
+		Tags: map[string]interface{}{"k8s-service": "live-service", "k8s-namespace": "other"},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	collector := &OrphanCollector{
+		Client:       client,
+		TritonClient: mockClient,
+		Log:          testr.New(t),
+		Recorder:     recorder,
+		DryRun:       dryRun,
+	}
+	return collector, mockClient, recorder
+}
+
+func TestOrphanCollectorDeletesOrphans(t *testing.T) {
+	collector, mockClient, recorder := newOrphanTestCollector(t, false)
+
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+
+	if mockClient.deleteCalled != 2 {
+		t.Errorf("expected 2 deletions, got %d", mockClient.deleteCalled)
+	}
+	if _, exists := mockClient.instances["live-service"]; !exists {
+		t.Error("expected instance backed by a Service to be kept")
+	}
+	if _, exists := mockClient.instances["orphan-service"]; exists {
+		t.Error("expected orphaned instance to be deleted")
+	}
+	if _, exists := mockClient.instances["live-service-other"]; exists {
+		t.Error("expected instance from a different namespace to be deleted")
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("expected an event per deletion, got %d", len(recorder.Events))
+	}
+}
+
+func TestOrphanCollectorDryRun(t *testing.T) {
+	collector, mockClient, recorder := newOrphanTestCollector(t, true)
+
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+
+	if mockClient.deleteCalled != 0 {
+		t.Errorf("expected no deletions in dry run, got %d", mockClient.deleteCalled)
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("expected an event per orphan, got %d", len(recorder.Events))
+	}
+}
+
+func TestOrphanCollectorLegacyInstanceWithoutNamespace(t *testing.T) {
+	collector, mockClient, _ := newOrphanTestCollector(t, false)
+	mockClient.instances = map[string]*triton.TritonInstance{
+		"live-service": {ID: "legacy-id", Name: "live-service"},
+	}
+
+	if err := collector.collect(context.Background()); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+
+	if mockClient.deleteCalled != 0 {
+		t.Errorf("expected legacy instance matching a Service name to be kept, got %d deletions", mockClient.deleteCalled)
+	}
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// controllerOwnedAnnotations are written by the controller itself and never
+// require another reconcile when they change. Keys use DefaultAnnotationPrefix.
+var controllerOwnedAnnotations = map[string]bool{
+	lastErrorAnnotation:     true,
+	lastErrorTimeAnnotation: true,
+	instanceIDAnnotation:    true,
+	dnsNamesAnnotation:      true,
+	instanceNameAnnotation:  true,
+	dnsRecordAnnotation:     true,
+}
+
+// loadBalancerServicePredicate filters out events of Services that are not
+// of type LoadBalancer, unless they still hold the finalizer: a Service
+// changed to another type is reconciled once more to release its load
+// balancer.
+func loadBalancerServicePredicate(finalizer string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		service, ok := obj.(*corev1.Service)
+		if !ok {
+			return true
+		}
+		return service.Spec.Type == corev1.ServiceTypeLoadBalancer || controllerutil.ContainsFinalizer(service, finalizer)
+	})
+}
+
+// serviceChangedPredicate filters out Service updates that only touch the
+// status or controller-owned annotations, which the controller writes at the
+// end of every reconcile. Spec, label, finalizer and user annotation changes,
+// as well as deletion, still trigger a reconcile. The controller-owned
+// annotations are looked up under the given annotation prefix.
+func serviceChangedPredicate(prefix string) predicate.Predicate {
+	owned := make(map[string]bool, len(controllerOwnedAnnotations))
+	for key := range controllerOwnedAnnotations {
+		owned[annotationKey(prefix, key)] = true
+	}
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldService, ok := e.ObjectOld.(*corev1.Service)
+			if !ok {
+				return true
+			}
+			newService, ok := e.ObjectNew.(*corev1.Service)
+			if !ok {
+				return true
+			}
+			return serviceNeedsReconcile(oldService, newService, owned)
+		},
+	}
+}
+
+// serviceNeedsReconcile reports whether the change from oldService to
+// newService is relevant to the load balancer, ignoring the owned annotations
+func serviceNeedsReconcile(oldService, newService *corev1.Service, owned map[string]bool) bool {
+	if !newService.DeletionTimestamp.Equal(oldService.DeletionTimestamp) {
+		return true
+	}
+	if !equality.Semantic.DeepEqual(oldService.Spec, newService.Spec) {
+		return true
+	}
+	if !reflect.DeepEqual(oldService.Labels, newService.Labels) ||
+		!reflect.DeepEqual(oldService.Finalizers, newService.Finalizers) {
+		return true
+	}
+	return !reflect.DeepEqual(userAnnotations(oldService.Annotations, owned), userAnnotations(newService.Annotations, owned))
+}
+
+// userAnnotations returns the annotations that are not owned by the controller
+func userAnnotations(annotations map[string]string, owned map[string]bool) map[string]string {
+	filtered := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		if !owned[k] {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// nodeChangedPredicate filters out Node updates that don't change whether or
+// where the node serves NodePorts, such as status heartbeats
+func nodeChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return true
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return true
+			}
+			_, oldExcluded := oldNode.Labels[corev1.LabelNodeExcludeBalancers]
+			_, newExcluded := newNode.Labels[corev1.LabelNodeExcludeBalancers]
+			return nodeReady(oldNode) != nodeReady(newNode) ||
+				nodeInternalIP(oldNode) != nodeInternalIP(newNode) ||
+				oldExcluded != newExcluded
+		},
+	}
+}
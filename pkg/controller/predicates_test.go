@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestServiceChangedPredicate(t *testing.T) {
+	base := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{"cloud.tritoncompute/max_rs": "16"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(s *corev1.Service)
+		want   bool
+	}{
+		{
+			name: "status only",
+			mutate: func(s *corev1.Service) {
+				s.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.1"}}
+			},
+			want: false,
+		},
+		{
+			name: "controller-owned annotations only",
+			mutate: func(s *corev1.Service) {
+				s.Annotations[lastErrorAnnotation] = "boom"
+				s.Annotations[lastErrorTimeAnnotation] = "2024-01-01T00:00:00Z"
+			},
+			want: false,
+		},
+		{
+			name: "recorded instance name only",
+			mutate: func(s *corev1.Service) {
+				s.Annotations[instanceNameAnnotation] = "prod-web-lb"
+			},
+			want: false,
+		},
+		{
+			name: "registered DNS record only",
+			mutate: func(s *corev1.Service) {
+				s.Annotations[dnsRecordAnnotation] = "shop.example.com"
+			},
+			want: false,
+		},
+		{
+			name: "spec change",
+			mutate: func(s *corev1.Service) {
+				s.Spec.Ports = append(s.Spec.Ports, corev1.ServicePort{Name: "https", Port: 443})
+			},
+			want: true,
+		},
+		{
+			name: "user annotation change",
+			mutate: func(s *corev1.Service) {
+				s.Annotations["cloud.tritoncompute/max_rs"] = "32"
+			},
+			want: true,
+		},
+		{
+			name: "deletion",
+			mutate: func(s *corev1.Service) {
+				now := metav1.Now()
+				s.DeletionTimestamp = &now
+			},
+			want: true,
+		},
+	}
+
+	p := serviceChangedPredicate(DefaultAnnotationPrefix)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.DeepCopy()
+			tt.mutate(updated)
+
+			got := p.Update(event.UpdateEvent{ObjectOld: base, ObjectNew: updated})
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServiceChangedPredicateAnnotationPrefix(t *testing.T) {
+	base := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Namespace:   "default",
+			Annotations: map[string]string{},
+		},
+	}
+
+	p := serviceChangedPredicate("lb.example.com")
+
+	owned := base.DeepCopy()
+	owned.Annotations["lb.example.com/last-error"] = "boom"
+	if p.Update(event.UpdateEvent{ObjectOld: base, ObjectNew: owned}) {
+		t.Error("expected controller-owned annotations under the custom prefix to be ignored")
+	}
+
+	// Under a custom prefix, default-prefix annotations are user annotations
+	other := base.DeepCopy()
+	other.Annotations[lastErrorAnnotation] = "boom"
+	if !p.Update(event.UpdateEvent{ObjectOld: base, ObjectNew: other}) {
+		t.Error("expected annotations under another prefix to trigger a reconcile")
+	}
+}
+
+func TestNodeChangedPredicate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(n *corev1.Node)
+		want   bool
+	}{
+		{
+			name: "heartbeat only",
+			mutate: func(n *corev1.Node) {
+				n.Status.Conditions[0].LastHeartbeatTime = metav1.Now()
+			},
+			want: false,
+		},
+		{
+			name: "not ready",
+			mutate: func(n *corev1.Node) {
+				n.Status.Conditions[0].Status = corev1.ConditionFalse
+			},
+			want: true,
+		},
+		{
+			name: "new address",
+			mutate: func(n *corev1.Node) {
+				n.Status.Addresses[1].Address = "10.0.0.99"
+			},
+			want: true,
+		},
+		{
+			name: "excluded from load balancers",
+			mutate: func(n *corev1.Node) {
+				n.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: "true"}
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldNode := node("node-a", "10.0.0.11", true)
+			newNode := oldNode.DeepCopy()
+			tt.mutate(newNode)
+			got := nodeChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode})
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadBalancerServicePredicate(t *testing.T) {
+	p := loadBalancerServicePredicate(DefaultFinalizerName)
+	service := func(serviceType corev1.ServiceType, finalizers ...string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Finalizers: finalizers},
+			Spec:       corev1.ServiceSpec{Type: serviceType},
+		}
+	}
+
+	if !p.Create(event.CreateEvent{Object: service(corev1.ServiceTypeLoadBalancer)}) {
+		t.Error("expected a LoadBalancer Service to be reconciled")
+	}
+	if p.Create(event.CreateEvent{Object: service(corev1.ServiceTypeClusterIP)}) {
+		t.Error("expected a ClusterIP Service to be ignored")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: service(corev1.ServiceTypeClusterIP), ObjectNew: service(corev1.ServiceTypeNodePort)}) {
+		t.Error("expected an update between other types to be ignored")
+	}
+	// Changed away from LoadBalancer while its load balancer still exists
+	if !p.Update(event.UpdateEvent{
+		ObjectOld: service(corev1.ServiceTypeLoadBalancer, DefaultFinalizerName),
+		ObjectNew: service(corev1.ServiceTypeClusterIP, DefaultFinalizerName),
+	}) {
+		t.Error("expected a Service holding the finalizer to be reconciled")
+	}
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// recreatePolicyAnnotation selects which unhealthy load balancer instances
+// of a Service are deleted and provisioned again
+const recreatePolicyAnnotation = "cloud.tritoncompute/recreate-policy"
+
+const (
+	// RecreatePolicyNever reports unhealthy instances and leaves them alone
+	RecreatePolicyNever = "Never"
+	// RecreatePolicyOnFailure recreates instances that failed, stopped while
+	// provisioning or were deleted
+	RecreatePolicyOnFailure = "OnFailure"
+	// RecreatePolicyAlways also recreates running instances that stopped
+	RecreatePolicyAlways = "Always"
+)
+
+// healthCheckPeriod is how often the load balancer of a Service with a
+// recreate policy is checked when no ResyncPeriod is configured
+const healthCheckPeriod = 5 * time.Minute
+
+// recreatePolicy returns the recreate policy of service, which defaults to
+// OnFailure with AutoRecreateFailed and to Never otherwise
+func (r *LoadBalancerReconciler) recreatePolicy(service *corev1.Service) (string, error) {
+	value, ok := service.Annotations[r.annotation(recreatePolicyAnnotation)]
+	if !ok {
+		if r.AutoRecreateFailed {
+			return RecreatePolicyOnFailure, nil
+		}
+		return RecreatePolicyNever, nil
+	}
+	for _, policy := range []string{RecreatePolicyNever, RecreatePolicyOnFailure, RecreatePolicyAlways} {
+		if strings.EqualFold(strings.TrimSpace(value), policy) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %s annotation %q: must be %s, %s or %s", r.annotation(recreatePolicyAnnotation), value,
+		RecreatePolicyNever, RecreatePolicyOnFailure, RecreatePolicyAlways)
+}
+
+// stoppedReplica returns the first replica of lb that is stopped, or nil
+func stoppedReplica(lb *triton.TritonInstance) *triton.TritonInstance {
+	replicas := lb.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{lb}
+	}
+
+	for _, replica := range replicas {
+		if replica.State == "stopped" {
+			return replica
+		}
+	}
+	return nil
+}
+
+// recoverReplica handles a replica of a provisioned load balancer that
+// failed, was deleted or stopped, recreating it as the recreate policy of
+// service allows. Its addresses are withdrawn from the Service status first
+// so clients are only sent to the replicas that still serve.
+func (r *LoadBalancerReconciler) recoverReplica(ctx context.Context, service *corev1.Service, replica *triton.TritonInstance) (ctrl.Result, error) {
+	log := r.loggerFor(ctx, service)
+	policy, err := r.recreatePolicy(service)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	failed := &triton.InstanceFailedError{InstanceID: replica.ID, State: replica.State}
+
+	if replica.State == "stopped" && policy != RecreatePolicyAlways {
+		// Someone may have stopped it on purpose, e.g. for maintenance
+		log.Info("Load balancer instance is stopped", "instanceID", replica.ID, "name", replica.Name)
+		r.recordEvent(service, corev1.EventTypeWarning, "InstanceStopped",
+			fmt.Sprintf("load balancer instance %s (%s) is stopped; start it or set %s to %s",
+				replica.Name, replica.ID, r.annotation(recreatePolicyAnnotation), RecreatePolicyAlways))
+		return r.setState(ctx, service, StateDegraded, fmt.Sprintf("instance %s is stopped", replica.Name)), nil
+	}
+
+	if policy != RecreatePolicyNever {
+		if err := r.withdrawIngress(ctx, service, replica.IPs); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if replica.State == "stopped" {
+		log.Error(failed, "Load balancer instance stopped", "instanceID", replica.ID)
+		r.recordEvent(service, corev1.EventTypeWarning, "InstanceStopped", failed.Error())
+		return r.recreateInstance(ctx, service, failed)
+	}
+	return r.handleFailedInstance(ctx, service, failed)
+}
+
+// withdrawIngress removes ips from the load balancer ingress status of
+// service
+func (r *LoadBalancerReconciler) withdrawIngress(ctx context.Context, service *corev1.Service, ips []string) error {
+	withdrawn := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		withdrawn[ip] = true
+	}
+	var ingress []corev1.LoadBalancerIngress
+	for _, entry := range service.Status.LoadBalancer.Ingress {
+		if entry.IP == "" || !withdrawn[entry.IP] {
+			ingress = append(ingress, entry)
+		}
+	}
+	if len(ingress) == len(service.Status.LoadBalancer.Ingress) {
+		return nil
+	}
+
+	updatedService := service.DeepCopy()
+	updatedService.Status.LoadBalancer.Ingress = ingress
+	if err := r.Status().Update(ctx, updatedService); err != nil {
+		return fmt.Errorf("failed to withdraw load balancer IPs from Service status: %w", err)
+	}
+	service.ResourceVersion = updatedService.ResourceVersion
+	service.Status.LoadBalancer.Ingress = ingress
+	r.loggerFor(ctx, service).Info("Withdrew load balancer IPs from service status", "ips", ips)
+	return nil
+}
+
+// healthCheckResult requeues a ready Service whose recreate policy acts on
+// unhealthy instances often enough for them to be noticed, even without a
+// ResyncPeriod
+func (r *LoadBalancerReconciler) healthCheckResult(service *corev1.Service, result ctrl.Result) ctrl.Result {
+	if result.RequeueAfter > 0 && result.RequeueAfter <= healthCheckPeriod {
+		return result
+	}
+	if policy, err := r.recreatePolicy(service); err != nil || policy == RecreatePolicyNever {
+		return result
+	}
+	return ctrl.Result{RequeueAfter: healthCheckPeriod}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Shard selects the namespaces one of several controller replicas manages,
+// by a hash of the namespace name, so a large cluster's Services are split
+// between replicas that each elect their own leader. The zero value manages
+// every namespace.
+type Shard struct {
+	// Index is this replica's shard, from 0 to Count-1
+	Index int
+	// Count is the number of shards; 0 or 1 disables sharding
+	Count int
+}
+
+// Validate reports whether the shard describes one of Count shards
+func (s Shard) Validate() error {
+	if s.Count < 0 {
+		return fmt.Errorf("invalid shard count %d: must not be negative", s.Count)
+	}
+	if s.Count <= 1 {
+		if s.Index != 0 {
+			return fmt.Errorf("invalid shard index %d: sharding is disabled", s.Index)
+		}
+		return nil
+	}
+	if s.Index < 0 || s.Index >= s.Count {
+		return fmt.Errorf("invalid shard index %d: must be between 0 and %d", s.Index, s.Count-1)
+	}
+	return nil
+}
+
+// Owns reports whether the Services in namespace belong to this shard
+func (s Shard) Owns(namespace string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(namespace))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index
+}
+
+// String returns the shard as <index>/<count>, or "" when sharding is
+// disabled
+func (s Shard) String() string {
+	if s.Count <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestShardOwns(t *testing.T) {
+	const count = 3
+	owned := make([]int, count)
+	for i := 0; i < 100; i++ {
+		namespace := fmt.Sprintf("team-%d", i)
+		owners := 0
+		for index := 0; index < count; index++ {
+			if (Shard{Index: index, Count: count}).Owns(namespace) {
+				owners++
+				owned[index]++
+			}
+		}
+		if owners != 1 {
+			t.Fatalf("expected namespace %s to belong to exactly one shard, got %d", namespace, owners)
+		}
+	}
+	for index, n := range owned {
+		if n == 0 {
+			t.Errorf("expected shard %d to own some namespaces", index)
+		}
+	}
+
+	if !(Shard{}).Owns("default") {
+		t.Error("expected the zero shard to own every namespace")
+	}
+}
+
+func TestShardValidate(t *testing.T) {
+	tests := []struct {
+		shard   Shard
+		wantErr bool
+	}{
+		{shard: Shard{}},
+		{shard: Shard{Index: 0, Count: 1}},
+		{shard: Shard{Index: 2, Count: 3}},
+		{shard: Shard{Index: 3, Count: 3}, wantErr: true},
+		{shard: Shard{Index: -1, Count: 3}, wantErr: true},
+		{shard: Shard{Index: 1}, wantErr: true},
+		{shard: Shard{Count: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.shard.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v): expected error %v, got %v", tt.shard, tt.wantErr, err)
+		}
+	}
+}
+
+func TestReconcileSkipsOtherShards(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(corev1.SchemeGroupVersion, service)
+	client := fake.NewClientBuilder().WithRuntimeObjects(service).Build()
+
+	mockClient := NewMockTritonClient()
+	shard := Shard{Count: 2}
+	if shard.Owns("default") {
+		shard.Index = 1
+	}
+	reconciler := &LoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		Scheme:       s,
+		TritonClient: mockClient,
+		Shard:        shard,
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-service", Namespace: "default"}}
+	if _, err := reconciler.Reconcile(context.Background(), req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.createCalled != 0 || mockClient.getCalled != 0 {
+		t.Errorf("expected a Service of another shard to be left alone, got %d creates and %d gets",
+			mockClient.createCalled, mockClient.getCalled)
+	}
+}
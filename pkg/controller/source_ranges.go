@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// SourceRangesCondition is the Service condition reporting whether the
+// source ranges of a load balancer are enforced
+const SourceRangesCondition = "LoadBalancerSourceRangesEnforced"
+
+// sourceRanges returns the addresses allowed to reach the load balancer of
+// service: spec.loadBalancerSourceRanges, or else the firewall-source-ranges
+// annotation
+func (r *LoadBalancerReconciler) sourceRanges(service *corev1.Service) ([]string, error) {
+	sources := service.Spec.LoadBalancerSourceRanges
+	if len(sources) == 0 {
+		sources = strings.Split(service.Annotations[r.annotation(firewallSourceRangesAnnotation)], ",")
+	}
+
+	var ranges []string
+	for _, source := range sources {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+		if err := triton.ValidateSourceRange(source); err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, source)
+	}
+	return ranges, nil
+}
+
+// checkSourceRanges records in the SourceRangesCondition of service whether
+// the source ranges of params can be enforced, which takes the firewall
+// rules of ManageFirewall, and returns the reason when they can't, so the
+// load balancer isn't provisioned open to every source
+func (r *LoadBalancerReconciler) checkSourceRanges(ctx context.Context, service *corev1.Service, params triton.LoadBalancerParams) (unsupported, err error) {
+	if len(params.FirewallSourceRanges) == 0 {
+		if meta.FindStatusCondition(service.Status.Conditions, SourceRangesCondition) == nil {
+			return nil, nil
+		}
+		return nil, r.setServiceCondition(ctx, service, SourceRangesCondition, nil)
+	}
+
+	condition := metav1.Condition{
+		Type:               SourceRangesCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "FirewallRules",
+		Message:            "only " + strings.Join(params.FirewallSourceRanges, ", ") + " may reach the listen ports",
+		ObservedGeneration: service.Generation,
+	}
+	if !r.ManageFirewall {
+		unsupported = fmt.Errorf("source ranges %s can't be enforced: the controller doesn't manage Cloud Firewall rules (--manage-firewall)",
+			strings.Join(params.FirewallSourceRanges, ", "))
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "FirewallNotManaged"
+		condition.Message = unsupported.Error()
+	}
+	if err := r.setServiceCondition(ctx, service, SourceRangesCondition, &condition); err != nil {
+		return nil, err
+	}
+	return unsupported, nil
+}
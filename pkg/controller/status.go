@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LoadBalancerReadyCondition is the Service condition reporting the state of
+// its load balancer. It is True in StateReady; otherwise its reason is the
+// state and its message the cause.
+const LoadBalancerReadyCondition = "LoadBalancerReady"
+
+// States of the load balancer of a Service, the reasons of its
+// LoadBalancerReadyCondition
+const (
+	// StateProvisioning means the load balancer is being created, or is
+	// waiting for its replicas or listeners before it is published
+	StateProvisioning = "Provisioning"
+	// StateReady means the load balancer is published in the Service status
+	StateReady = "Ready"
+	// StateDegraded means the last reconcile failed; the load balancer, if
+	// any, is left as it was
+	StateDegraded = "Degraded"
+	// StateDeleteFailed means the load balancer could not be deleted, so the
+	// finalizer stays on the Service until it can
+	StateDeleteFailed = "DeleteFailed"
+)
+
+// setState records state, with message as its cause, in the
+// LoadBalancerReadyCondition of service and returns the requeue for it:
+// Provisioning load balancers are polled, Degraded ones retried with a
+// backoff that grows while they stay Degraded and Ready ones resynced.
+// DeleteFailed is retried through the error returned with it.
+func (r *LoadBalancerReconciler) setState(ctx context.Context, service *corev1.Service, state, message string) ctrl.Result {
+	status := metav1.ConditionFalse
+	if state == StateReady {
+		status = metav1.ConditionTrue
+	}
+	condition := metav1.Condition{
+		Type:               LoadBalancerReadyCondition,
+		Status:             status,
+		Reason:             state,
+		Message:            truncateMessage(message),
+		ObservedGeneration: service.Generation,
+	}
+
+	// A new state is a transition even if the status stays False
+	previous := meta.FindStatusCondition(service.Status.Conditions, LoadBalancerReadyCondition)
+	if previous != nil && previous.Reason != state {
+		condition.LastTransitionTime = metav1.Now()
+		if previous.Status == status {
+			meta.RemoveStatusCondition(&service.Status.Conditions, LoadBalancerReadyCondition)
+		}
+		r.loggerFor(ctx, service).Info("Load balancer changed state", "from", previous.Reason, "to", state)
+	}
+
+	// The condition is informational; a failed update is retried with the
+	// next state
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := r.setServiceCondition(ctx, service, LoadBalancerReadyCondition, &condition); err != nil {
+		r.loggerFor(ctx, service).Error(err, "Failed to record load balancer state", "state", state)
+	}
+
+	if state != StateDegraded {
+		r.resetRetries(service)
+	}
+	switch state {
+	case StateProvisioning:
+		return ctrl.Result{RequeueAfter: r.pollInterval()}
+	case StateDegraded:
+		return ctrl.Result{RequeueAfter: r.retryAfter(service)}
+	case StateReady:
+		return ctrl.Result{RequeueAfter: r.ResyncPeriod}
+	}
+	return ctrl.Result{}
+}
+
+// setServiceCondition sets condition in the status of service, or removes
+// the condition conditionType when condition is nil
+func (r *LoadBalancerReconciler) setServiceCondition(ctx context.Context, service *corev1.Service, conditionType string, condition *metav1.Condition) error {
+	original := service.DeepCopy()
+	if condition == nil {
+		meta.RemoveStatusCondition(&service.Status.Conditions, conditionType)
+	} else {
+		meta.SetStatusCondition(&service.Status.Conditions, *condition)
+	}
+	if equality.Semantic.DeepEqual(original.Status.Conditions, service.Status.Conditions) {
+		return nil
+	}
+	if err := r.Status().Patch(ctx, service, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("failed to update Service conditions: %w", err)
+	}
+	return nil
+}
+
+// truncateMessage caps msg at maxLastErrorLength bytes
+func truncateMessage(msg string) string {
+	if len(msg) > maxLastErrorLength {
+		msg = strings.ToValidUTF8(msg[:maxLastErrorLength-3], "") + "..."
+	}
+	return msg
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/triton/loadbalancer-controller/api/v1alpha1"
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// loadBalancerSpec describes the load balancer of the Service serviceName in
+// a TritonLoadBalancer spec. Certificate material stays in its Secret, which
+// is only referenced by name.
+func loadBalancerSpec(serviceName string, params triton.LoadBalancerParams, certificateSecret string) v1alpha1.TritonLoadBalancerSpec {
+	spec := v1alpha1.TritonLoadBalancerSpec{
+		ServiceName:           serviceName,
+		InstanceName:          params.Name,
+		Replicas:              params.Replicas,
+		MaxBackends:           params.MaxBackends,
+		MaxConnections:        params.MaxConnections,
+		CertificateName:       params.CertificateName,
+		CertificateSecretName: certificateSecret,
+		MetricsACL:            params.MetricsACL,
+		ProxyProtocol:         params.ProxyProtocol,
+		Sticky:                params.Sticky,
+		ExternalTrafficPolicy: params.ExternalTrafficPolicy,
+		Tags:                  params.Tags,
+		Affinity:              params.Affinity,
+		BackendWeights:        params.BackendWeights,
+		AllocatePublicIP:      params.AllocatePublicIP,
+		ReloadOnChange:        params.ReloadOnChange,
+		TimeoutConnect:        specDuration(params.TimeoutConnect),
+		TimeoutClient:         specDuration(params.TimeoutClient),
+		TimeoutServer:         specDuration(params.TimeoutServer),
+		Package:               params.Package,
+		Image:                 params.Image,
+		Networks:              params.Networks,
+		FirewallSourceRanges:  params.FirewallSourceRanges,
+		Datacenter:            params.Datacenter,
+
+		HealthCheckPath:               params.HealthCheckPath,
+		HealthCheckInterval:           specDuration(params.HealthCheckInterval),
+		HealthCheckTimeout:            specDuration(params.HealthCheckTimeout),
+		HealthCheckUnhealthyThreshold: params.HealthCheckUnhealthyThreshold,
+		DrainTimeout:                  specDuration(params.DrainTimeout),
+		ProvisionTimeout:              specDuration(params.ProvisionTimeout),
+		DeleteTimeout:                 specDuration(params.DeleteTimeout),
+
+		LocalityNear:   params.LocalityNear,
+		LocalityFar:    params.LocalityFar,
+		LocalityStrict: params.LocalityStrict,
+		SpreadReplicas: params.SpreadReplicas,
+	}
+	for _, mapping := range params.PortMappings {
+		spec.PortMappings = append(spec.PortMappings, v1alpha1.PortMapping(mapping))
+	}
+	return spec
+}
+
+// specDuration returns d as a spec field, or nil when it is unset
+func specDuration(d time.Duration) *metav1.Duration {
+	if d <= 0 {
+		return nil
+	}
+	return &metav1.Duration{Duration: d}
+}
+
+// paramsDuration returns the duration of a spec field, or zero when unset
+func paramsDuration(d *metav1.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return d.Duration
+}
+
+// loadBalancerParams returns the Triton parameters described by lb, without
+// the certificate of CertificateSecretName
+func loadBalancerParams(lb *v1alpha1.TritonLoadBalancer) triton.LoadBalancerParams {
+	spec := lb.Spec
+	params := triton.LoadBalancerParams{
+		Name:                  spec.InstanceName,
+		Namespace:             lb.Namespace,
+		Replicas:              spec.Replicas,
+		MaxBackends:           spec.MaxBackends,
+		MaxConnections:        spec.MaxConnections,
+		CertificateName:       spec.CertificateName,
+		MetricsACL:            spec.MetricsACL,
+		ProxyProtocol:         spec.ProxyProtocol,
+		Sticky:                spec.Sticky,
+		ExternalTrafficPolicy: spec.ExternalTrafficPolicy,
+		Tags:                  spec.Tags,
+		Affinity:              spec.Affinity,
+		BackendWeights:        spec.BackendWeights,
+		AllocatePublicIP:      spec.AllocatePublicIP,
+		ReloadOnChange:        spec.ReloadOnChange,
+		TimeoutConnect:        paramsDuration(spec.TimeoutConnect),
+		TimeoutClient:         paramsDuration(spec.TimeoutClient),
+		TimeoutServer:         paramsDuration(spec.TimeoutServer),
+		Package:               spec.Package,
+		Image:                 spec.Image,
+		Networks:              spec.Networks,
+		FirewallSourceRanges:  spec.FirewallSourceRanges,
+		Datacenter:            spec.Datacenter,
+
+		HealthCheckPath:               spec.HealthCheckPath,
+		HealthCheckInterval:           paramsDuration(spec.HealthCheckInterval),
+		HealthCheckTimeout:            paramsDuration(spec.HealthCheckTimeout),
+		HealthCheckUnhealthyThreshold: spec.HealthCheckUnhealthyThreshold,
+		DrainTimeout:                  paramsDuration(spec.DrainTimeout),
+		ProvisionTimeout:              paramsDuration(spec.ProvisionTimeout),
+		DeleteTimeout:                 paramsDuration(spec.DeleteTimeout),
+
+		LocalityNear:   spec.LocalityNear,
+		LocalityFar:    spec.LocalityFar,
+		LocalityStrict: spec.LocalityStrict,
+		SpreadReplicas: spec.SpreadReplicas,
+	}
+	if spec.ServiceName != spec.InstanceName {
+		params.ServiceName = spec.ServiceName
+	}
+	for _, mapping := range spec.PortMappings {
+		params.PortMappings = append(params.PortMappings, triton.PortMapping(mapping))
+	}
+	return params
+}
+
+// statusInstance rebuilds the load balancer recorded in a TritonLoadBalancer
+// status, so it can be published like one returned by the Triton client
+func statusInstance(status v1alpha1.TritonLoadBalancerStatus) *triton.TritonInstance {
+	var replicas []*triton.TritonInstance
+	for _, replica := range status.Replicas {
+		replicas = append(replicas, &triton.TritonInstance{
+			ID:       replica.ID,
+			Name:     replica.Name,
+			State:    replica.State,
+			IPs:      replica.IPs,
+			DNSNames: replica.DNSNames,
+		})
+	}
+	if len(replicas) == 0 {
+		return &triton.TritonInstance{ID: status.InstanceID, State: status.State, IPs: status.IPs}
+	}
+
+	primary := *replicas[0]
+	primary.Replicas = replicas
+	return &primary
+}
+
+// reconcileLoadBalancerObject records the load balancer of service in a
+// TritonLoadBalancer owned by the Service, leaving the Triton API to the
+// TritonLoadBalancerReconciler, and publishes the addresses in its status
+// once it is ready
+func (r *LoadBalancerReconciler) reconcileLoadBalancerObject(ctx context.Context, service *corev1.Service, params triton.LoadBalancerParams) (ctrl.Result, error) {
+	log := r.loggerFor(ctx, service)
+
+	var certificateSecret string
+	if key, ok, _ := r.certificateSecretKey(service); ok {
+		certificateSecret = key.Name
+	}
+
+	lb := &v1alpha1.TritonLoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: service.Name, Namespace: service.Namespace},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, lb, func() error {
+		lb.Spec = loadBalancerSpec(service.Name, params, certificateSecret)
+		return controllerutil.SetControllerReference(service, lb, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "Failed to update TritonLoadBalancer")
+		return ctrl.Result{}, fmt.Errorf("failed to update TritonLoadBalancer: %w", err)
+	}
+	if op != controllerutil.OperationResultNone {
+		log.Info("Updated TritonLoadBalancer", "operation", op)
+	}
+
+	// Status changes of the object requeue the Service
+	if lb.Status.ObservedGeneration != lb.Generation || !meta.IsStatusConditionTrue(lb.Status.Conditions, v1alpha1.ReadyCondition) {
+		log.Info("TritonLoadBalancer is not ready yet", "state", lb.Status.State)
+		r.setState(ctx, service, StateProvisioning, "waiting for TritonLoadBalancer "+lb.Name)
+		return ctrl.Result{}, nil
+	}
+
+	lbInstance := statusInstance(lb.Status)
+	lbIPs, err := selectReplicaIngressIPs(lbInstance, service.Annotations[r.annotation(ipFamilyPolicyAnnotation)])
+	if err != nil {
+		log.Error(err, "Failed to select load balancer IP")
+		return ctrl.Result{}, err
+	}
+	if len(lbIPs) > 0 {
+		hostnames, err := r.ingressHostnames(ctx, service, lbInstance)
+		if err != nil {
+			log.Error(err, "Failed to select load balancer hostname")
+			return ctrl.Result{}, err
+		}
+		if err := r.publishIngress(ctx, service, lbIPs, hostnames, params.PortMappings); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.syncDNSRecord(ctx, service, params.Name, lb.Status.InstanceID, lbIPs); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	r.setDNSNamesAnnotation(ctx, service, replicaDNSNames(lbInstance))
+
+	r.clearLastError(ctx, service)
+	return r.setState(ctx, service, StateReady, "TritonLoadBalancer "+lb.Name+" is ready"), nil
+}
+
+// deleteLoadBalancerObject deletes the TritonLoadBalancer of service; its own
+// finalizer keeps it until the TritonLoadBalancerReconciler has deleted the
+// Triton instances
+func (r *LoadBalancerReconciler) deleteLoadBalancerObject(ctx context.Context, service *corev1.Service) error {
+	lb := &v1alpha1.TritonLoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: service.Name, Namespace: service.Namespace},
+	}
+	if err := r.Delete(ctx, lb); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete TritonLoadBalancer: %w", err)
+	}
+	r.loggerFor(ctx, service).Info("Deleted TritonLoadBalancer", "name", lb.Name)
+	return nil
+}
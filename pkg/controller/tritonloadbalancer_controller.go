@@ -0,0 +1,270 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/triton/loadbalancer-controller/api/v1alpha1"
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// TritonLoadBalancerReconciler provisions the Triton instances described by
+// TritonLoadBalancer objects and records their state in the object's status
+type TritonLoadBalancerReconciler struct {
+	client.Client
+	Log          logr.Logger
+	TritonClient TritonClientInterface
+	Recorder     record.EventRecorder
+
+	// FinalizerName overrides DefaultFinalizerName
+	FinalizerName string
+
+	// PollInterval is how soon a load balancer that is still provisioning is
+	// checked again; zero means DefaultPollInterval
+	PollInterval time.Duration
+
+	// ConcurrentReconciles is how many TritonLoadBalancers are reconciled in
+	// parallel; zero means DefaultConcurrentReconciles
+	ConcurrentReconciles int
+
+	// Shard limits the controller to the objects in the namespaces of one
+	// shard; the zero value manages every namespace
+	Shard Shard
+
+	// lbLocks serializes reconciles per instance name, which objects in
+	// different namespaces may share
+	lbLocks keyedMutex
+}
+
+// +kubebuilder:rbac:groups=loadbalancer.triton.io,resources=tritonloadbalancers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=loadbalancer.triton.io,resources=tritonloadbalancers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=loadbalancer.triton.io,resources=tritonloadbalancers/finalizers,verbs=update
+
+// Reconcile creates, updates or deletes the Triton load balancer of a
+// TritonLoadBalancer
+func (r *TritonLoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if !r.Shard.Owns(req.Namespace) {
+		return ctrl.Result{}, nil
+	}
+	log := r.Log.WithValues("tritonloadbalancer", req.NamespacedName)
+
+	lb := &v1alpha1.TritonLoadBalancer{}
+	if err := r.Get(ctx, req.NamespacedName, lb); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	unlock := r.lbLocks.Lock(lb.Spec.InstanceName)
+	defer unlock()
+	ctx = triton.WithDatacenter(ctx, lb.Spec.Datacenter)
+
+	finalizerName := r.FinalizerName
+	if finalizerName == "" {
+		finalizerName = DefaultFinalizerName
+	}
+
+	if !lb.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(lb, finalizerName) {
+			return ctrl.Result{}, nil
+		}
+		if timeout := paramsDuration(lb.Spec.DrainTimeout); timeout > 0 {
+			started, err := r.TritonClient.DrainLoadBalancer(ctx, lb.Status.InstanceID, lb.Spec.InstanceName)
+			if err != nil {
+				return ctrl.Result{}, r.setFailed(ctx, lb, "DrainFailed", fmt.Errorf("failed to drain load balancer: %w", err))
+			}
+			if remaining := timeout - time.Since(started); !started.IsZero() && remaining > 0 {
+				log.Info("Draining load balancer before deleting it", "name", lb.Spec.InstanceName, "remaining", remaining.Round(time.Second).String())
+				return ctrl.Result{RequeueAfter: remaining}, nil
+			}
+		}
+		log.Info("Deleting load balancer", "name", lb.Spec.InstanceName)
+		if err := r.TritonClient.DeleteLoadBalancer(ctx, lb.Status.InstanceID, lb.Spec.InstanceName); err != nil {
+			return ctrl.Result{}, r.setFailed(ctx, lb, "DeleteFailed", fmt.Errorf("failed to delete load balancer: %w", err))
+		}
+		controllerutil.RemoveFinalizer(lb, finalizerName)
+		if err := r.Update(ctx, lb); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to remove finalizer: %w", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(lb, finalizerName) {
+		controllerutil.AddFinalizer(lb, finalizerName)
+		if err := r.Update(ctx, lb); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
+		}
+	}
+
+	params := loadBalancerParams(lb)
+	if name := lb.Spec.CertificateSecretName; name != "" {
+		key := types.NamespacedName{Namespace: lb.Namespace, Name: name}
+		if err := loadCertificateSecret(ctx, r, key, &params); err != nil {
+			return ctrl.Result{}, r.setFailed(ctx, lb, "InvalidConfiguration", err)
+		}
+	}
+
+	instance, err := r.apply(ctx, lb, params)
+	if err != nil {
+		log.Error(err, "Failed to apply load balancer", "name", params.Name)
+		return ctrl.Result{}, r.setFailed(ctx, lb, "SyncFailed", err)
+	}
+
+	ready := r.recordInstance(lb, instance)
+	if err := r.Status().Update(ctx, lb); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update TritonLoadBalancer status: %w", err)
+	}
+	if !ready && failedReplica(instance) == nil {
+		log.Info("Load balancer is not running yet, requeueing", "replicas", pendingReplicas(instance))
+		return ctrl.Result{RequeueAfter: r.pollInterval()}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// apply creates the load balancer of lb or updates it to match params
+func (r *TritonLoadBalancerReconciler) apply(ctx context.Context, lb *v1alpha1.TritonLoadBalancer, params triton.LoadBalancerParams) (*triton.TritonInstance, error) {
+	// Look the load balancer up by its first replica, which is found even
+	// while it is provisioning and not listed by name yet
+	existing, err := r.TritonClient.GetLoadBalancer(ctx, lb.Status.InstanceID, params.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Namespace != "" && existing.Namespace != lb.Namespace {
+			return nil, fmt.Errorf("load balancer %s already belongs to a Service in namespace %s; include {{.Namespace}} in the instance name template",
+				params.Name, existing.Namespace)
+		}
+		return r.TritonClient.UpdateLoadBalancer(ctx, lb.Status.InstanceID, params.Name, params)
+	}
+
+	r.recordEvent(lb, corev1.EventTypeNormal, "Provisioning",
+		fmt.Sprintf("provisioning load balancer %s with %d replica(s)", params.Name, params.ReplicaCount()))
+	return r.TritonClient.CreateLoadBalancer(ctx, params)
+}
+
+// recordInstance sets the status of lb from instance and reports whether
+// every replica is running with an address
+func (r *TritonLoadBalancerReconciler) recordInstance(lb *v1alpha1.TritonLoadBalancer, instance *triton.TritonInstance) bool {
+	replicas := instance.Replicas
+	if len(replicas) == 0 {
+		replicas = []*triton.TritonInstance{instance}
+	}
+
+	status := &lb.Status
+	status.ObservedGeneration = lb.Generation
+	status.InstanceID = instance.ID
+	status.State = instance.State
+	status.IPs = nil
+	status.Replicas = nil
+	for _, replica := range replicas {
+		status.IPs = append(status.IPs, replica.IPs...)
+		status.Replicas = append(status.Replicas, v1alpha1.ReplicaStatus{
+			ID:       replica.ID,
+			Name:     replica.Name,
+			State:    replica.State,
+			IPs:      replica.IPs,
+			DNSNames: replica.DNSNames,
+		})
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.ReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Running",
+		Message:            "every replica is running",
+		ObservedGeneration: lb.Generation,
+	}
+	if failed := failedReplica(instance); failed != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ProvisioningFailed"
+		condition.Message = fmt.Sprintf("instance %s is %s", failed.ID, failed.State)
+		if previous := meta.FindStatusCondition(status.Conditions, condition.Type); previous == nil || previous.Reason != condition.Reason {
+			r.recordEvent(lb, corev1.EventTypeWarning, "ProvisioningFailed", condition.Message)
+		}
+	} else if pending := pendingReplicas(instance); len(pending) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Provisioning"
+		condition.Message = "waiting for " + strings.Join(pending, ", ")
+	} else if len(status.IPs) == 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Provisioning"
+		condition.Message = "waiting for the load balancer's addresses"
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+	return condition.Status == metav1.ConditionTrue
+}
+
+// setFailed records err in the Ready condition of lb and returns it
+func (r *TritonLoadBalancerReconciler) setFailed(ctx context.Context, lb *v1alpha1.TritonLoadBalancer, reason string, err error) error {
+	r.recordEvent(lb, corev1.EventTypeWarning, reason, err.Error())
+	meta.SetStatusCondition(&lb.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ReadyCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: lb.Generation,
+	})
+	if updateErr := r.Status().Update(ctx, lb); updateErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to update TritonLoadBalancer status: %w", updateErr))
+	}
+	return err
+}
+
+// recordEvent emits an event on lb if a recorder is configured
+func (r *TritonLoadBalancerReconciler) recordEvent(lb *v1alpha1.TritonLoadBalancer, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(lb, eventType, reason, message)
+	}
+}
+
+// pollInterval returns the configured poll interval or DefaultPollInterval
+func (r *TritonLoadBalancerReconciler) pollInterval() time.Duration {
+	if r.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return r.PollInterval
+}
+
+// loadBalancersForSecret maps a Secret to the TritonLoadBalancers installing
+// its certificate, so certificate rotation is picked up
+func (r *TritonLoadBalancerReconciler) loadBalancersForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	lbs := &v1alpha1.TritonLoadBalancerList{}
+	if err := r.List(ctx, lbs, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list TritonLoadBalancers for certificate secret", "secret", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, lb := range lbs.Items {
+		if lb.Spec.CertificateSecretName == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&lb)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager reconciles TritonLoadBalancers when their spec changes
+func (r *TritonLoadBalancerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.TritonLoadBalancer{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.loadBalancersForSecret)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: concurrentReconciles(r.ConcurrentReconciles),
+		}).
+		Complete(r)
+}
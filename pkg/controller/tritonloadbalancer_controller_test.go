@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/triton/loadbalancer-controller/api/v1alpha1"
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+func TestLoadBalancerSpecRoundTrip(t *testing.T) {
+	params := triton.LoadBalancerParams{
+		Name:                  "default-web-lb",
+		ServiceName:           "web",
+		Namespace:             "default",
+		PortMappings:          []triton.PortMapping{{Type: "https", ListenPort: 443, BackendName: "web", BackendPort: 8443, ProxyProtocol: true}},
+		Replicas:              2,
+		MaxBackends:           64,
+		MaxConnections:        100,
+		CertificateName:       "example.com",
+		MetricsACL:            []string{"10.0.0.0/8"},
+		ProxyProtocol:         true,
+		ExternalTrafficPolicy: "Local",
+		Tags:                  map[string]string{"team": "web"},
+		Affinity:              []string{"instance!=db-*"},
+		BackendWeights:        map[string]int{"web": 90, "canary": 10},
+		AllocatePublicIP:      true,
+		ReloadOnChange:        true,
+		TimeoutConnect:        5 * time.Second,
+		TimeoutServer:         time.Minute,
+		Sticky:                true,
+		Package:               "g4-highcpu-4G",
+		Image:                 "haproxy@2.0",
+		Networks:              []string{"external", "my-fabric"},
+		FirewallSourceRanges:  []string{"10.0.0.0/8"},
+
+		HealthCheckPath:               "/healthz",
+		HealthCheckInterval:           5 * time.Second,
+		HealthCheckUnhealthyThreshold: 3,
+		DrainTimeout:                  30 * time.Second,
+		ProvisionTimeout:              15 * time.Minute,
+		DeleteTimeout:                 10 * time.Minute,
+
+		LocalityFar:    []string{"0bd8a5e6-7f4c-4b2e-9c51-3f6f1a2d7e90"},
+		LocalityStrict: true,
+		SpreadReplicas: true,
+	}
+
+	lb := &v1alpha1.TritonLoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       loadBalancerSpec("web", params, "web-tls"),
+	}
+	if lb.Spec.CertificateSecretName != "web-tls" {
+		t.Errorf("expected the certificate secret to be referenced, got %q", lb.Spec.CertificateSecretName)
+	}
+	if got := loadBalancerParams(lb); !reflect.DeepEqual(got, params) {
+		t.Errorf("round trip mismatch:\n got  %+v\n want %+v", got, params)
+	}
+}
+
+// newLoadBalancerObjectClient returns a fake client for objs that serves the
+// status subresource of Services and TritonLoadBalancers
+func newLoadBalancerObjectClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	if err := v1alpha1.AddToScheme(scheme.Scheme); err != nil {
+		t.Fatalf("failed to register the TritonLoadBalancer API: %v", err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&corev1.Service{}, &v1alpha1.TritonLoadBalancer{}).
+		Build()
+}
+
+func TestTritonLoadBalancerReconcile(t *testing.T) {
+	lb := &v1alpha1.TritonLoadBalancer{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.TritonLoadBalancerSpec{
+			ServiceName:  "web",
+			InstanceName: "web",
+			PortMappings: []v1alpha1.PortMapping{{Type: "http", ListenPort: 80, BackendName: "web", BackendPort: 8080}},
+		},
+	}
+	client := newLoadBalancerObjectClient(t, lb)
+
+	mockClient := NewMockTritonClient()
+	mockClient.createState = "provisioning"
+	reconciler := &TritonLoadBalancerReconciler{
+		Client:       client,
+		Log:          testr.New(t),
+		TritonClient: mockClient,
+		PollInterval: 3 * time.Second,
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}}
+	ctx := context.Background()
+
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter != 3*time.Second {
+		t.Errorf("expected a requeue after the poll interval while provisioning, got %v", result)
+	}
+
+	current := &v1alpha1.TritonLoadBalancer{}
+	if err := client.Get(ctx, req.NamespacedName, current); err != nil {
+		t.Fatalf("failed to get TritonLoadBalancer: %v", err)
+	}
+	if !reflect.DeepEqual(current.Finalizers, []string{DefaultFinalizerName}) {
+		t.Errorf("expected the finalizer to be added, got %v", current.Finalizers)
+	}
+	if current.Status.InstanceID != "test-id" || current.Status.State != "provisioning" {
+		t.Errorf("expected the provisioning instance in the status, got %+v", current.Status)
+	}
+	if ready := meta.FindStatusCondition(current.Status.Conditions, v1alpha1.ReadyCondition); ready == nil || ready.Reason != "Provisioning" {
+		t.Errorf("expected a Provisioning Ready condition, got %+v", ready)
+	}
+
+	mockClient.instances["web"].State = "running"
+	result, err = reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue once running, got %v", result)
+	}
+	if mockClient.createCalled != 1 || mockClient.updateCalled != 1 {
+		t.Errorf("expected one create and one update, got %d creates and %d updates",
+			mockClient.createCalled, mockClient.updateCalled)
+	}
+	if err := client.Get(ctx, req.NamespacedName, current); err != nil {
+		t.Fatalf("failed to get TritonLoadBalancer: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(current.Status.Conditions, v1alpha1.ReadyCondition) {
+		t.Errorf("expected the load balancer to be ready, got %+v", current.Status.Conditions)
+	}
+	if !reflect.DeepEqual(current.Status.IPs, []string{"203.0.113.1", "10.0.0.1"}) {
+		t.Errorf("expected the instance IPs in the status, got %v", current.Status.IPs)
+	}
+
+	if err := client.Delete(ctx, current); err != nil {
+		t.Fatalf("failed to delete TritonLoadBalancer: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.deleteCalled != 1 {
+		t.Errorf("expected the load balancer to be deleted, got %d deletes", mockClient.deleteCalled)
+	}
+	if err := client.Get(ctx, req.NamespacedName, current); err == nil {
+		t.Error("expected the TritonLoadBalancer to be removed once the load balancer is deleted")
+	}
+}
+
+// TestReconcileLoadBalancerObject tests that a Service reconciled with
+// UseLoadBalancerObjects gets a TritonLoadBalancer instead of an instance, and
+// publishes the addresses in its status once it is ready
+func TestReconcileLoadBalancerObject(t *testing.T) {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "web",
+			Namespace:  "default",
+			UID:        "web-uid",
+			Finalizers: []string{DefaultFinalizerName},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+	client := newLoadBalancerObjectClient(t, service)
+
+	mockClient := NewMockTritonClient()
+	reconciler := &LoadBalancerReconciler{
+		Client:                 client,
+		Log:                    testr.New(t),
+		Scheme:                 scheme.Scheme,
+		TritonClient:           mockClient,
+		UseLoadBalancerObjects: true,
+	}
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "web", Namespace: "default"}}
+	ctx := context.Background()
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if mockClient.createCalled != 0 || mockClient.getCalled != 0 {
+		t.Error("expected the Triton API to be left to the TritonLoadBalancer reconciler")
+	}
+
+	lb := &v1alpha1.TritonLoadBalancer{}
+	if err := client.Get(ctx, req.NamespacedName, lb); err != nil {
+		t.Fatalf("expected a TritonLoadBalancer for the Service: %v", err)
+	}
+	if owner := metav1.GetControllerOf(lb); owner == nil || owner.UID != service.UID {
+		t.Errorf("expected the TritonLoadBalancer to be owned by the Service, got %v", owner)
+	}
+	want := []v1alpha1.PortMapping{{Type: "http", ListenPort: 80, BackendName: "web", BackendPort: 8080}}
+	if lb.Spec.InstanceName != "web" || !reflect.DeepEqual(lb.Spec.PortMappings, want) {
+		t.Errorf("unexpected spec %+v", lb.Spec)
+	}
+
+	// The TritonLoadBalancer reconciler provisioned it
+	lb.Status = v1alpha1.TritonLoadBalancerStatus{
+		ObservedGeneration: lb.Generation,
+		InstanceID:         "test-id",
+		State:              "running",
+		IPs:                []string{"203.0.113.1"},
+		Replicas:           []v1alpha1.ReplicaStatus{{ID: "test-id", Name: "web", State: "running", IPs: []string{"203.0.113.1"}}},
+		Conditions: []metav1.Condition{{
+			Type:               v1alpha1.ReadyCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Running",
+			LastTransitionTime: metav1.Now(),
+		}},
+	}
+	if err := client.Status().Update(ctx, lb); err != nil {
+		t.Fatalf("failed to update TritonLoadBalancer status: %v", err)
+	}
+
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	updatedService := &corev1.Service{}
+	if err := client.Get(ctx, req.NamespacedName, updatedService); err != nil {
+		t.Fatalf("failed to get service: %v", err)
+	}
+	if got := ingressAddresses(updatedService.Status.LoadBalancer.Ingress); !reflect.DeepEqual(got, []string{"203.0.113.1"}) {
+		t.Errorf("expected the TritonLoadBalancer IPs to be published, got %v", got)
+	}
+
+	if err := client.Delete(ctx, updatedService); err != nil {
+		t.Fatalf("failed to delete service: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: (%v)", err)
+	}
+	if err := client.Get(ctx, req.NamespacedName, lb); err == nil {
+		t.Error("expected the TritonLoadBalancer to be deleted with the Service")
+	}
+	if mockClient.deleteCalled != 0 {
+		t.Error("expected the Triton API to be left to the TritonLoadBalancer reconciler")
+	}
+}
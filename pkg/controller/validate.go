@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// maxBackendsLimit is the largest max_rs ValidateService accepts
+const maxBackendsLimit = 1024
+
+// ValidateService checks the annotations of service as a reconcile would
+// apply them, and more strictly where a reconcile falls back to a default:
+// max_rs must be between 1 and 1024 and every metrics_acl entry an IP address
+// or CIDR. It returns the load balancer configuration of service, or nil if
+// the controller doesn't manage it. Package and image overrides are only
+// looked up when the instances are provisioned.
+func (r *LoadBalancerReconciler) ValidateService(service *corev1.Service) (*triton.LoadBalancerParams, error) {
+	if !r.managesService(service) || !service.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+	if ignored, _ := strconv.ParseBool(service.Annotations[r.annotation(ignoreAnnotation)]); ignored {
+		return nil, nil
+	}
+
+	params, err := r.extractLoadBalancerParams(service)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRS, ok := service.Annotations[r.annotation(maxRSAnnotation)]; ok {
+		count, err := strconv.Atoi(strings.TrimSpace(maxRS))
+		if err != nil || count < 1 || count > maxBackendsLimit {
+			return nil, fmt.Errorf("invalid %s annotation %q: must be an integer between 1 and %d",
+				r.annotation(maxRSAnnotation), maxRS, maxBackendsLimit)
+		}
+	}
+	for _, acl := range splitMetricsACL(service.Annotations[r.annotation(metricsACLAnnotation)]) {
+		if err := triton.ValidateSourceRange(acl); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", r.annotation(metricsACLAnnotation), err)
+		}
+	}
+	return &params, nil
+}
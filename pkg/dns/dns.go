@@ -0,0 +1,62 @@
+// Package dns registers the addresses of load balancers under the DNS names
+// their Services ask for, in Triton CNS or in an external DNS provider.
+package dns
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Record is a DNS name and the load balancer it resolves to
+type Record struct {
+	// Name is the name to register, e.g. shop.example.com
+	Name string
+	// IPs are the published addresses of the load balancer
+	IPs []string
+
+	// LoadBalancer and InstanceID identify the load balancer in Triton
+	LoadBalancer string
+	InstanceID   string
+}
+
+// Provider creates, updates and deletes records
+type Provider interface {
+	// Ensure points the record's name at its IPs, creating it if needed
+	Ensure(ctx context.Context, record Record) error
+	// Delete removes the record's name; a name that isn't registered is
+	// not an error
+	Delete(ctx context.Context, record Record) error
+}
+
+// CNSTagger publishes load balancer instances as Triton CNS services, as
+// *triton.Client does
+type CNSTagger interface {
+	SetCNSServices(ctx context.Context, id, name string, services []string) error
+}
+
+// cnsServicePattern matches the DNS labels CNS accepts as service names
+var cnsServicePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// CNS registers records as Triton CNS services of the load balancer
+// instances. The name of a record is the service label, and CNS serves it as
+// <name>.svc.<account>.<datacenter>.<zone>; instances that are deleted drop
+// out of it by themselves.
+type CNS struct {
+	Tagger CNSTagger
+}
+
+var _ Provider = &CNS{}
+
+// Ensure publishes the load balancer under the CNS service record.Name
+func (p *CNS) Ensure(ctx context.Context, record Record) error {
+	if !cnsServicePattern.MatchString(record.Name) {
+		return fmt.Errorf("invalid CNS service name %q: must be a lowercase DNS label", record.Name)
+	}
+	return p.Tagger.SetCNSServices(ctx, record.InstanceID, record.LoadBalancer, []string{record.Name})
+}
+
+// Delete unpublishes the load balancer from CNS
+func (p *CNS) Delete(ctx context.Context, record Record) error {
+	return p.Tagger.SetCNSServices(ctx, record.InstanceID, record.LoadBalancer, nil)
+}
@@ -0,0 +1,87 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeTagger records the CNS services of each load balancer
+type fakeTagger struct {
+	services map[string][]string
+}
+
+func (f *fakeTagger) SetCNSServices(ctx context.Context, id, name string, services []string) error {
+	f.services[name] = services
+	return nil
+}
+
+func TestCNS(t *testing.T) {
+	tagger := &fakeTagger{services: map[string][]string{}}
+	p := &CNS{Tagger: tagger}
+	ctx := context.Background()
+	record := Record{Name: "shop", LoadBalancer: "web", InstanceID: "web-id"}
+
+	if err := p.Ensure(ctx, record); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if got := tagger.services["web"]; !reflect.DeepEqual(got, []string{"shop"}) {
+		t.Errorf("expected web to be published as shop, got %v", got)
+	}
+	if err := p.Ensure(ctx, Record{Name: "shop.example.com", LoadBalancer: "web"}); err == nil {
+		t.Error("expected a name that isn't a DNS label to be rejected")
+	}
+	if err := p.Delete(ctx, record); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got := tagger.services["web"]; got != nil {
+		t.Errorf("expected web to be unpublished, got %v", got)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var requests []string
+	var ensured webhookRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			if err := json.NewDecoder(r.Body).Decode(&ensured); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	p := &Webhook{URL: server.URL + "/", Token: "secret", TTL: 60}
+	ctx := context.Background()
+	record := Record{Name: "shop.example.com", IPs: []string{"203.0.113.10", "2001:db8::10"}}
+
+	if err := p.Ensure(ctx, record); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	want := webhookRecord{Name: "shop.example.com", Addresses: []string{"203.0.113.10", "2001:db8::10"}, TTL: 60}
+	if !reflect.DeepEqual(ensured, want) {
+		t.Errorf("expected %+v to be sent, got %+v", want, ensured)
+	}
+	if err := p.Delete(ctx, record); err != nil {
+		t.Errorf("expected deleting a missing record to succeed, got %v", err)
+	}
+	if want := []string{"PUT /records/shop.example.com", "DELETE /records/shop.example.com"}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("expected requests %v, got %v", want, requests)
+	}
+
+	p.Token = "wrong"
+	if err := p.Ensure(ctx, record); err == nil {
+		t.Error("expected a rejected request to fail")
+	}
+}
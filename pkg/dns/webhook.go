@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Webhook registers records with an HTTP service in front of a DNS
+// provider. Ensure sends
+//
+//	PUT <URL>/records/<name>
+//	{"name": "shop.example.com", "addresses": ["203.0.113.10"], "ttl": 60}
+//
+// and the service creates or replaces the A and AAAA records of the name
+// with the addresses. Delete sends DELETE <URL>/records/<name>, which may
+// answer 404 for a name that doesn't exist.
+type Webhook struct {
+	// URL is the base URL of the service
+	URL string
+	// Token, if set, is sent as a bearer token
+	Token string
+	// TTL is the TTL of records in seconds; zero leaves it to the service
+	TTL int
+	// Client overrides http.DefaultClient
+	Client *http.Client
+}
+
+var _ Provider = &Webhook{}
+
+// webhookRecord is the body of an Ensure request
+type webhookRecord struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+	TTL       int      `json:"ttl,omitempty"`
+}
+
+// Ensure creates or replaces the records of record.Name
+func (w *Webhook) Ensure(ctx context.Context, record Record) error {
+	body, err := json.Marshal(webhookRecord{Name: record.Name, Addresses: record.IPs, TTL: w.TTL})
+	if err != nil {
+		return err
+	}
+	return w.do(ctx, http.MethodPut, record.Name, body)
+}
+
+// Delete removes the records of record.Name
+func (w *Webhook) Delete(ctx context.Context, record Record) error {
+	return w.do(ctx, http.MethodDelete, record.Name, nil)
+}
+
+// do sends a request for the records of name and checks the response
+func (w *Webhook) do(ctx context.Context, method, name string, body []byte) error {
+	endpoint := strings.TrimSuffix(w.URL, "/") + "/records/" + url.PathEscape(name)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("DNS webhook %s %s failed: %w", method, name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodDelete {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("DNS webhook %s %s returned %s: %s", method, name, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// instanceIDPattern matches Triton instance UUIDs
+var instanceIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// AdoptLoadBalancer brings an existing, manually created instance under
+// management as the load balancer described by params. The instance is
+// looked up by ID or by name, renamed to params.Name if needed, tagged as
+// managed by this controller and then updated to match params. Instances
+// already managed by another controller, cluster or Service are refused.
+func (c *Client) AdoptLoadBalancer(ctx context.Context, ref string, params LoadBalancerParams) (*TritonInstance, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.AdoptLoadBalancer(ctx, ref, params)
+	}
+	instance, err := c.findInstance(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkAdoptable(instance, params.serviceName()); err != nil {
+		return nil, err
+	}
+
+	if instance.Name != params.Name {
+		if err := c.renameInstance(ctx, instance, params.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the instance's existing tags alongside the management tags; user
+	// tags are reconciled with the Service labels by the update below
+	tags := map[string]interface{}{}
+	for k, v := range instance.Tags {
+		tags[k] = v
+	}
+	for k, v := range c.managedTags(params, 0) {
+		tags[k] = v
+	}
+	replaceInput := &compute.ReplaceTagsInput{
+		ID:   instance.ID,
+		Tags: tags,
+	}
+	err = c.call(ctx, "ReplaceMachineTags", func(ctx context.Context) error {
+		return c.instances.ReplaceTags(ctx, replaceInput)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to tag instance %s: %w", instance.ID, err)
+	}
+
+	return c.UpdateLoadBalancer(ctx, instance.ID, params.Name, params)
+}
+
+// renameInstance renames instance to name
+func (c *Client) renameInstance(ctx context.Context, instance *compute.Instance, name string) error {
+	previous := instance.Name
+	renameInput := &compute.RenameInstanceInput{
+		ID:   instance.ID,
+		Name: name,
+	}
+	err := c.call(ctx, "RenameMachine", func(ctx context.Context) error {
+		return c.instances.Rename(ctx, renameInput)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename instance %s to %s: %w", instance.ID, name, err)
+	}
+	fmt.Printf("Renamed load balancer instance %s from %s to %s\n", instance.ID, previous, name)
+	instance.Name = name
+	return nil
+}
+
+// findInstance looks up a single instance by ID or by exact name
+func (c *Client) findInstance(ctx context.Context, ref string) (*compute.Instance, error) {
+	if instanceIDPattern.MatchString(ref) {
+		getInput := &compute.GetInstanceInput{ID: ref}
+		var instance *compute.Instance
+		err := c.call(ctx, "GetMachine", func(ctx context.Context) error {
+			var err error
+			instance, err = c.instances.Get(ctx, getInput)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get instance %s: %w", ref, err)
+		}
+		return instance, nil
+	}
+
+	listInput := &compute.ListInstancesInput{Name: ref}
+	var instances []*compute.Instance
+	err := c.call(ctx, "ListMachines", func(ctx context.Context) error {
+		var err error
+		instances, err = c.instances.List(ctx, listInput)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances named %s: %w", ref, err)
+	}
+
+	switch len(instances) {
+	case 0:
+		return nil, fmt.Errorf("no instance named %s", ref)
+	case 1:
+		return instances[0], nil
+	default:
+		return nil, fmt.Errorf("%d instances are named %s, adopt one by ID instead", len(instances), ref)
+	}
+}
+
+// checkAdoptable refuses instances that belong to another controller,
+// cluster or load balancer
+func (c *Client) checkAdoptable(instance *compute.Instance, name string) error {
+	managedBy, ok := instance.Tags["managed-by"]
+	if !ok {
+		return nil
+	}
+	if fmt.Sprint(managedBy) != c.managedBy() {
+		return newError(ErrConflict, "instance %s is already managed by %v", instance.ID, managedBy)
+	}
+	if cluster, ok := instance.Tags[clusterTag]; ok && fmt.Sprint(cluster) != c.clusterName {
+		return newError(ErrConflict, "instance %s belongs to cluster %v", instance.ID, cluster)
+	}
+	if service, ok := instance.Tags["k8s-service"]; ok && fmt.Sprint(service) != name {
+		return newError(ErrConflict, "instance %s is already the load balancer of Service %v", instance.ID, service)
+	}
+	return nil
+}
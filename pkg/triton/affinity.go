@@ -0,0 +1,90 @@
+package triton
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// affinityRulePattern matches a Triton affinity rule: a key ("instance",
+// "container" or a tag name), an operator and a value, which may be a glob or
+// a /regular expression/
+var affinityRulePattern = regexp.MustCompile(`^([A-Za-z0-9_.:-]+)\s*(==~|!=~|==|!=)\s*(\S+)$`)
+
+// ValidateAffinityRule checks that rule uses the Triton affinity syntax
+// "<key><op><value>", where op is one of ==, !=, ==~ or !=~
+func ValidateAffinityRule(rule string) error {
+	m := affinityRulePattern.FindStringSubmatch(rule)
+	if m == nil {
+		return fmt.Errorf("invalid affinity rule %q: expected <key><op><value> with op one of ==, !=, ==~, !=~", rule)
+	}
+
+	value := m[3]
+	if len(value) > 1 && value[0] == '/' && value[len(value)-1] == '/' {
+		if _, err := regexp.Compile(value[1 : len(value)-1]); err != nil {
+			return fmt.Errorf("invalid affinity rule %q: %v", rule, err)
+		}
+	} else if value[0] == '/' || value[len(value)-1] == '/' {
+		return fmt.Errorf("invalid affinity rule %q: unterminated regular expression", rule)
+	}
+	return nil
+}
+
+// instanceUUIDPattern matches a Triton instance UUID
+var instanceUUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidateLocality checks that the locality hints of params are instance
+// UUIDs and aren't combined with affinity rules, which CloudAPI refuses
+func ValidateLocality(params LoadBalancerParams) error {
+	for _, id := range append(append([]string{}, params.LocalityNear...), params.LocalityFar...) {
+		if !instanceUUIDPattern.MatchString(id) {
+			return fmt.Errorf("invalid locality hint %q: must be an instance UUID", id)
+		}
+	}
+	if len(params.Affinity) > 0 && (len(params.LocalityNear) > 0 || len(params.LocalityFar) > 0) {
+		return fmt.Errorf("locality hints can't be combined with affinity rules")
+	}
+	return nil
+}
+
+// setPlacement sets the affinity rules and locality hints of params on
+// input. With SpreadReplicas the peers other than the instance itself are
+// added as far hints, or as affinity rules when params use those.
+func setPlacement(input *compute.CreateInstanceInput, params LoadBalancerParams, peers []*compute.Instance) error {
+	for _, rule := range params.Affinity {
+		if err := ValidateAffinityRule(rule); err != nil {
+			return err
+		}
+	}
+	if err := ValidateLocality(params); err != nil {
+		return err
+	}
+	input.Affinity = params.Affinity
+	input.LocalityNear = params.LocalityNear
+	input.LocalityFar = params.LocalityFar
+	input.LocalityStrict = params.LocalityStrict
+	if !params.SpreadReplicas {
+		return nil
+	}
+
+	far := append([]string{}, params.LocalityFar...)
+	affinity := append([]string{}, params.Affinity...)
+	for _, peer := range peers {
+		if peer.Name == input.Name {
+			// The instance being replaced
+			continue
+		}
+		if len(params.Affinity) > 0 {
+			affinity = append(affinity, "instance!="+peer.Name)
+		} else {
+			far = append(far, peer.ID)
+		}
+	}
+	if len(params.Affinity) > 0 {
+		input.Affinity = affinity
+	} else if len(far) > 0 {
+		input.LocalityFar = far
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package triton
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithLookupCache keeps the results of GetLoadBalancer and GetInstanceByName
+// for ttl, so reconciles that find nothing to change don't list and get the
+// same instances again. Every request that changes an instance, made through
+// this client, drops the cached results. A non-positive ttl disables the
+// cache.
+func WithLookupCache(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		if ttl <= 0 {
+			c.cache = nil
+			return
+		}
+		c.cache = &lookupCache{ttl: ttl}
+	}
+}
+
+// lookupCache holds lookup results by kind and load balancer name for a
+// short time. The nil cache holds nothing.
+type lookupCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[lookupKey]lookupEntry
+	// generation counts invalidations, so a lookup that started before one
+	// doesn't store what it read
+	generation uint64
+}
+
+// lookupKey identifies a cached result
+type lookupKey struct {
+	kind string
+	name string
+}
+
+// lookupEntry is a cached result and when it expires
+type lookupEntry struct {
+	value   any
+	expires time.Time
+}
+
+// get returns the unexpired result of the lookup kind for name
+func (l *lookupCache) get(kind, name string) (any, bool) {
+	if l == nil {
+		return nil, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[lookupKey{kind, name}]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// start returns the generation to pass to put for a lookup starting now
+func (l *lookupCache) start() uint64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.generation
+}
+
+// put stores the result of the lookup kind for name, unless the cache was
+// invalidated since the lookup started at generation
+func (l *lookupCache) put(kind, name string, generation uint64, value any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if generation != l.generation {
+		return
+	}
+	if l.entries == nil {
+		l.entries = map[lookupKey]lookupEntry{}
+	}
+	now := time.Now()
+	for key, entry := range l.entries {
+		if now.After(entry.expires) {
+			delete(l.entries, key)
+		}
+	}
+	l.entries[lookupKey{kind, name}] = lookupEntry{value: value, expires: now.Add(l.ttl)}
+}
+
+// invalidate drops every cached result
+func (l *lookupCache) invalidate() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+	l.generation++
+}
+
+// readOnlyOp reports whether the CloudAPI request op only reads, so it
+// leaves cached lookups valid
+func readOnlyOp(op string) bool {
+	return strings.HasPrefix(op, "List") || strings.HasPrefix(op, "Get")
+}
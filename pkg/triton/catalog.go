@@ -0,0 +1,144 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// Defaults used for load balancers that don't set a package or image, unless
+// overridden by $TRITON_LB_PACKAGE and $TRITON_LB_IMAGE
+const (
+	DefaultPackage = "g4-highcpu-1G"
+	DefaultImage   = "70e3ae72-96b6-11ea-9274-2f3c66e8b2c4" // HAProxy image
+)
+
+// uuidPattern matches image and package IDs
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// catalogAPI is the subset of the CloudAPI package and image clients used to
+// validate per-Service overrides
+type catalogAPI interface {
+	GetPackage(ctx context.Context, input *compute.GetPackageInput) (*compute.Package, error)
+	GetImage(ctx context.Context, input *compute.GetImageInput) (*compute.Image, error)
+	ListImages(ctx context.Context, input *compute.ListImagesInput) ([]*compute.Image, error)
+}
+
+// computeCatalog implements catalogAPI with a compute client
+type computeCatalog struct {
+	client *compute.ComputeClient
+}
+
+func (c computeCatalog) GetPackage(ctx context.Context, input *compute.GetPackageInput) (*compute.Package, error) {
+	return c.client.Packages().Get(ctx, input)
+}
+
+func (c computeCatalog) GetImage(ctx context.Context, input *compute.GetImageInput) (*compute.Image, error) {
+	return c.client.Images().Get(ctx, input)
+}
+
+func (c computeCatalog) ListImages(ctx context.Context, input *compute.ListImagesInput) ([]*compute.Image, error) {
+	return c.client.Images().List(ctx, input)
+}
+
+// catalogClient returns the package and image API client
+func (c *Client) catalogClient() (catalogAPI, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.catalog == nil {
+		return nil, fmt.Errorf("package and image API is not available")
+	}
+	return c.catalog, nil
+}
+
+// ValidateCatalog looks up the package and image overrides of params, so a
+// typo can be reported before anything is provisioned. Overrides that don't
+// exist, like a datacenter the client doesn't reach, fail with ErrNotFound.
+func (c *Client) ValidateCatalog(ctx context.Context, params LoadBalancerParams) error {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return err
+		}
+		return dc.ValidateCatalog(ctx, params)
+	}
+	if _, err := c.resolvePackage(ctx, params); err != nil {
+		return err
+	}
+	_, err := c.resolveImage(ctx, params)
+	return err
+}
+
+// resolvePackage returns the package load balancers described by params are
+// provisioned with. A package set in params, by name or ID, is looked up so a
+// typo is reported instead of failing provisioning.
+func (c *Client) resolvePackage(ctx context.Context, params LoadBalancerParams) (string, error) {
+	if params.Package == "" {
+		return c.currentDefaults().Package, nil
+	}
+
+	catalog, err := c.catalogClient()
+	if err != nil {
+		return "", err
+	}
+	var pkg *compute.Package
+	err = c.call(ctx, "GetPackage", func(ctx context.Context) error {
+		var err error
+		pkg, err = catalog.GetPackage(ctx, &compute.GetPackageInput{ID: params.Package})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid package %q: %w", params.Package, err)
+	}
+	return pkg.Name, nil
+}
+
+// resolveImage returns the ID of the image load balancers described by params
+// are provisioned with. An image set in params is either an ID or a name with
+// an optional @version; a name without a version selects the most recently
+// published image of that name.
+func (c *Client) resolveImage(ctx context.Context, params LoadBalancerParams) (string, error) {
+	if params.Image == "" {
+		return c.currentDefaults().Image, nil
+	}
+
+	catalog, err := c.catalogClient()
+	if err != nil {
+		return "", err
+	}
+	if uuidPattern.MatchString(params.Image) {
+		var image *compute.Image
+		err = c.call(ctx, "GetImage", func(ctx context.Context) error {
+			var err error
+			image, err = catalog.GetImage(ctx, &compute.GetImageInput{ImageID: params.Image})
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("invalid image %q: %w", params.Image, err)
+		}
+		return image.ID, nil
+	}
+
+	name, version, _ := strings.Cut(params.Image, "@")
+	var images []*compute.Image
+	err = c.call(ctx, "ListImages", func(ctx context.Context) error {
+		var err error
+		images, err = catalog.ListImages(ctx, &compute.ListImagesInput{Name: name, Version: version})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up image %q: %w", params.Image, err)
+	}
+	var latest *compute.Image
+	for _, image := range images {
+		if latest == nil || image.PublishedAt.After(latest.PublishedAt) {
+			latest = image
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("invalid image %q: no image with that name and version: %w", params.Image, ErrNotFound)
+	}
+	return latest.ID, nil
+}
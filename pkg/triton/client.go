@@ -2,39 +2,207 @@ package triton
 
 import (
 	"context"
-	"encoding/pem"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 
-	triton "github.com/joyent/triton-go/v2"
-	"github.com/joyent/triton-go/v2/authentication"
 	"github.com/joyent/triton-go/v2/compute"
 	"github.com/joyent/triton-go/v2/network"
+	"golang.org/x/time/rate"
 )
 
+// ErrNetworkUnavailable is returned by network-dependent operations when the
+// network API client could not be initialized
+var ErrNetworkUnavailable = errors.New("network API unavailable")
+
+// ProvisionInterruptedError is returned when the context is cancelled while
+// waiting for a newly created instance to finish provisioning. The instance
+// keeps provisioning in Triton and can be waited on again by ID.
+type ProvisionInterruptedError struct {
+	InstanceID string
+	Err        error
+}
+
+func (e *ProvisionInterruptedError) Error() string {
+	return fmt.Sprintf("context cancelled while waiting for load balancer instance %s to provision: %v", e.InstanceID, e.Err)
+}
+
+func (e *ProvisionInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// InstanceFailedError is returned when a load balancer instance reaches a
+// terminal state such as failed instead of running. Waiting longer won't
+// help; the instance has to be deleted and provisioned again.
+type InstanceFailedError struct {
+	InstanceID string
+	State      string
+}
+
+func (e *InstanceFailedError) Error() string {
+	return fmt.Sprintf("load balancer instance %s is in terminal state %s", e.InstanceID, e.State)
+}
+
+// DefaultManagerID is the managed-by tag value used when no manager ID is configured
+const DefaultManagerID = "triton-loadbalancer-controller"
+
+// defaultPageSize is the number of instances requested per List call, which is
+// the maximum CloudAPI allows
+const defaultPageSize = 1000
+
+// clusterTag records the Kubernetes cluster that owns a load balancer
+const clusterTag = "cluster"
+
+// deleteAttempts bounds how many times a delete request failing with a
+// transient error that call doesn't retry, such as a refused connection, is
+// sent
+const deleteAttempts = 3
+
+// deleteRetryBackoff is the initial wait between those delete attempts; it
+// doubles after each failure
+var deleteRetryBackoff = 2 * time.Second
+
+// instancesAPI is the subset of the CloudAPI instances client used by Client
+type instancesAPI interface {
+	List(ctx context.Context, input *compute.ListInstancesInput) ([]*compute.Instance, error)
+	Get(ctx context.Context, input *compute.GetInstanceInput) (*compute.Instance, error)
+	Create(ctx context.Context, input *compute.CreateInstanceInput) (*compute.Instance, error)
+	Delete(ctx context.Context, input *compute.DeleteInstanceInput) error
+	UpdateMetadata(ctx context.Context, input *compute.UpdateMetadataInput) (map[string]interface{}, error)
+	ReplaceTags(ctx context.Context, input *compute.ReplaceTagsInput) error
+	Rename(ctx context.Context, input *compute.RenameInstanceInput) error
+	DeleteMetadata(ctx context.Context, input *compute.DeleteMetadataInput) error
+	AddNIC(ctx context.Context, input *compute.AddNICInput) (*compute.NIC, error)
+	RemoveNIC(ctx context.Context, input *compute.RemoveNICInput) error
+	Reboot(ctx context.Context, input *compute.RebootInstanceInput) error
+	Resize(ctx context.Context, input *compute.ResizeInstanceInput) error
+	EnableFirewall(ctx context.Context, input *compute.EnableFirewallInput) error
+}
+
+// networksAPI is the subset of the CloudAPI network client used by Client
+type networksAPI interface {
+	List(ctx context.Context, input *network.ListInput) ([]*network.Network, error)
+}
+
 // Client wraps the Triton API clients and provides methods for interacting with load balancers
 type Client struct {
-	compute *compute.ComputeClient
-	network *network.NetworkClient
+	instances instancesAPI
+	network   networksAPI
+
+	// networkErr records why the network client is unavailable, if it is
+	networkErr error
+
+	// catalog looks up the packages and images of Package and Image overrides
+	catalog catalogAPI
+
+	// firewall manages the Cloud Firewall rules of listen ports
+	firewall firewallAPI
+
+	// mu guards network, networkErr, catalog and firewall, which
+	// SetCredentials replaces
+	mu sync.RWMutex
+
+	// pageSize overrides defaultPageSize when listing instances
+	pageSize int
+
+	// managerID is the managed-by tag value identifying instances owned by this controller
+	managerID string
+
+	// apiTimeout bounds each individual CloudAPI request; zero means no per-call limit
+	apiTimeout time.Duration
+
+	// clusterName, when set, is written to the cluster tag and required on
+	// every listed instance so clusters sharing an account stay isolated
+	clusterName string
+
+	// reloadKeys overrides DefaultReloadKeys
+	reloadKeys []string
+
+	// asyncProvisioning returns newly created instances without waiting for
+	// them to finish provisioning
+	asyncProvisioning bool
+
+	// manageFirewall enables Cloud Firewall on instances and keeps rules
+	// allowing traffic to their listen ports
+	manageFirewall bool
+
+	// userScript, when set, is rendered into the metadata of new instances
+	userScript *template.Template
+
+	// limiter bounds the rate of CloudAPI requests; nil means no limit
+	limiter *rate.Limiter
+
+	// retryAttempts is how many times a throttled or failed request is made;
+	// zero or one means it isn't retried
+	retryAttempts int
+
+	// cache holds recent lookups by name; nil means every lookup calls CloudAPI
+	cache *lookupCache
+
+	// datacenter is the datacenter this client's CloudAPI serves, when it
+	// was created by NewMultiDatacenterClient
+	datacenter Datacenter
+
+	// datacenters holds the client of every datacenter, this one first, on
+	// the default client of NewMultiDatacenterClient
+	datacenters []*Client
+
+	// defaults holds the Defaults set by WithDefaults or SetDefaults
+	defaults atomic.Pointer[Defaults]
 }
 
-// NewClient creates a new Triton client with the provided credentials
-func NewClient(account, keyID, keyPath, url string) (*Client, error) {
-	if account == "" {
-		return nil, fmt.Errorf("Triton account name is required")
+// ClientOption configures optional Client behavior
+type ClientOption func(*Client)
+
+// WithManagerID sets the managed-by tag value used to create and find
+// instances, allowing several controllers to share one Triton account
+func WithManagerID(id string) ClientOption {
+	return func(c *Client) {
+		if id != "" {
+			c.managerID = id
+		}
 	}
-	if keyID == "" {
-		return nil, fmt.Errorf("Triton key ID is required")
+}
+
+// WithClusterName scopes the client to load balancers tagged with the given
+// cluster name. The name must be unique per Kubernetes cluster.
+func WithClusterName(name string) ClientOption {
+	return func(c *Client) {
+		c.clusterName = name
+	}
+}
+
+// WithAPITimeout bounds each individual CloudAPI request so that a single hung
+// request fails quickly instead of consuming the whole provision timeout
+func WithAPITimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.apiTimeout = timeout
+	}
+}
+
+// WithAsyncProvisioning makes CreateLoadBalancer and scale ups return as soon
+// as CloudAPI has accepted the new instances, still provisioning, instead of
+// polling until they are running. The caller checks on them later with
+// GetInstanceState, and UpdateLoadBalancer leaves them alone until they run.
+func WithAsyncProvisioning(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.asyncProvisioning = enabled
 	}
+}
+
+// NewClient creates a new Triton client with the provided credentials
+func NewClient(account, keyID, keyPath, url string, opts ...ClientOption) (*Client, error) {
 	if keyPath == "" {
 		return nil, fmt.Errorf("Triton key path is required")
 	}
-	if url == "" {
-		return nil, fmt.Errorf("Triton API URL is required")
-	}
 
 	// Read the SSH private key file
 	privateKeyData, err := os.ReadFile(keyPath)
@@ -42,84 +210,402 @@ func NewClient(account, keyID, keyPath, url string) (*Client, error) {
 		return nil, fmt.Errorf("failed to read private key from %s: %v", keyPath, err)
 	}
 
-	// Parse the private key
-	block, _ := pem.Decode(privateKeyData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block containing private key, check if file is in valid PEM format")
+	return NewClientWithCredentials(Credentials{
+		Account:    account,
+		KeyID:      keyID,
+		PrivateKey: privateKeyData,
+		URL:        url,
+	}, opts...)
+}
+
+// NewClientWithCredentials creates a new Triton client from credentials held
+// in memory, such as those loaded from a Kubernetes Secret
+func NewClientWithCredentials(creds Credentials, opts ...ClientOption) (*Client, error) {
+	apis, err := newCloudAPIs(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		instances: &rotatingInstances{api: apis.instances},
+		managerID: DefaultManagerID,
+	}
+	c.network, c.networkErr = apis.network, apis.networkErr
+	c.catalog, c.firewall = apis.catalog, apis.firewall
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// Verify connection with a simple API call
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.verifyConnection(ctx, apis.instances, creds.URL); err != nil {
+		return nil, err
 	}
 
-	// Check if it's an encrypted key
-	if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
-		return nil, fmt.Errorf("encrypted private keys are not supported, please decrypt the key first")
+	return c, nil
+}
+
+// call runs a CloudAPI request within the rate limit, retrying it as
+// configured by WithRetries. Each attempt is bounded by the per-call timeout
+// if one is configured. Cancellation of the parent context still propagates,
+// and a per-call timeout is reported distinctly from the caller's own
+// deadline. Errors are wrapped with their class, such as ErrTransient or
+// ErrNotFound.
+func (c *Client) call(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if !readOnlyOp(op) {
+		// The request may have changed instances even if it failed
+		defer c.cache.invalidate()
 	}
+	return c.callWithRetries(ctx, op, fn)
+}
 
-	// Create signer input
-	input := authentication.PrivateKeySignerInput{
-		KeyID:              keyID,
-		PrivateKeyMaterial: privateKeyData,
-		AccountName:        account,
+// callOnce makes a single attempt at a CloudAPI request
+func (c *Client) callOnce(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if err := c.waitRateLimit(ctx, op); err != nil {
+		return err
+	}
+	if c.apiTimeout <= 0 {
+		return classify(fn(ctx))
 	}
 
-	signer, err := authentication.NewPrivateKeySigner(input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create private key signer: %v", err)
+	callCtx, cancel := context.WithTimeout(ctx, c.apiTimeout)
+	defer cancel()
+
+	err := fn(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return &Error{
+			Class: ErrTransient,
+			Err:   fmt.Errorf("CloudAPI %s request exceeded per-call timeout of %s: %w", op, c.apiTimeout, err),
+		}
 	}
+	return classify(err)
+}
 
-	config := &triton.ClientConfig{
-		TritonURL:   url,
-		AccountName: account,
-		Signers:     []authentication.Signer{signer},
+// NetworkError returns the error encountered while initializing the network
+// client, or nil if the network API is available
+func (c *Client) NetworkError() error {
+	if len(c.datacenters) > 0 {
+		var errs []error
+		for _, dc := range c.datacenters {
+			if err := dc.networkError(); err != nil {
+				errs = append(errs, fmt.Errorf("datacenter %s: %w", dc.datacenter.Name, err))
+			}
+		}
+		return errors.Join(errs...)
 	}
+	return c.networkError()
+}
 
-	computeClient, err := compute.NewClient(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create compute client: %v", err)
+// networkError is NetworkError of this client's own CloudAPI
+func (c *Client) networkError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.networkErr
+}
+
+// networkClient returns the network API client, or ErrNetworkUnavailable if it
+// could not be initialized
+func (c *Client) networkClient() (networksAPI, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.network == nil {
+		if c.networkErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNetworkUnavailable, c.networkErr)
+		}
+		return nil, ErrNetworkUnavailable
 	}
+	return c.network, nil
+}
 
-	networkClient, err := network.NewClient(config)
+// ListPublicNetworks returns the IDs of the public networks available to the account
+func (c *Client) ListPublicNetworks(ctx context.Context) ([]string, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.ListPublicNetworks(ctx)
+	}
+	networkClient, err := c.networkClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create network client: %v", err)
+		return nil, err
 	}
 
-	// Verify connection with a simple API call
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	_, err = computeClient.Instances().List(ctx, &compute.ListInstancesInput{})
+	var networks []*network.Network
+	err = c.call(ctx, "ListNetworks", func(ctx context.Context) error {
+		var err error
+		networks, err = networkClient.List(ctx, &network.ListInput{})
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Triton API at %s: %v", url, err)
+		return nil, fmt.Errorf("failed to list networks: %w", err)
 	}
 
-	return &Client{
-		compute: computeClient,
-		network: networkClient,
-	}, nil
+	var ids []string
+	for _, n := range networks {
+		if n.Public {
+			ids = append(ids, n.Id)
+		}
+	}
+
+	return ids, nil
 }
 
 // LoadBalancerParams defines the parameters for creating a load balancer
 type LoadBalancerParams struct {
 	Name            string
+	ServiceName     string // name of the owning Service if it differs from Name, recorded as a tag
+	Namespace       string // namespace of the owning Service, recorded as a tag
+	Datacenter      string // datacenter the instances run in; empty means the default
 	PortMappings    []PortMapping
 	MaxBackends     int
 	CertificateName string
 	MetricsACL      []string
+	ProxyProtocol   bool // send PROXY protocol headers to backends
+	Sticky          bool // pin each client IP to one backend
+
+	// ExternalTrafficPolicy is "Local" when the LB should only use backends
+	// with local endpoints; empty means the default Cluster behaviour
+	ExternalTrafficPolicy string
+
+	// Replicas is the number of load balancer instances to run; zero means one
+	Replicas int
+
+	// Certificate and CertificateKey hold a PEM certificate chain and private
+	// key installed on the load balancer for TLS termination
+	Certificate    string
+	CertificateKey string
+
+	// Tags are additional user tags set on every instance; reserved tags
+	// used by the controller are ignored
+	Tags map[string]string
+
+	// Affinity holds Triton locality rules such as "instance!=backend-*"
+	// applied when provisioning instances
+	Affinity []string
+
+	// LocalityNear and LocalityFar are instance UUIDs whose compute nodes the
+	// instances are provisioned on or kept off. They are hints unless
+	// LocalityStrict is set, and can't be combined with Affinity.
+	LocalityNear   []string
+	LocalityFar    []string
+	LocalityStrict bool
+
+	// SpreadReplicas provisions every replica on a compute node without
+	// another replica of the load balancer
+	SpreadReplicas bool
+
+	// BackendWeights splits traffic between named backends, e.g. for canary
+	// rollouts; nil sends traffic evenly
+	BackendWeights map[string]int
+
+	// AllocatePublicIP attaches a NIC on a public network to instances that
+	// only have private or fabric addresses
+	AllocatePublicIP bool
+
+	// ReloadOnChange reboots instances when UpdateLoadBalancer changes one
+	// of the client's reload keys, for images that only read them at boot
+	ReloadOnChange bool
+
+	// TimeoutConnect, TimeoutClient and TimeoutServer override the HAProxy
+	// connect, client and server timeouts; zero keeps the image default
+	TimeoutConnect time.Duration
+	TimeoutClient  time.Duration
+	TimeoutServer  time.Duration
+
+	// MaxConnections caps the concurrent connections HAProxy opens to each
+	// backend server; zero keeps the image default
+	MaxConnections int
+
+	// DrainTimeout is how long UpdateLoadBalancer lets replicas it removes
+	// or replaces finish their connections before deleting them; zero
+	// deletes them right away
+	DrainTimeout time.Duration
+
+	// ProvisionTimeout and DeleteTimeout bound how long instances are
+	// waited for to run and to be deleted; zero means the client's Defaults
+	ProvisionTimeout time.Duration
+	DeleteTimeout    time.Duration
+
+	// HealthCheckPath makes backends of HTTP listeners healthy only when a
+	// GET of the path succeeds rather than on TCP connect. The interval,
+	// timeout and number of failed checks before a backend is marked down
+	// apply to every check; zero keeps the image default.
+	HealthCheckPath               string
+	HealthCheckInterval           time.Duration
+	HealthCheckTimeout            time.Duration
+	HealthCheckUnhealthyThreshold int
+
+	// Package and Image override the package, by name or ID, and the image,
+	// by ID or name[@version], instances are provisioned with; empty means
+	// DefaultPackage and DefaultImage
+	Package string
+	Image   string
+
+	// Networks are the names or IDs of the networks instances are attached
+	// to when provisioned; empty means the account's default networks
+	Networks []string
+
+	// FirewallSourceRanges restricts the managed firewall rules of the
+	// listen ports to these addresses and CIDRs; empty allows any source
+	FirewallSourceRanges []string
+
+	// Instance is the load balancer's first replica, with every replica in
+	// its Replicas, when returned by GetLoadBalancer or ListLoadBalancers
+	Instance *TritonInstance
+
+	// PortMapErr is set by GetLoadBalancer when some portmap entries stored
+	// on the instance could not be parsed and were dropped
+	PortMapErr error
 }
 
 // PortMapping represents a port mapping configuration for the load balancer
 type PortMapping struct {
-	Type        string // http, https, or tcp
-	ListenPort  int
-	BackendName string
-	BackendPort int
+	Type        string `json:"type"` // http, https, tcp, or udp
+	ListenPort  int    `json:"listenPort"`
+	BackendName string `json:"backendName"`
+	BackendPort int    `json:"backendPort"`
+
+	// ProxyProtocol sends PROXY protocol headers to the backends of this
+	// listener, written as a "proxy-" prefix of its type
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+}
+
+// proxyTypePrefix marks a portmap listener type whose backends receive PROXY
+// protocol headers, e.g. "proxy-tcp"
+const proxyTypePrefix = "proxy-"
+
+// String returns the mapping in portmap format:
+// "[proxy-]<type>://<listen port>:<backend name>[:<backend port>]"
+func (m PortMapping) String() string {
+	portType := m.Type
+	if m.ProxyProtocol {
+		portType = proxyTypePrefix + portType
+	}
+	entry := portType + "://" + strconv.Itoa(m.ListenPort) + ":" + m.BackendName
+	if m.BackendPort > 0 {
+		entry += ":" + strconv.Itoa(m.BackendPort)
+	}
+	return entry
+}
+
+// managedBy returns the managed-by tag value for this client
+func (c *Client) managedBy() string {
+	if c.managerID == "" {
+		return DefaultManagerID
+	}
+	return c.managerID
+}
+
+// listManagedInstances returns all load balancer instances managed by this
+// controller with the given name, following CloudAPI pagination until every
+// page has been retrieved
+func (c *Client) listManagedInstances(ctx context.Context, name string) ([]*compute.Instance, error) {
+	return c.listManagedInstancesTagged(ctx, name, nil)
+}
+
+// listManagedInstancesTagged is listManagedInstances with additional tag filters
+func (c *Client) listManagedInstancesTagged(ctx context.Context, name string, tags map[string]interface{}) ([]*compute.Instance, error) {
+	return c.listInstancesInCluster(ctx, name, tags, c.clusterName)
+}
+
+// listInstancesInCluster lists instances managed by this controller that are
+// tagged with the given cluster name, or with any cluster if it is empty
+func (c *Client) listInstancesInCluster(ctx context.Context, name string, tags map[string]interface{}, clusterName string) ([]*compute.Instance, error) {
+	pageSize := c.pageSize
+	if pageSize <= 0 || pageSize > defaultPageSize {
+		pageSize = defaultPageSize
+	}
+
+	managerID := c.managedBy()
+
+	var instances []*compute.Instance
+	for offset := 0; ; offset += pageSize {
+		if offset > math.MaxUint16 {
+			return nil, fmt.Errorf("too many instances to list: offset %d exceeds CloudAPI limit", offset)
+		}
+
+		listInput := &compute.ListInstancesInput{
+			Name: name,
+			Tags: map[string]interface{}{
+				"loadbalancer": "true",
+				"managed-by":   managerID,
+			},
+			Limit:  uint16(pageSize),
+			Offset: uint16(offset),
+		}
+		if clusterName != "" {
+			listInput.Tags[clusterTag] = clusterName
+		}
+		for k, v := range tags {
+			listInput.Tags[k] = v
+		}
+
+		var page []*compute.Instance
+		err := c.call(ctx, "ListMachines", func(ctx context.Context) error {
+			var err error
+			page, err = c.instances.List(ctx, listInput)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Never touch instances owned by another controller or cluster,
+		// even if the API returns them
+		for _, instance := range page {
+			if fmt.Sprint(instance.Tags["managed-by"]) != managerID {
+				continue
+			}
+			if clusterName != "" && fmt.Sprint(instance.Tags[clusterTag]) != clusterName {
+				continue
+			}
+			instances = append(instances, instance)
+		}
+
+		// A short page means there are no more results
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	return instances, nil
 }
 
-// CreateLoadBalancer creates a new load balancer in Triton
-func (c *Client) CreateLoadBalancer(ctx context.Context, params LoadBalancerParams) error {
-	// Implementation for creating a load balancer via Triton CloudAPI
-	// This will include translating the LoadBalancerParams to the appropriate
-	// Triton API calls for creating a machine with the correct metadata
+// CreateLoadBalancer creates a new load balancer in Triton and returns the
+// provisioned instance. When more than one replica is requested every replica
+// is provisioned and returned in the Replicas field.
+func (c *Client) CreateLoadBalancer(ctx context.Context, params LoadBalancerParams) (*TritonInstance, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.CreateLoadBalancer(ctx, params)
+	}
+	for i := 1; i < params.ReplicaCount(); i++ {
+		if err := c.checkReplicaName(ctx, params.Name, i); err != nil {
+			return nil, err
+		}
+	}
+	var replicas []*compute.Instance
+	for i := 0; i < params.ReplicaCount(); i++ {
+		instance, err := c.createInstance(ctx, params, i, replicas)
+		if err != nil {
+			return nil, err
+		}
+		replicas = append(replicas, instance)
+	}
+	if err := c.syncFirewall(ctx, params, replicas); err != nil {
+		return nil, err
+	}
 
-	// Metadata we'll set for the load balancer
+	return newReplicaSet(replicas), nil
+}
+
+// buildMetadata translates the load balancer parameters into the
+// cloud.tritoncompute:* metadata read by the load balancer image
+func buildMetadata(params LoadBalancerParams) map[string]interface{} {
 	metadata := map[string]interface{}{
 		"cloud.tritoncompute:loadbalancer": "true",
 	}
@@ -131,16 +617,7 @@ func (c *Client) CreateLoadBalancer(ctx context.Context, params LoadBalancerPara
 		if i > 0 {
 			portmap += ","
 		}
-
-		// Convert integers to strings properly
-		listenPortStr := strconv.Itoa(mapping.ListenPort)
-
-		if mapping.BackendPort > 0 {
-			backendPortStr := strconv.Itoa(mapping.BackendPort)
-			portmap += mapping.Type + "://" + listenPortStr + ":" + mapping.BackendName + ":" + backendPortStr
-		} else {
-			portmap += mapping.Type + "://" + listenPortStr + ":" + mapping.BackendName
-		}
+		portmap += mapping.String()
 	}
 	metadata["cloud.tritoncompute:portmap"] = portmap
 
@@ -148,6 +625,18 @@ func (c *Client) CreateLoadBalancer(ctx context.Context, params LoadBalancerPara
 		metadata["cloud.tritoncompute:max_rs"] = strconv.Itoa(params.MaxBackends)
 	}
 
+	if params.MaxConnections > 0 {
+		metadata["cloud.tritoncompute:max_connections"] = strconv.Itoa(params.MaxConnections)
+	}
+
+	if params.HealthCheckPath != "" {
+		metadata["cloud.tritoncompute:health_check_path"] = params.HealthCheckPath
+	}
+
+	if params.HealthCheckUnhealthyThreshold > 0 {
+		metadata["cloud.tritoncompute:health_check_unhealthy_threshold"] = strconv.Itoa(params.HealthCheckUnhealthyThreshold)
+	}
+
 	if params.CertificateName != "" {
 		metadata["cloud.tritoncompute:certificate_name"] = params.CertificateName
 	}
@@ -164,42 +653,265 @@ func (c *Client) CreateLoadBalancer(ctx context.Context, params LoadBalancerPara
 		metadata["cloud.tritoncompute:metrics_acl"] = aclString
 	}
 
-	// Default values
-	packageName := os.Getenv("TRITON_LB_PACKAGE")
-	if packageName == "" {
-		packageName = "g4-highcpu-1G"
+	if params.Certificate != "" && params.CertificateKey != "" {
+		metadata["cloud.tritoncompute:certificate"] = params.Certificate
+		metadata["cloud.tritoncompute:certificate_key"] = params.CertificateKey
+	}
+
+	if params.ProxyProtocol {
+		metadata["cloud.tritoncompute:proxy_protocol"] = "true"
+	}
+
+	if params.Sticky {
+		metadata[stickyMetadataKey] = "true"
+	}
+
+	if params.ExternalTrafficPolicy != "" {
+		metadata["cloud.tritoncompute:external_traffic_policy"] = params.ExternalTrafficPolicy
+	}
+
+	if len(params.BackendWeights) > 0 {
+		metadata["cloud.tritoncompute:backend_weights"] = formatBackendWeights(params.BackendWeights)
+	}
+
+	// HAProxy reads bare numbers as milliseconds and doesn't understand Go's
+	// compound durations such as 1m30s
+	for key, timeout := range map[string]time.Duration{
+		"cloud.tritoncompute:timeout_connect": params.TimeoutConnect,
+		"cloud.tritoncompute:timeout_client":  params.TimeoutClient,
+		"cloud.tritoncompute:timeout_server":  params.TimeoutServer,
+
+		"cloud.tritoncompute:health_check_interval": params.HealthCheckInterval,
+		"cloud.tritoncompute:health_check_timeout":  params.HealthCheckTimeout,
+
+		provisionTimeoutMetadataKey: params.ProvisionTimeout,
+		deleteTimeoutMetadataKey:    params.DeleteTimeout,
+	} {
+		if timeout > 0 {
+			metadata[key] = strconv.FormatInt(timeout.Milliseconds(), 10) + "ms"
+		}
+	}
+
+	return metadata
+}
+
+// provisionTimeoutMetadataKey and deleteTimeoutMetadataKey record the
+// ProvisionTimeout and DeleteTimeout of a load balancer, so they also apply
+// when only its name or instance ID is known
+const (
+	provisionTimeoutMetadataKey = "cloud.tritoncompute:provision_timeout"
+	deleteTimeoutMetadataKey    = "cloud.tritoncompute:delete_timeout"
+)
+
+// metadataTimeout returns the timeout recorded under key on instance, or
+// zero if there is none
+func metadataTimeout(instance *compute.Instance, key string) time.Duration {
+	value, _ := instance.Metadata[key].(string)
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return timeout
+}
+
+// stickyMetadataKey pins the connections of a client IP to one backend
+const stickyMetadataKey = "cloud.tritoncompute:sticky"
+
+// changedMetadata returns the entries of desired whose value differs from, or
+// is missing in, current
+func changedMetadata(current, desired map[string]interface{}) map[string]interface{} {
+	changes := map[string]interface{}{}
+	for key, value := range desired {
+		if existing, ok := current[key]; !ok || fmt.Sprint(existing) != fmt.Sprint(value) {
+			changes[key] = value
+		}
+	}
+	return changes
+}
+
+// optionalMetadataKeys are the keys buildMetadata only writes when their
+// setting is on or not the default
+var optionalMetadataKeys = []string{
+	"cloud.tritoncompute:max_rs",
+	"cloud.tritoncompute:max_connections",
+	"cloud.tritoncompute:health_check_path",
+	"cloud.tritoncompute:health_check_unhealthy_threshold",
+	"cloud.tritoncompute:health_check_interval",
+	"cloud.tritoncompute:health_check_timeout",
+	"cloud.tritoncompute:certificate_name",
+	"cloud.tritoncompute:metrics_acl",
+	"cloud.tritoncompute:certificate",
+	"cloud.tritoncompute:certificate_key",
+	"cloud.tritoncompute:proxy_protocol",
+	stickyMetadataKey,
+	"cloud.tritoncompute:external_traffic_policy",
+	"cloud.tritoncompute:backend_weights",
+	"cloud.tritoncompute:timeout_connect",
+	"cloud.tritoncompute:timeout_client",
+	"cloud.tritoncompute:timeout_server",
+	provisionTimeoutMetadataKey,
+	deleteTimeoutMetadataKey,
+}
+
+// staleMetadata returns the optional keys set in current that desired no
+// longer sets. Metadata updates are merged into the existing metadata, so
+// these have to be deleted to turn their setting off.
+func staleMetadata(current, desired map[string]interface{}) []string {
+	var stale []string
+	for _, key := range optionalMetadataKeys {
+		if _, ok := current[key]; !ok {
+			continue
+		}
+		if _, ok := desired[key]; !ok {
+			stale = append(stale, key)
+		}
 	}
+	return stale
+}
 
-	imageId := os.Getenv("TRITON_LB_IMAGE")
-	if imageId == "" {
-		imageId = "70e3ae72-96b6-11ea-9274-2f3c66e8b2c4" // Default HAProxy image
+// deleteMetadata removes key from the metadata of instance
+func (c *Client) deleteMetadata(ctx context.Context, instance *compute.Instance, key string) error {
+	deleteInput := &compute.DeleteMetadataInput{
+		ID:  instance.ID,
+		Key: key,
+	}
+	err := c.call(ctx, "DeleteMachineMetadata", func(ctx context.Context) error {
+		return c.instances.DeleteMetadata(ctx, deleteInput)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete metadata %s of instance %s: %w", key, instance.ID, err)
+	}
+	delete(instance.Metadata, key)
+	return nil
+}
+
+// createInstance provisions a single load balancer replica and, unless
+// provisioning is asynchronous, waits for it to reach the running state.
+// peers are the other replicas, which SpreadReplicas keeps it away from.
+func (c *Client) createInstance(ctx context.Context, params LoadBalancerParams, index int, peers []*compute.Instance) (*compute.Instance, error) {
+	packageName, err := c.resolvePackage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	imageId, err := c.resolveImage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	networks, err := c.resolveNetworks(ctx, params)
+	if err != nil {
+		return nil, err
 	}
 
 	// Use Triton API to create the load balancer as a machine
 	createInput := &compute.CreateInstanceInput{
-		Name:     params.Name,
+		Name:     replicaName(params.Name, index),
 		Package:  packageName,
 		Image:    imageId,
-		Metadata: metadata,
-		Tags: map[string]interface{}{
-			"k8s-service":  params.Name,
-			"managed-by":   "triton-loadbalancer-controller",
-			"loadbalancer": "true",
-		},
+		Networks: networks,
+		Metadata: buildMetadata(params),
+		Tags:     c.managedTags(params, index),
+
+		FirewallEnabled: c.manageFirewall,
+	}
+	addUserTags(createInput.Tags, params.Tags)
+	if err := c.addUserScript(createInput.Metadata, params, index); err != nil {
+		return nil, err
+	}
+	if err := setPlacement(createInput, params, peers); err != nil {
+		return nil, err
 	}
 
-	instance, err := c.compute.Instances().Create(ctx, createInput)
-	if err != nil {
+	var instance *compute.Instance
+	err = c.call(ctx, "CreateMachine", func(ctx context.Context) error {
+		var err error
+		instance, err = c.instances.Create(ctx, createInput)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.asyncProvisioning {
+		// The public IP is attached by UpdateLoadBalancer once it's running
+		return instance, nil
+	}
+
+	instance, err = c.waitForRunning(ctx, instance.ID, createInput.Name, params.ProvisionTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.ensurePublicIP(ctx, instance, params); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// managedTags returns the tags identifying replica index of the load
+// balancer described by params as managed by this controller
+func (c *Client) managedTags(params LoadBalancerParams, index int) map[string]interface{} {
+	tags := map[string]interface{}{
+		"k8s-service":  params.serviceName(),
+		"managed-by":   c.managedBy(),
+		"loadbalancer": "true",
+	}
+	if params.Namespace != "" {
+		tags["k8s-namespace"] = params.Namespace
+	}
+	if c.clusterName != "" {
+		tags[clusterTag] = c.clusterName
 	}
+	if index > 0 {
+		tags[replicaOfTag] = params.Name
+	}
+	return tags
+}
 
-	// Get timeout settings from environment or use defaults
-	timeoutSeconds := 300 // Default: 5 minutes
-	if timeoutEnv := os.Getenv("TRITON_PROVISION_TIMEOUT"); timeoutEnv != "" {
-		if parsedTimeout, err := strconv.Atoi(timeoutEnv); err == nil && parsedTimeout > 0 {
-			timeoutSeconds = parsedTimeout
+// serviceName returns the name of the Service owning the load balancer
+func (p LoadBalancerParams) serviceName() string {
+	if p.ServiceName != "" {
+		return p.ServiceName
+	}
+	return p.Name
+}
+
+// WaitForInstance resumes waiting for a previously created load balancer
+// instance to finish provisioning and returns it once running. It waits for
+// the provision timeout of the load balancer, or of the client's Defaults.
+func (c *Client) WaitForInstance(ctx context.Context, id string) (*TritonInstance, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
 		}
+		return dc.WaitForInstance(ctx, id)
+	}
+	// The timeout recorded on the instance outlasts the reconcile that
+	// created it
+	var instance *compute.Instance
+	err := c.call(ctx, "GetMachine", func(ctx context.Context) error {
+		var err error
+		instance, err = c.instances.Get(ctx, &compute.GetInstanceInput{ID: id})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	instance, err = c.waitForRunning(ctx, id, id, metadataTimeout(instance, provisionTimeoutMetadataKey))
+	if err != nil {
+		return nil, err
+	}
+	return newTritonInstance(instance), nil
+}
+
+// waitForRunning polls the instance until it is running or timeout expires;
+// zero means the client's default provision timeout. If ctx is cancelled
+// first a *ProvisionInterruptedError carrying the instance ID is returned so
+// the caller can resume later, and an instance reaching a terminal state
+// fails with *InstanceFailedError.
+func (c *Client) waitForRunning(ctx context.Context, id, name string, timeout time.Duration) (*compute.Instance, error) {
+	if timeout <= 0 {
+		timeout = c.currentDefaults().ProvisionTimeout
+	}
+	timeoutSeconds := int(timeout / time.Second)
 
 	// Calculate how many iterations needed with 10 second intervals
 	maxIterations := timeoutSeconds / 10
@@ -207,56 +919,72 @@ func (c *Client) CreateLoadBalancer(ctx context.Context, params LoadBalancerPara
 		maxIterations = 1
 	}
 
+	interrupted := func() error {
+		return &ProvisionInterruptedError{InstanceID: id, Err: ctx.Err()}
+	}
+
 	// Wait for the instance to be provisioned
 	for i := 0; i < maxIterations; i++ {
 		select {
 		case <-ctx.Done():
-			return fmt.Errorf("context cancelled while waiting for load balancer to provision")
+			return nil, interrupted()
 		default:
-			getInput := &compute.GetInstanceInput{
-				ID: instance.ID,
-			}
-
-			currentInstance, err := c.compute.Instances().Get(ctx, getInput)
+			currentInstance, err := c.getInstance(ctx, id)
 			if err != nil {
-				return fmt.Errorf("error checking instance status: %v", err)
+				if ctx.Err() != nil {
+					return nil, interrupted()
+				}
+				return nil, fmt.Errorf("error checking instance status: %w", err)
 			}
 
-			if currentInstance.State == "running" {
-				return nil // Successfully provisioned
+			if isTerminalState(currentInstance.State) {
+				if currentInstance.State == "running" {
+					return currentInstance, nil // Successfully provisioned
+				}
+				// Stopped or failed: it won't come up without intervention
+				return nil, &InstanceFailedError{InstanceID: id, State: currentInstance.State}
 			}
 
 			// Log progress
 			if i%6 == 0 { // Every minute
 				fmt.Printf("Load balancer %s still provisioning (state: %s), waiting...\n",
-					params.Name, currentInstance.State)
+					name, currentInstance.State)
 			}
 
-			time.Sleep(10 * time.Second)
+			select {
+			case <-ctx.Done():
+				return nil, interrupted()
+			case <-time.After(10 * time.Second):
+			}
 		}
 	}
 
-	return fmt.Errorf("timed out waiting for load balancer to provision after %d seconds", timeoutSeconds)
+	return nil, fmt.Errorf("timed out waiting for load balancer to provision after %d seconds", timeoutSeconds)
 }
 
-// DeleteLoadBalancer deletes a load balancer in Triton
-func (c *Client) DeleteLoadBalancer(ctx context.Context, name string) error {
+// DeleteLoadBalancer deletes a load balancer and all of its replicas in
+// Triton. id is the ID of its first replica, if known; see GetLoadBalancer.
+func (c *Client) DeleteLoadBalancer(ctx context.Context, id, name string) error {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return err
+		}
+		return dc.DeleteLoadBalancer(ctx, id, name)
+	}
 	if name == "" {
 		return fmt.Errorf("load balancer name cannot be empty")
 	}
 
-	// Find instance by name
-	listInput := &compute.ListInstancesInput{
-		Name: name,
-		Tags: map[string]interface{}{
-			"loadbalancer": "true",
-			"managed-by":   "triton-loadbalancer-controller",
-		},
+	// Find every replica of the load balancer
+	instances, err := c.findReplicas(ctx, id, name)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
 	}
 
-	instances, err := c.compute.Instances().List(ctx, listInput)
-	if err != nil {
-		return fmt.Errorf("failed to list instances: %v", err)
+	// Remove the firewall rules first, so they aren't left behind once the
+	// instances are gone and the load balancer can no longer be found
+	if err := c.removeFirewall(ctx, name); err != nil {
+		return err
 	}
 
 	if len(instances) == 0 {
@@ -264,23 +992,20 @@ func (c *Client) DeleteLoadBalancer(ctx context.Context, name string) error {
 		return nil
 	}
 
-	// Delete the instance
-	deleteInput := &compute.DeleteInstanceInput{
-		ID: instances[0].ID,
-	}
-
-	err = c.compute.Instances().Delete(ctx, deleteInput)
-	if err != nil {
-		return fmt.Errorf("failed to delete instance %s: %v", instances[0].ID, err)
+	// Delete every match, including instances that share the name such as
+	// leftovers from a failed recreate
+	for _, instance := range instances {
+		fmt.Printf("Deleting load balancer %s instance %s (%s)\n", name, instance.ID, instance.Name)
+		if err := c.deleteInstance(ctx, instance.ID); err != nil {
+			return err
+		}
 	}
 
-	// Get timeout settings from environment or use defaults
-	timeoutSeconds := 300 // Default: 5 minutes
-	if timeoutEnv := os.Getenv("TRITON_DELETE_TIMEOUT"); timeoutEnv != "" {
-		if parsedTimeout, err := strconv.Atoi(timeoutEnv); err == nil && parsedTimeout > 0 {
-			timeoutSeconds = parsedTimeout
-		}
+	timeout := metadataTimeout(instances[0], deleteTimeoutMetadataKey)
+	if timeout <= 0 {
+		timeout = c.currentDefaults().DeleteTimeout
 	}
+	timeoutSeconds := int(timeout / time.Second)
 
 	// Calculate how many iterations needed with 10 second intervals
 	maxIterations := timeoutSeconds / 10
@@ -288,19 +1013,30 @@ func (c *Client) DeleteLoadBalancer(ctx context.Context, name string) error {
 		maxIterations = 1
 	}
 
-	// Wait for the instance to be deleted (no longer appears in list)
+	// Wait for every deleted instance to reach the deleted state
+	pending := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		pending = append(pending, instance.ID)
+	}
 	for i := 0; i < maxIterations; i++ {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("context cancelled while waiting for load balancer to be deleted")
 		default:
-			instances, err := c.compute.Instances().List(ctx, listInput)
-			if err != nil {
-				return fmt.Errorf("failed to check if instance was deleted: %v", err)
+			remaining := pending[:0]
+			for _, id := range pending {
+				state, err := c.GetInstanceState(ctx, id)
+				if err != nil {
+					return fmt.Errorf("failed to check if instance %s was deleted: %w", id, err)
+				}
+				if state != "deleted" && state != "destroyed" {
+					remaining = append(remaining, id)
+				}
 			}
+			pending = remaining
 
-			if len(instances) == 0 {
-				// Instance successfully deleted
+			if len(pending) == 0 {
+				// Instances successfully deleted
 				return nil
 			}
 
@@ -309,102 +1045,268 @@ func (c *Client) DeleteLoadBalancer(ctx context.Context, name string) error {
 				fmt.Printf("Waiting for load balancer %s to be deleted...\n", name)
 			}
 
-			// Sleep for 10 seconds before retrying
-			time.Sleep(10 * time.Second)
+			// Wait 10 seconds before retrying
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("context cancelled while waiting for load balancer to be deleted")
+			case <-time.After(10 * time.Second):
+			}
 		}
 	}
 
-	return fmt.Errorf("timed out waiting for load balancer %s to be deleted after %d seconds", name, timeoutSeconds)
+	return fmt.Errorf("delete of load balancer %s accepted but instance still visible after %d seconds", name, timeoutSeconds)
 }
 
-// UpdateLoadBalancer updates an existing load balancer in Triton
-func (c *Client) UpdateLoadBalancer(ctx context.Context, name string, params LoadBalancerParams) error {
-	// Find instance by name
-	listInput := &compute.ListInstancesInput{
-		Name: name,
-		Tags: map[string]interface{}{
-			"loadbalancer": "true",
-			"managed-by":   "triton-loadbalancer-controller",
-		},
+// DeleteInstance deletes a single load balancer instance by ID, such as a
+// replica that failed to provision, leaving the other replicas in place
+func (c *Client) DeleteInstance(ctx context.Context, id string) error {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return err
+		}
+		return dc.DeleteInstance(ctx, id)
+	}
+	return c.deleteInstance(ctx, id)
+}
+
+// deleteInstance issues the delete request for a single instance. Throttled
+// requests and server errors are retried by call; other transient failures
+// are retried here with exponential backoff.
+func (c *Client) deleteInstance(ctx context.Context, id string) error {
+	deleteInput := &compute.DeleteInstanceInput{
+		ID: id,
+	}
+
+	backoff := deleteRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.call(ctx, "DeleteMachine", func(ctx context.Context) error {
+			return c.instances.Delete(ctx, deleteInput)
+		})
+		if err == nil || errors.Is(err, ErrNotFound) {
+			// An instance that is already gone needs no deleting
+			return nil
+		}
+		if !IsTransientError(err) || retryable("DeleteMachine", err) {
+			return fmt.Errorf("delete request for instance %s failed: %w", id, err)
+		}
+		if attempt == deleteAttempts {
+			return fmt.Errorf("delete request for instance %s failed after %d attempt(s): %w", id, attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("context cancelled while retrying delete of instance %s: %v", id, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
+}
 
-	instances, err := c.compute.Instances().List(ctx, listInput)
+// UpdateLoadBalancer updates an existing load balancer in Triton, scaling its
+// replicas up or down to match params, and returns the updated instance. id
+// is the ID of its first replica, if known; see GetLoadBalancer. A first
+// replica found by ID under another name is renamed back to name.
+func (c *Client) UpdateLoadBalancer(ctx context.Context, id, name string, params LoadBalancerParams) (*TritonInstance, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.UpdateLoadBalancer(ctx, id, name, params)
+	}
+	// Find every replica of the load balancer
+	instances, err := c.findReplicas(ctx, id, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(instances) == 0 {
-		return fmt.Errorf("load balancer %s not found", name)
+		return nil, newError(ErrNotFound, "load balancer %s not found", name)
+	}
+	if primary := instances[0]; primary.ID == id && primary.Name != name {
+		if err := c.renameInstance(ctx, primary, name); err != nil {
+			return nil, err
+		}
 	}
 
-	// Prepare metadata for update
-	metadata := map[string]interface{}{
-		"cloud.tritoncompute:loadbalancer": "true",
+	metadata := buildMetadata(params)
+	desired := params.ReplicaCount()
+	params.Name = name
+
+	// Replicas are only resized for an explicit package, so changing the
+	// controller-wide default doesn't resize every load balancer
+	var pkg string
+	if params.Package != "" {
+		if pkg, err = c.resolvePackage(ctx, params); err != nil {
+			return nil, err
+		}
 	}
 
-	// Build the portmap string from the port mappings
-	var portmap string
-	for i, mapping := range params.PortMappings {
-		if i > 0 {
-			portmap += ","
+	existing := make(map[int]*compute.Instance, len(instances))
+	var kept, draining []*compute.Instance
+	replaced := false
+	for _, instance := range instances {
+		index := replicaIndex(instance.Name, name)
+		if index >= desired {
+			// Scale down: remove replicas beyond the desired count once
+			// they have drained; until then they are no longer published
+			drained, err := c.drained(ctx, instance, params.DrainTimeout)
+			if err != nil {
+				return nil, err
+			}
+			if !drained {
+				draining = append(draining, instance)
+				continue
+			}
+			if err := c.deleteInstance(ctx, instance.ID); err != nil {
+				return nil, err
+			}
+			continue
 		}
+		existing[index] = instance
 
-		listenPortStr := strconv.Itoa(mapping.ListenPort)
+		if c.asyncProvisioning && !isTerminalState(instance.State) {
+			// Still provisioning with the metadata it was created with;
+			// it's updated by a later call once it's running
+			kept = append(kept, instance)
+			continue
+		}
 
-		if mapping.BackendPort > 0 {
-			backendPortStr := strconv.Itoa(mapping.BackendPort)
-			portmap += mapping.Type + "://" + listenPortStr + ":" + mapping.BackendName + ":" + backendPortStr
-		} else {
-			portmap += mapping.Type + "://" + listenPortStr + ":" + mapping.BackendName
+		if pkg != "" && instance.Package != pkg {
+			err := c.resizeInstance(ctx, instance, pkg)
+			if err != nil && isResizeRejected(err) {
+				// Replace one replica at a time, and only while the others
+				// keep serving; later updates replace the rest
+				if replaced || !othersRunning(instances, instance) {
+					kept = append(kept, instance)
+					continue
+				}
+				replaced = true
+				drained, drainErr := c.drained(ctx, instance, params.DrainTimeout)
+				if drainErr != nil {
+					return nil, drainErr
+				}
+				if !drained {
+					draining = append(draining, instance)
+					continue
+				}
+				fmt.Printf("Replacing load balancer instance %s (%s), which can't be resized: %v\n", instance.Name, instance.ID, err)
+				if err := c.deleteInstance(ctx, instance.ID); err != nil {
+					return nil, err
+				}
+				replacement, err := c.createInstance(ctx, params, index, instances)
+				if err != nil {
+					return nil, err
+				}
+				kept = append(kept, replacement)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
 		}
-	}
-	metadata["cloud.tritoncompute:portmap"] = portmap
 
-	if params.MaxBackends > 0 {
-		metadata["cloud.tritoncompute:max_rs"] = strconv.Itoa(params.MaxBackends)
-	}
+		// A replica drained for an earlier scale down is wanted again
+		if err := c.cancelDrain(ctx, instance); err != nil {
+			return nil, err
+		}
 
-	if params.CertificateName != "" {
-		metadata["cloud.tritoncompute:certificate_name"] = params.CertificateName
-	}
+		// Compare before updating, which changes the listed instance's metadata
+		var changed []string
+		if params.ReloadOnChange {
+			changed = c.changedReloadKeys(instance, metadata)
+		}
 
-	if len(params.MetricsACL) > 0 {
-		var aclString string
-		for i, acl := range params.MetricsACL {
-			if i > 0 {
-				aclString += ","
+		// Update only the metadata keys that changed; listed instances
+		// include their current metadata
+		changes := changedMetadata(instance.Metadata, metadata)
+		stale := staleMetadata(instance.Metadata, metadata)
+		if len(changes) > 0 {
+			updateInput := &compute.UpdateMetadataInput{
+				ID:       instance.ID,
+				Metadata: changes,
+			}
+
+			err = c.call(ctx, "UpdateMachineMetadata", func(ctx context.Context) error {
+				_, err := c.instances.UpdateMetadata(ctx, updateInput)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, key := range stale {
+			if err := c.deleteMetadata(ctx, instance, key); err != nil {
+				return nil, err
 			}
-			aclString += acl
 		}
-		metadata["cloud.tritoncompute:metrics_acl"] = aclString
-	}
 
-	// Update the instance metadata
-	updateInput := &compute.UpdateMetadataInput{
-		ID:       instances[0].ID,
-		Metadata: metadata,
+		if err := c.syncUserTags(ctx, instance, params.Tags); err != nil {
+			return nil, err
+		}
+		if err := c.ensurePublicIP(ctx, instance, params); err != nil {
+			return nil, err
+		}
+		if len(changed) > 0 {
+			if err := c.rebootInstance(ctx, instance, changed); err != nil {
+				return nil, err
+			}
+		}
+		kept = append(kept, instance)
 	}
 
-	_, err = c.compute.Instances().UpdateMetadata(ctx, updateInput)
-	if err != nil {
-		return err
+	// Scale up: provision any replicas that are missing
+	for i := 0; i < desired; i++ {
+		if _, ok := existing[i]; ok {
+			continue
+		}
+		if err := c.checkReplicaName(ctx, name, i); err != nil {
+			return nil, err
+		}
+		instance, err := c.createInstance(ctx, params, i, kept)
+		if err != nil {
+			return nil, err
+		}
+		kept = append(kept, instance)
 	}
 
-	return nil
+	// Draining replicas stay reachable until they are deleted
+	sortReplicas(kept, name)
+	if err := c.syncFirewall(ctx, params, append(draining, kept...)); err != nil {
+		return nil, err
+	}
+	return newReplicaSet(kept), nil
 }
 
-// GetLoadBalancer retrieves information about a load balancer
-func (c *Client) GetLoadBalancer(ctx context.Context, name string) (*LoadBalancerParams, error) {
-	// Find instance by name
-	listInput := &compute.ListInstancesInput{
-		Name: name,
-		Tags: map[string]interface{}{
-			"loadbalancer": "true",
-			"managed-by":   "triton-loadbalancer-controller",
-		},
+// GetLoadBalancer retrieves information about a load balancer. id, if not
+// empty, is the ID of its first replica as returned when it was created; the
+// load balancer is then found by that instance even if it was renamed or
+// another instance has its name, and by name only if the instance is gone.
+// With WithLookupCache the result may be shared with other callers and must
+// not be modified.
+func (c *Client) GetLoadBalancer(ctx context.Context, id, name string) (*LoadBalancerParams, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.GetLoadBalancer(ctx, id, name)
+	}
+	key := id + "/" + name
+	if cached, ok := c.cache.get("loadbalancer", key); ok {
+		return cached.(*LoadBalancerParams), nil
+	}
+	generation := c.cache.start()
+	params, err := c.getLoadBalancer(ctx, id, name)
+	if err != nil {
+		return nil, err
 	}
+	c.cache.put("loadbalancer", key, generation, params)
+	return params, nil
+}
 
-	instances, err := c.compute.Instances().List(ctx, listInput)
+// getLoadBalancer is GetLoadBalancer without the cache
+func (c *Client) getLoadBalancer(ctx context.Context, id, name string) (*LoadBalancerParams, error) {
+	// Find every replica of the load balancer
+	instances, err := c.findReplicas(ctx, id, name)
 	if err != nil {
 		return nil, err
 	}
@@ -414,25 +1316,91 @@ func (c *Client) GetLoadBalancer(ctx context.Context, name string) (*LoadBalance
 		return nil, nil
 	}
 
-	// Get instance metadata to extract load balancer configuration
+	// Replicas share their configuration, so read it from the first one
 	getInput := &compute.GetInstanceInput{
 		ID: instances[0].ID,
 	}
 
-	instance, err := c.compute.Instances().Get(ctx, getInput)
+	var instance *compute.Instance
+	err = c.call(ctx, "GetMachine", func(ctx context.Context) error {
+		var err error
+		instance, err = c.instances.Get(ctx, getInput)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	params := parseLoadBalancer(name, instance)
+	params.Datacenter = c.datacenter.Name
+	params.Replicas = len(instances)
+	params.Instance = newReplicaSet(instances)
+	return params, nil
+}
+
+// ListLoadBalancers returns every load balancer managed by this controller,
+// sorted by name, with replicas grouped under the load balancer they belong to
+func (c *Client) ListLoadBalancers(ctx context.Context) ([]*LoadBalancerParams, error) {
+	if clients := c.spannedDatacenters(ctx); clients != nil {
+		return listDatacenters(ctx, clients, (*Client).ListLoadBalancers)
+	}
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.ListLoadBalancers(ctx)
+	}
+	instances, err := c.listManagedInstances(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed instances: %w", err)
+	}
+
+	groups := map[string][]*compute.Instance{}
+	for _, instance := range instances {
+		name := instance.Name
+		if owner, ok := instance.Tags[replicaOfTag].(string); ok && replicaIndex(instance.Name, owner) > 0 {
+			name = owner
+		}
+		groups[name] = append(groups[name], instance)
+	}
+
+	result := make([]*LoadBalancerParams, 0, len(groups))
+	for name, replicas := range groups {
+		sortReplicas(replicas, name)
+		// Listed instances include their metadata, so unlike
+		// GetLoadBalancer no further request is needed
+		params := parseLoadBalancer(name, replicas[0])
+		params.Datacenter = c.datacenter.Name
+		params.Replicas = len(replicas)
+		params.Instance = newReplicaSet(replicas)
+		result = append(result, params)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// parseLoadBalancer reads the configuration of the named load balancer from
+// the tags and metadata of one of its instances
+func parseLoadBalancer(name string, instance *compute.Instance) *LoadBalancerParams {
 	params := &LoadBalancerParams{
 		Name: name,
 	}
+	if service, ok := instance.Tags["k8s-service"].(string); ok && service != name {
+		params.ServiceName = service
+	}
+	if namespace, ok := instance.Tags["k8s-namespace"].(string); ok {
+		params.Namespace = namespace
+	}
 
 	// Extract configuration from metadata
 	if portmapVal, ok := instance.Metadata["cloud.tritoncompute:portmap"]; ok {
 		// Parse portmap string
 		if portmapStr, ok := portmapVal.(string); ok {
-			portMappings := parsePortMap(portmapStr)
+			portMappings, err := parsePortMapStrict(portmapStr)
+			if err != nil {
+				fmt.Printf("WARNING: load balancer %s has invalid portmap entries that were dropped: %v\n", name, err)
+				params.PortMapErr = err
+			}
 			params.PortMappings = portMappings
 		}
 	}
@@ -445,6 +1413,14 @@ func (c *Client) GetLoadBalancer(ctx context.Context, name string) (*LoadBalance
 		}
 	}
 
+	if maxConnVal, ok := instance.Metadata["cloud.tritoncompute:max_connections"]; ok {
+		if maxConnStr, ok := maxConnVal.(string); ok {
+			if maxConn, err := strconv.Atoi(maxConnStr); err == nil {
+				params.MaxConnections = maxConn
+			}
+		}
+	}
+
 	if certNameVal, ok := instance.Metadata["cloud.tritoncompute:certificate_name"]; ok {
 		if certName, ok := certNameVal.(string); ok {
 			params.CertificateName = certName
@@ -465,7 +1441,61 @@ func (c *Client) GetLoadBalancer(ctx context.Context, name string) (*LoadBalance
 		}
 	}
 
-	return params, nil
+	if proxyVal, ok := instance.Metadata["cloud.tritoncompute:proxy_protocol"]; ok {
+		if proxyStr, ok := proxyVal.(string); ok {
+			params.ProxyProtocol, _ = strconv.ParseBool(proxyStr)
+		}
+	}
+
+	if path, ok := instance.Metadata["cloud.tritoncompute:health_check_path"].(string); ok {
+		params.HealthCheckPath = path
+	}
+
+	if thresholdVal, ok := instance.Metadata["cloud.tritoncompute:health_check_unhealthy_threshold"].(string); ok {
+		params.HealthCheckUnhealthyThreshold, _ = strconv.Atoi(thresholdVal)
+	}
+
+	if stickyVal, ok := instance.Metadata[stickyMetadataKey]; ok {
+		if stickyStr, ok := stickyVal.(string); ok {
+			params.Sticky, _ = strconv.ParseBool(stickyStr)
+		}
+	}
+
+	if policyVal, ok := instance.Metadata["cloud.tritoncompute:external_traffic_policy"]; ok {
+		if policy, ok := policyVal.(string); ok {
+			params.ExternalTrafficPolicy = policy
+		}
+	}
+
+	if weightsVal, ok := instance.Metadata["cloud.tritoncompute:backend_weights"]; ok {
+		if weightsStr, ok := weightsVal.(string); ok {
+			weights, err := ParseBackendWeights(weightsStr)
+			if err != nil {
+				fmt.Printf("WARNING: load balancer %s has invalid backend weights: %v\n", name, err)
+			}
+			params.BackendWeights = weights
+		}
+	}
+
+	for key, timeout := range map[string]*time.Duration{
+		"cloud.tritoncompute:timeout_connect": &params.TimeoutConnect,
+		"cloud.tritoncompute:timeout_client":  &params.TimeoutClient,
+		"cloud.tritoncompute:timeout_server":  &params.TimeoutServer,
+
+		"cloud.tritoncompute:health_check_interval": &params.HealthCheckInterval,
+		"cloud.tritoncompute:health_check_timeout":  &params.HealthCheckTimeout,
+
+		provisionTimeoutMetadataKey: &params.ProvisionTimeout,
+		deleteTimeoutMetadataKey:    &params.DeleteTimeout,
+	} {
+		if val, ok := instance.Metadata[key].(string); ok {
+			if d, err := time.ParseDuration(val); err == nil {
+				*timeout = d
+			}
+		}
+	}
+
+	return params
 }
 
 // parsePortMap parses a port map string into PortMapping structs
@@ -484,7 +1514,7 @@ func parsePortMap(portmapStr string) []PortMapping {
 			continue
 		}
 
-		portType := parts[0]
+		portType, proxyProtocol := strings.CutPrefix(parts[0], proxyTypePrefix)
 
 		portParts := strings.Split(parts[1], ":")
 		if len(portParts) < 2 {
@@ -504,10 +1534,11 @@ func parsePortMap(portmapStr string) []PortMapping {
 		}
 
 		mapping := PortMapping{
-			Type:        portType,
-			ListenPort:  listenPort,
-			BackendName: backendName,
-			BackendPort: backendPort,
+			Type:          portType,
+			ListenPort:    listenPort,
+			BackendName:   backendName,
+			BackendPort:   backendPort,
+			ProxyProtocol: proxyProtocol,
 		}
 
 		mappings = append(mappings, mapping)
@@ -516,26 +1547,138 @@ func parsePortMap(portmapStr string) []PortMapping {
 	return mappings
 }
 
+// parsePortMapStrict parses a port map string like parsePortMap but reports
+// every malformed entry. The valid entries are returned alongside the error so
+// callers can keep working with what could be parsed.
+func parsePortMapStrict(portmapStr string) ([]PortMapping, error) {
+	if portmapStr == "" {
+		return nil, nil
+	}
+
+	var mappings []PortMapping
+	var errs []error
+	for i, entry := range strings.Split(portmapStr, ",") {
+		mapping, err := parsePortMapEntry(entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("entry %d %q: %w", i, entry, err))
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, errors.Join(errs...)
+}
+
+// parsePortMapEntry parses a single "[proxy-]<type>://<listen port>:<backend name>[:<backend port>]" entry
+func parsePortMapEntry(entry string) (PortMapping, error) {
+	portType, rest, ok := strings.Cut(entry, "://")
+	if !ok {
+		return PortMapping{}, errors.New("missing \"://\" separator")
+	}
+	portType, proxyProtocol := strings.CutPrefix(portType, proxyTypePrefix)
+	if portType == "" {
+		return PortMapping{}, errors.New("missing type")
+	}
+
+	portParts := strings.Split(rest, ":")
+	if len(portParts) < 2 || len(portParts) > 3 {
+		return PortMapping{}, errors.New("expected <listen port>:<backend name>[:<backend port>]")
+	}
+
+	listenPort, err := strconv.Atoi(portParts[0])
+	if err != nil || listenPort < 1 || listenPort > 65535 {
+		return PortMapping{}, fmt.Errorf("invalid listen port %q", portParts[0])
+	}
+
+	if portParts[1] == "" {
+		return PortMapping{}, errors.New("missing backend name")
+	}
+
+	var backendPort int
+	if len(portParts) == 3 {
+		backendPort, err = strconv.Atoi(portParts[2])
+		if err != nil || backendPort < 1 || backendPort > 65535 {
+			return PortMapping{}, fmt.Errorf("invalid backend port %q", portParts[2])
+		}
+	}
+
+	return PortMapping{
+		Type:          portType,
+		ListenPort:    listenPort,
+		BackendName:   portParts[1],
+		BackendPort:   backendPort,
+		ProxyProtocol: proxyProtocol,
+	}, nil
+}
+
 // TritonInstance represents a Triton compute instance with necessary information
 type TritonInstance struct {
-	ID   string
-	Name string
-	IPs  []string
-	Tags map[string]interface{}
+	ID    string
+	Name  string
+	State string
+	IPs   []string
+	Tags  map[string]interface{}
+
+	// Package is the name of the package the instance runs with
+	Package string
+
+	// DNSNames are the names Triton CNS publishes for the instance
+	DNSNames []string
+
+	// Replicas lists every instance of the load balancer, including this one,
+	// when it was returned by a create or update
+	Replicas []*TritonInstance
+}
+
+// ListManagedInstances returns every load balancer instance managed by this controller
+func (c *Client) ListManagedInstances(ctx context.Context) ([]*TritonInstance, error) {
+	if clients := c.spannedDatacenters(ctx); clients != nil {
+		return listDatacenters(ctx, clients, (*Client).ListManagedInstances)
+	}
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.ListManagedInstances(ctx)
+	}
+	instances, err := c.listManagedInstances(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed instances: %w", err)
+	}
+
+	result := make([]*TritonInstance, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, newTritonInstance(instance))
+	}
+	return result, nil
 }
 
-// GetInstanceByName retrieves a Triton instance by name
+// GetInstanceByName retrieves a Triton instance by name. With
+// WithLookupCache the result may be shared with other callers and must not
+// be modified.
 func (c *Client) GetInstanceByName(ctx context.Context, name string) (*TritonInstance, error) {
-	// Find instance by name and tags
-	listInput := &compute.ListInstancesInput{
-		Name: name,
-		Tags: map[string]interface{}{
-			"loadbalancer": "true",
-			"managed-by":   "triton-loadbalancer-controller",
-		},
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.GetInstanceByName(ctx, name)
+	}
+	if cached, ok := c.cache.get("instance", name); ok {
+		return cached.(*TritonInstance), nil
+	}
+	generation := c.cache.start()
+	instance, err := c.getInstanceByName(ctx, name)
+	if err != nil {
+		return nil, err
 	}
+	c.cache.put("instance", name, generation, instance)
+	return instance, nil
+}
 
-	instances, err := c.compute.Instances().List(ctx, listInput)
+// getInstanceByName is GetInstanceByName without the cache
+func (c *Client) getInstanceByName(ctx context.Context, name string) (*TritonInstance, error) {
+	// Find instance by name and tags
+	instances, err := c.listManagedInstances(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -550,11 +1693,21 @@ func (c *Client) GetInstanceByName(ctx context.Context, name string) (*TritonIns
 		ID: instances[0].ID,
 	}
 
-	instance, err := c.compute.Instances().Get(ctx, getInput)
+	var instance *compute.Instance
+	err = c.call(ctx, "GetMachine", func(ctx context.Context) error {
+		var err error
+		instance, err = c.instances.Get(ctx, getInput)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return newTritonInstance(instance), nil
+}
+
+// newTritonInstance converts a CloudAPI instance into a TritonInstance
+func newTritonInstance(instance *compute.Instance) *TritonInstance {
 	// Extract IP addresses from networks
 	var ips []string
 	for _, ip := range instance.IPs {
@@ -562,9 +1715,13 @@ func (c *Client) GetInstanceByName(ctx context.Context, name string) (*TritonIns
 	}
 
 	return &TritonInstance{
-		ID:   instance.ID,
-		Name: instance.Name,
-		IPs:  ips,
-		Tags: instance.Tags,
-	}, nil
+		ID:    instance.ID,
+		Name:  instance.Name,
+		State: instance.State,
+		IPs:   ips,
+		Tags:  instance.Tags,
+
+		Package:  instance.Package,
+		DNSNames: instance.DomainNames,
+	}
 }
@@ -0,0 +1,60 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// cnsServicesTag lists the Triton CNS services an instance is published
+// under, as <service>.svc.<account>.<datacenter>.<zone>
+const cnsServicesTag = "triton.cns.services"
+
+// SetCNSServices publishes every replica of the load balancer, found by its
+// instance ID or name, under the Triton CNS services, so each service name
+// resolves to the addresses of all replicas. No services unpublishes them.
+// Replicas already tagged as requested are left alone.
+func (c *Client) SetCNSServices(ctx context.Context, id, name string, services []string) error {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return err
+		}
+		return dc.SetCNSServices(ctx, id, name, services)
+	}
+
+	instances, err := c.findReplicas(ctx, id, name)
+	if err != nil {
+		return err
+	}
+
+	value := strings.Join(services, ",")
+	for _, instance := range instances {
+		tags := make(map[string]interface{}, len(instance.Tags)+1)
+		for k, v := range instance.Tags {
+			tags[k] = v
+		}
+		if value == "" {
+			delete(tags, cnsServicesTag)
+		} else {
+			tags[cnsServicesTag] = value
+		}
+		if tagsEqual(instance.Tags, tags) {
+			continue
+		}
+
+		replaceInput := &compute.ReplaceTagsInput{
+			ID:   instance.ID,
+			Tags: tags,
+		}
+		err := c.call(ctx, "ReplaceMachineTags", func(ctx context.Context) error {
+			return c.instances.ReplaceTags(ctx, replaceInput)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set CNS services on instance %s: %w", instance.ID, err)
+		}
+		instance.Tags = tags
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package triton
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+
+	triton "github.com/joyent/triton-go/v2"
+	"github.com/joyent/triton-go/v2/authentication"
+	"github.com/joyent/triton-go/v2/compute"
+	"github.com/joyent/triton-go/v2/network"
+	"golang.org/x/crypto/ssh"
+)
+
+// Credentials are the settings used to authenticate with CloudAPI
+type Credentials struct {
+	Account string
+	KeyID   string
+	// PrivateKey is the PEM or OpenSSH RSA private key matching KeyID
+	PrivateKey []byte
+	// Passphrase decrypts PrivateKey when it is encrypted
+	Passphrase string
+	// SSHAgent signs requests with the key KeyID held by the SSH agent at
+	// $SSH_AUTH_SOCK instead of PrivateKey
+	SSHAgent bool
+	URL      string
+}
+
+// Equal reports whether c and other authenticate the same way
+func (c Credentials) Equal(other Credentials) bool {
+	return c.Account == other.Account && c.KeyID == other.KeyID &&
+		c.URL == other.URL && string(c.PrivateKey) == string(other.PrivateKey) &&
+		c.Passphrase == other.Passphrase && c.SSHAgent == other.SSHAgent
+}
+
+// cloudAPIs are the CloudAPI clients created from one set of credentials
+type cloudAPIs struct {
+	instances  instancesAPI
+	network    networksAPI
+	networkErr error
+	catalog    catalogAPI
+	firewall   firewallAPI
+}
+
+// newCloudAPIs validates creds and creates the CloudAPI clients using them
+func newCloudAPIs(creds Credentials) (*cloudAPIs, error) {
+	if creds.Account == "" {
+		return nil, fmt.Errorf("Triton account name is required")
+	}
+	if creds.KeyID == "" {
+		return nil, fmt.Errorf("Triton key ID is required")
+	}
+	if len(creds.PrivateKey) == 0 && !creds.SSHAgent {
+		return nil, fmt.Errorf("Triton private key is required")
+	}
+	if creds.URL == "" {
+		return nil, fmt.Errorf("Triton API URL is required")
+	}
+
+	signer, err := newSigner(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &triton.ClientConfig{
+		TritonURL:   creds.URL,
+		AccountName: creds.Account,
+		Signers:     []authentication.Signer{signer},
+	}
+
+	computeClient, err := compute.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compute client: %v", err)
+	}
+
+	// The network client is optional; accounts with restricted network API
+	// permissions can still manage load balancers through the compute API
+	apis := &cloudAPIs{instances: computeClient.Instances(), catalog: computeCatalog{client: computeClient}}
+	if networkClient, err := network.NewClient(config); err != nil {
+		apis.networkErr = fmt.Errorf("failed to create network client: %v", err)
+	} else {
+		apis.network = networkClient
+		apis.firewall = networkClient.Firewall()
+	}
+	return apis, nil
+}
+
+// newSigner returns the signer authenticating requests made with creds
+func newSigner(creds Credentials) (authentication.Signer, error) {
+	if creds.SSHAgent {
+		signer, err := authentication.NewSSHAgentSigner(authentication.SSHAgentSignerInput{
+			KeyID:       creds.KeyID,
+			AccountName: creds.Account,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SSH agent signer: %v", err)
+		}
+		return signer, nil
+	}
+
+	// Parse the private key
+	keyMaterial, err := signerKeyMaterial(creds.PrivateKey, creds.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create signer input
+	input := authentication.PrivateKeySignerInput{
+		KeyID:              creds.KeyID,
+		PrivateKeyMaterial: keyMaterial,
+		AccountName:        creds.Account,
+	}
+
+	signer, err := authentication.NewPrivateKeySigner(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create private key signer: %v", err)
+	}
+	return signer, nil
+}
+
+// signerKeyMaterial returns key as the unencrypted PKCS #1 PEM block the
+// private key signer accepts, decrypting it with passphrase first if it is an
+// encrypted PEM or OpenSSH key
+func signerKeyMaterial(key []byte, passphrase string) ([]byte, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block containing private key, check if file is in valid PEM format")
+	}
+	if block.Type == "RSA PRIVATE KEY" && block.Headers["Proc-Type"] == "" {
+		return key, nil
+	}
+
+	raw, err := ssh.ParseRawPrivateKey(key)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("private key is encrypted but no passphrase was given")
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(key, []byte(passphrase))
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, fmt.Errorf("failed to decrypt private key: incorrect passphrase")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	rsaKey, ok := raw.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T, only RSA keys are supported", raw)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), nil
+}
+
+// verifyConnection makes a simple API call to check that CloudAPI at url
+// accepts the credentials of instances
+func (c *Client) verifyConnection(ctx context.Context, instances instancesAPI, url string) error {
+	err := c.call(ctx, "ListMachines", func(ctx context.Context) error {
+		_, err := instances.List(ctx, &compute.ListInstancesInput{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to Triton API at %s: %w", url, err)
+	}
+	return nil
+}
+
+// SetCredentials re-initializes the CloudAPI clients with creds, e.g. after
+// the key was rotated. The new credentials are verified first; if they are
+// rejected the client keeps using the old ones. Requests already in flight
+// complete with the old credentials. A client of NewMultiDatacenterClient
+// keeps the URL of each datacenter.
+func (c *Client) SetCredentials(ctx context.Context, creds Credentials) error {
+	if len(c.datacenters) > 0 {
+		return c.setDatacenterCredentials(ctx, creds)
+	}
+	return c.setCredentials(ctx, creds)
+}
+
+// setCredentials is SetCredentials of this client's own CloudAPI
+func (c *Client) setCredentials(ctx context.Context, creds Credentials) error {
+	rotating, ok := c.instances.(*rotatingInstances)
+	if !ok {
+		return fmt.Errorf("client does not support changing credentials")
+	}
+
+	apis, err := newCloudAPIs(creds)
+	if err != nil {
+		return err
+	}
+	if err := c.verifyConnection(ctx, apis.instances, creds.URL); err != nil {
+		return err
+	}
+
+	rotating.set(apis.instances)
+	c.mu.Lock()
+	c.network, c.networkErr = apis.network, apis.networkErr
+	c.catalog, c.firewall = apis.catalog, apis.firewall
+	c.mu.Unlock()
+
+	// The new credentials may belong to another account
+	c.cache.invalidate()
+	return nil
+}
+
+// rotatingInstances is an instancesAPI whose underlying client can be
+// replaced while requests are being made
+type rotatingInstances struct {
+	mu  sync.RWMutex
+	api instancesAPI
+}
+
+func (r *rotatingInstances) current() instancesAPI {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.api
+}
+
+func (r *rotatingInstances) set(api instancesAPI) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.api = api
+}
+
+func (r *rotatingInstances) List(ctx context.Context, input *compute.ListInstancesInput) ([]*compute.Instance, error) {
+	return r.current().List(ctx, input)
+}
+
+func (r *rotatingInstances) Get(ctx context.Context, input *compute.GetInstanceInput) (*compute.Instance, error) {
+	return r.current().Get(ctx, input)
+}
+
+func (r *rotatingInstances) Create(ctx context.Context, input *compute.CreateInstanceInput) (*compute.Instance, error) {
+	return r.current().Create(ctx, input)
+}
+
+func (r *rotatingInstances) Delete(ctx context.Context, input *compute.DeleteInstanceInput) error {
+	return r.current().Delete(ctx, input)
+}
+
+func (r *rotatingInstances) UpdateMetadata(ctx context.Context, input *compute.UpdateMetadataInput) (map[string]interface{}, error) {
+	return r.current().UpdateMetadata(ctx, input)
+}
+
+func (r *rotatingInstances) ReplaceTags(ctx context.Context, input *compute.ReplaceTagsInput) error {
+	return r.current().ReplaceTags(ctx, input)
+}
+
+func (r *rotatingInstances) Rename(ctx context.Context, input *compute.RenameInstanceInput) error {
+	return r.current().Rename(ctx, input)
+}
+
+func (r *rotatingInstances) DeleteMetadata(ctx context.Context, input *compute.DeleteMetadataInput) error {
+	return r.current().DeleteMetadata(ctx, input)
+}
+
+func (r *rotatingInstances) AddNIC(ctx context.Context, input *compute.AddNICInput) (*compute.NIC, error) {
+	return r.current().AddNIC(ctx, input)
+}
+
+func (r *rotatingInstances) RemoveNIC(ctx context.Context, input *compute.RemoveNICInput) error {
+	return r.current().RemoveNIC(ctx, input)
+}
+
+func (r *rotatingInstances) Reboot(ctx context.Context, input *compute.RebootInstanceInput) error {
+	return r.current().Reboot(ctx, input)
+}
+
+func (r *rotatingInstances) Resize(ctx context.Context, input *compute.ResizeInstanceInput) error {
+	return r.current().Resize(ctx, input)
+}
+
+func (r *rotatingInstances) EnableFirewall(ctx context.Context, input *compute.EnableFirewallInput) error {
+	return r.current().EnableFirewall(ctx, input)
+}
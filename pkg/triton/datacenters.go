@@ -0,0 +1,129 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Datacenter is a Triton datacenter and the CloudAPI endpoint serving it
+type Datacenter struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// datacenterKey is the context key of WithDatacenter
+type datacenterKey struct{}
+
+// WithDatacenter returns a copy of ctx whose requests through a Client go to
+// the CloudAPI of the named datacenter; an empty name selects the default
+// datacenter
+func WithDatacenter(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, datacenterKey{}, name)
+}
+
+// DatacenterFromContext returns the datacenter selected by WithDatacenter,
+// or "" for the default one
+func DatacenterFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(datacenterKey{}).(string)
+	return name
+}
+
+// NewMultiDatacenterClient creates a Client that sends each request to the
+// CloudAPI of the datacenter selected by WithDatacenter. The first of
+// datacenters is the default. Every datacenter is reached with creds, apart
+// from the URL, and has its own rate limit and lookup cache configured by
+// opts. Without datacenters it is NewClientWithCredentials.
+func NewMultiDatacenterClient(creds Credentials, datacenters []Datacenter, opts ...ClientOption) (*Client, error) {
+	if len(datacenters) == 0 {
+		return NewClientWithCredentials(creds, opts...)
+	}
+
+	clients := make(map[string]*Client, len(datacenters))
+	for _, dc := range datacenters {
+		if dc.Name == "" || dc.URL == "" {
+			return nil, fmt.Errorf("datacenter %q needs a name and a URL", dc.Name)
+		}
+		if _, ok := clients[dc.Name]; ok {
+			return nil, fmt.Errorf("datacenter %s is configured twice", dc.Name)
+		}
+		dcCreds := creds
+		dcCreds.URL = dc.URL
+		client, err := NewClientWithCredentials(dcCreds, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("datacenter %s: %w", dc.Name, err)
+		}
+		client.datacenter = dc
+		clients[dc.Name] = client
+	}
+
+	primary := clients[datacenters[0].Name]
+	primary.datacenters = make([]*Client, 0, len(datacenters))
+	for _, dc := range datacenters {
+		primary.datacenters = append(primary.datacenters, clients[dc.Name])
+	}
+	return primary, nil
+}
+
+// Datacenters returns the name of every datacenter the client reaches, the
+// default first, or nil if it only reaches the CloudAPI it was created with
+func (c *Client) Datacenters() []string {
+	var names []string
+	for _, dc := range c.datacenters {
+		names = append(names, dc.datacenter.Name)
+	}
+	return names
+}
+
+// forDatacenter returns the client of the datacenter ctx selects. A client
+// reaching a single datacenter only accepts its own name.
+func (c *Client) forDatacenter(ctx context.Context) (*Client, error) {
+	name := DatacenterFromContext(ctx)
+	if name == "" || name == c.datacenter.Name {
+		return c, nil
+	}
+	for _, dc := range c.datacenters {
+		if dc.datacenter.Name == name {
+			return dc, nil
+		}
+	}
+	return nil, newError(ErrNotFound, "unknown Triton datacenter %q", name)
+}
+
+// spannedDatacenters returns the client of every datacenter when a listing
+// made with ctx covers all of them, because ctx selects none, and nil
+// otherwise
+func (c *Client) spannedDatacenters(ctx context.Context) []*Client {
+	if DatacenterFromContext(ctx) != "" {
+		return nil
+	}
+	return c.datacenters
+}
+
+// listDatacenters joins the results of list in every one of clients
+func listDatacenters[T any](ctx context.Context, clients []*Client, list func(*Client, context.Context) ([]T, error)) ([]T, error) {
+	var result []T
+	for _, dc := range clients {
+		items, err := list(dc, WithDatacenter(ctx, dc.datacenter.Name))
+		result = append(result, items...)
+		if err != nil {
+			return result, fmt.Errorf("datacenter %s: %w", dc.datacenter.Name, err)
+		}
+	}
+	return result, nil
+}
+
+// setDatacenterCredentials re-initializes every datacenter with creds and
+// its own URL. Datacenters updated before one rejects the credentials keep
+// the new ones.
+func (c *Client) setDatacenterCredentials(ctx context.Context, creds Credentials) error {
+	var errs []error
+	for _, dc := range c.datacenters {
+		dcCreds := creds
+		dcCreds.URL = dc.datacenter.URL
+		if err := dc.setCredentials(ctx, dcCreds); err != nil {
+			errs = append(errs, fmt.Errorf("datacenter %s: %w", dc.datacenter.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,91 @@
+package triton
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Defaults are the settings used for load balancers that don't override
+// them. They can be changed while the client is in use with SetDefaults.
+type Defaults struct {
+	// Package and Image are the package name and image ID of instances
+	// without a Package or Image override; empty means the
+	// TRITON_LB_PACKAGE and TRITON_LB_IMAGE environment variables, or
+	// DefaultPackage and DefaultImage
+	Package string
+	Image   string
+
+	// ProvisionTimeout and DeleteTimeout bound how long instances are
+	// waited for to run or to be deleted; zero means the
+	// TRITON_PROVISION_TIMEOUT and TRITON_DELETE_TIMEOUT environment
+	// variables in seconds, or 5 minutes
+	ProvisionTimeout time.Duration
+	DeleteTimeout    time.Duration
+}
+
+// defaultWaitTimeout bounds waiting for instances when no timeout is set
+const defaultWaitTimeout = 5 * time.Minute
+
+// WithDefaults sets the initial defaults of the client
+func WithDefaults(d Defaults) ClientOption {
+	return func(c *Client) {
+		c.setDefaults(d)
+	}
+}
+
+// SetDefaults replaces the defaults of the client, and of every datacenter
+// of a client created by NewMultiDatacenterClient. Operations already in
+// progress may finish with the previous defaults.
+func (c *Client) SetDefaults(d Defaults) {
+	c.setDefaults(d)
+	for _, dc := range c.datacenters {
+		if dc != c {
+			dc.setDefaults(d)
+		}
+	}
+}
+
+// setDefaults replaces the defaults of this client alone
+func (c *Client) setDefaults(d Defaults) {
+	c.defaults.Store(&d)
+}
+
+// currentDefaults returns the defaults of the client with the environment
+// and built-in fallbacks filled in
+func (c *Client) currentDefaults() Defaults {
+	var d Defaults
+	if stored := c.defaults.Load(); stored != nil {
+		d = *stored
+	}
+	if d.Package == "" {
+		d.Package = envOr("TRITON_LB_PACKAGE", DefaultPackage)
+	}
+	if d.Image == "" {
+		d.Image = envOr("TRITON_LB_IMAGE", DefaultImage)
+	}
+	if d.ProvisionTimeout <= 0 {
+		d.ProvisionTimeout = envSeconds("TRITON_PROVISION_TIMEOUT", defaultWaitTimeout)
+	}
+	if d.DeleteTimeout <= 0 {
+		d.DeleteTimeout = envSeconds("TRITON_DELETE_TIMEOUT", defaultWaitTimeout)
+	}
+	return d
+}
+
+// envOr returns the environment variable key, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envSeconds returns the positive number of seconds in the environment
+// variable key, or fallback when it is unset or invalid
+func envSeconds(key string, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv(key)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return fallback
+}
@@ -0,0 +1,122 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// drainingMetadataKey records when an instance started draining, in RFC 3339
+// format. The load balancer image stops accepting new connections while it
+// is set and lets the established ones finish.
+const drainingMetadataKey = "cloud.tritoncompute:draining"
+
+// DrainLoadBalancer starts draining every replica of the load balancer,
+// found by its instance ID or name, and returns when the earliest of them
+// started draining. Replicas that are already draining keep their start
+// time, so calling it again doesn't extend the drain. A load balancer that
+// doesn't exist returns the zero time.
+func (c *Client) DrainLoadBalancer(ctx context.Context, id, name string) (time.Time, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return time.Time{}, err
+		}
+		return dc.DrainLoadBalancer(ctx, id, name)
+	}
+
+	instances, err := c.findReplicas(ctx, id, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var started time.Time
+	for _, instance := range instances {
+		since, err := c.drainInstance(ctx, instance)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if started.IsZero() || since.Before(started) {
+			started = since
+		}
+	}
+	return started, nil
+}
+
+// drainInstance marks instance as draining, unless it already is, and
+// returns when it started draining
+func (c *Client) drainInstance(ctx context.Context, instance *compute.Instance) (time.Time, error) {
+	if since, ok := drainingSince(instance); ok {
+		return since, nil
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	updateInput := &compute.UpdateMetadataInput{
+		ID:       instance.ID,
+		Metadata: map[string]interface{}{drainingMetadataKey: now.Format(time.RFC3339)},
+	}
+	err := c.call(ctx, "UpdateMachineMetadata", func(ctx context.Context) error {
+		_, err := c.instances.UpdateMetadata(ctx, updateInput)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to drain instance %s: %w", instance.ID, err)
+	}
+	fmt.Printf("Draining load balancer instance %s (%s)\n", instance.Name, instance.ID)
+
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]interface{}{}
+	}
+	instance.Metadata[drainingMetadataKey] = now.Format(time.RFC3339)
+	return now, nil
+}
+
+// drained reports whether instance has drained for at least timeout,
+// starting to drain it first if needed. A zero timeout needs no draining.
+func (c *Client) drained(ctx context.Context, instance *compute.Instance, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		return true, nil
+	}
+	since, err := c.drainInstance(ctx, instance)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(since) >= timeout, nil
+}
+
+// cancelDrain puts a draining instance back into service, e.g. when the
+// replica it was drained for scaling down is wanted again
+func (c *Client) cancelDrain(ctx context.Context, instance *compute.Instance) error {
+	if _, ok := drainingSince(instance); !ok {
+		return nil
+	}
+
+	deleteInput := &compute.DeleteMetadataInput{
+		ID:  instance.ID,
+		Key: drainingMetadataKey,
+	}
+	err := c.call(ctx, "DeleteMachineMetadata", func(ctx context.Context) error {
+		return c.instances.DeleteMetadata(ctx, deleteInput)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stop draining instance %s: %w", instance.ID, err)
+	}
+	delete(instance.Metadata, drainingMetadataKey)
+	return nil
+}
+
+// drainingSince returns when instance started draining, if it is. An
+// unreadable start time counts as draining since the beginning of time, so
+// the drain doesn't start over.
+func drainingSince(instance *compute.Instance) (time.Time, bool) {
+	value, ok := instance.Metadata[drainingMetadataKey].(string)
+	if !ok || value == "" {
+		return time.Time{}, false
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Unix(0, 0).UTC(), true
+	}
+	return since, true
+}
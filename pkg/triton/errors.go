@@ -0,0 +1,149 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+
+	tritonerrors "github.com/joyent/triton-go/v2/errors"
+)
+
+// Errors returned by Client wrap one of these classes so callers can decide
+// how to react with errors.Is instead of matching on messages
+var (
+	// ErrTransient marks failures worth retrying, such as timeouts, refused
+	// connections and CloudAPI server errors
+	ErrTransient = errors.New("transient CloudAPI error")
+	// ErrThrottled marks requests refused by CloudAPI or by the client's own
+	// rate limit; it is also transient
+	ErrThrottled = fmt.Errorf("CloudAPI rate limit exceeded: %w", ErrTransient)
+	// ErrRateLimited is the former name of ErrThrottled
+	ErrRateLimited = ErrThrottled
+	// ErrNotFound marks requests for instances or other resources that don't exist
+	ErrNotFound = errors.New("CloudAPI resource not found")
+	// ErrAuth marks requests rejected because of invalid credentials or
+	// missing permissions
+	ErrAuth = errors.New("CloudAPI authentication failed")
+	// ErrQuotaExceeded marks provisioning refused because the account is out
+	// of its instance, memory or disk quota
+	ErrQuotaExceeded = errors.New("CloudAPI quota exceeded")
+	// ErrConflict marks requests that conflict with the current state of a
+	// resource, such as an instance owned by someone else
+	ErrConflict = errors.New("CloudAPI request conflicts with the resource state")
+)
+
+// errorClasses are the classes an Error can have, apart from ErrThrottled,
+// which is an ErrTransient
+var errorClasses = []error{ErrTransient, ErrNotFound, ErrAuth, ErrQuotaExceeded, ErrConflict}
+
+// Error is an error of the Client with its class, one of the Err values
+// above, so that errors.Is matches it against the class while the message
+// stays that of the underlying error. Use errors.As to read the class.
+type Error struct {
+	Class error
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Class, e.Err}
+}
+
+// newError returns an Error of class with a formatted message
+func newError(class error, format string, args ...interface{}) error {
+	return &Error{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// authErrorCodes are CloudAPI error codes caused by bad credentials or permissions
+var authErrorCodes = map[string]bool{
+	"AuthScheme":         true,
+	"Authorization":      true,
+	"InvalidCredentials": true,
+	"InvalidKeyId":       true,
+	"InvalidSignature":   true,
+	"NotAuthorized":      true,
+}
+
+// conflictErrorCodes are CloudAPI error codes for requests that conflict
+// with the state of a resource
+var conflictErrorCodes = map[string]bool{
+	"InUseError":   true,
+	"InvalidState": true,
+}
+
+// classify wraps a CloudAPI error with its error class, leaving errors it
+// can't classify unchanged
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class) {
+			return err
+		}
+	}
+
+	if class := errorClass(err); class != nil {
+		return &Error{Class: class, Err: err}
+	}
+	return err
+}
+
+// errorClass returns the class of err, or nil if it is unknown
+func errorClass(err error) error {
+	var apiErr *tritonerrors.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests || apiErr.Code == "RequestThrottled":
+			return ErrThrottled
+		case apiErr.Code == "QuotaExceeded":
+			// Sent with 403, so before the auth errors
+			return ErrQuotaExceeded
+		case apiErr.StatusCode == http.StatusConflict || conflictErrorCodes[apiErr.Code]:
+			return ErrConflict
+		case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden || authErrorCodes[apiErr.Code]:
+			return ErrAuth
+		case apiErr.StatusCode == http.StatusNotFound || apiErr.Code == "ResourceNotFound":
+			return ErrNotFound
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return ErrTransient
+		}
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return ErrTransient
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTransient
+	}
+	return nil
+}
+
+// IsTransientError reports whether err is a temporary CloudAPI or network
+// failure that is worth retrying. It goes by the class of err alone: errors
+// the Client returns are classified, and errors it can't classify, such as a
+// bad request, are not transient whatever their message says.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class) {
+			return false
+		}
+	}
+	return errors.Is(errorClass(err), ErrTransient)
+}
@@ -0,0 +1,232 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/joyent/triton-go/v2/compute"
+	"github.com/joyent/triton-go/v2/network"
+)
+
+// firewallAPI is the subset of the CloudAPI firewall client used to manage
+// the rules opening the listen ports of load balancers
+type firewallAPI interface {
+	ListRules(ctx context.Context, input *network.ListRulesInput) ([]*network.FirewallRule, error)
+	CreateRule(ctx context.Context, input *network.CreateRuleInput) (*network.FirewallRule, error)
+	UpdateRule(ctx context.Context, input *network.UpdateRuleInput) (*network.FirewallRule, error)
+	DeleteRule(ctx context.Context, input *network.DeleteRuleInput) error
+}
+
+// WithFirewallManagement enables Cloud Firewall on load balancer instances
+// and keeps a rule allowing inbound traffic to each listen port, restricted
+// to FirewallSourceRanges when they are set. The rules are removed with the
+// load balancer.
+func WithFirewallManagement(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.manageFirewall = enabled
+	}
+}
+
+// firewallClient returns the firewall API client, or ErrNetworkUnavailable if
+// the network API could not be initialized
+func (c *Client) firewallClient() (firewallAPI, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.firewall == nil {
+		if c.networkErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNetworkUnavailable, c.networkErr)
+		}
+		return nil, ErrNetworkUnavailable
+	}
+	return c.firewall, nil
+}
+
+// firewallOwner prefixes the description of every rule managed for the load
+// balancer name, so its rules are found again without tracking their IDs
+func (c *Client) firewallOwner(name string) string {
+	owner := c.managedBy()
+	if c.clusterName != "" {
+		owner += "/" + c.clusterName
+	}
+	return owner + "/" + name
+}
+
+// firewallRules returns the rules, keyed by description, allowing traffic to
+// the listen ports of params on instances
+func (c *Client) firewallRules(params LoadBalancerParams, instances []*compute.Instance) map[string]string {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	var targets []string
+	for _, instance := range instances {
+		targets = append(targets, "vm "+instance.ID)
+	}
+	target := strings.Join(targets, " OR ")
+	if len(targets) > 1 {
+		target = "(" + target + ")"
+	}
+
+	from := "any"
+	if len(params.FirewallSourceRanges) > 0 {
+		var sources []string
+		for _, source := range params.FirewallSourceRanges {
+			sources = append(sources, firewallSource(source))
+		}
+		from = strings.Join(sources, " OR ")
+		if len(sources) > 1 {
+			from = "(" + from + ")"
+		}
+	}
+
+	rules := map[string]string{}
+	for _, mapping := range params.PortMappings {
+		protocol := "tcp"
+		if mapping.Type == "udp" {
+			protocol = "udp"
+		}
+		description := fmt.Sprintf("%s %s/%d", c.firewallOwner(params.Name), protocol, mapping.ListenPort)
+		rules[description] = fmt.Sprintf("FROM %s TO %s ALLOW %s PORT %d", from, target, protocol, mapping.ListenPort)
+	}
+	return rules
+}
+
+// firewallSource returns the rule target of a source range, an address or a
+// CIDR; single addresses are matched with ip, other ranges with subnet
+func firewallSource(source string) string {
+	if ip := net.ParseIP(source); ip != nil {
+		return "ip " + ip.String()
+	}
+	ip, subnet, err := net.ParseCIDR(source)
+	if err != nil {
+		// Validated by ValidateSourceRange; let CloudAPI report it
+		return "subnet " + source
+	}
+	if ones, bits := subnet.Mask.Size(); ones == bits {
+		return "ip " + ip.String()
+	}
+	return "subnet " + subnet.String()
+}
+
+// ValidateSourceRange returns an error unless source is an IP address or a
+// CIDR such as 10.0.0.0/8
+func ValidateSourceRange(source string) error {
+	if net.ParseIP(source) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(source); err != nil {
+		return fmt.Errorf("invalid source range %q: expected an IP address or CIDR", source)
+	}
+	return nil
+}
+
+// syncFirewall makes the managed firewall rules of the load balancer
+// described by params match its listen ports and replicas, then enables the
+// firewall of replicas that don't have it yet. Rules exist before a firewall
+// is enabled, so open ports keep accepting traffic throughout.
+func (c *Client) syncFirewall(ctx context.Context, params LoadBalancerParams, instances []*compute.Instance) error {
+	if !c.manageFirewall {
+		return nil
+	}
+
+	desired := c.firewallRules(params, instances)
+	if err := c.applyFirewallRules(ctx, params.Name, desired); err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		if instance.FirewallEnabled || IsFailedState(instance.State) {
+			continue
+		}
+		input := &compute.EnableFirewallInput{ID: instance.ID}
+		err := c.call(ctx, "EnableMachineFirewall", func(ctx context.Context) error {
+			return c.instances.EnableFirewall(ctx, input)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enable the firewall of instance %s: %w", instance.ID, err)
+		}
+		instance.FirewallEnabled = true
+	}
+	return nil
+}
+
+// removeFirewall deletes the managed firewall rules of the load balancer name
+func (c *Client) removeFirewall(ctx context.Context, name string) error {
+	if !c.manageFirewall {
+		return nil
+	}
+	return c.applyFirewallRules(ctx, name, nil)
+}
+
+// applyFirewallRules creates, updates and deletes the managed rules of the
+// load balancer name so they match desired, keyed by description
+func (c *Client) applyFirewallRules(ctx context.Context, name string, desired map[string]string) error {
+	firewall, err := c.firewallClient()
+	if err != nil {
+		return err
+	}
+
+	var rules []*network.FirewallRule
+	err = c.call(ctx, "ListFirewallRules", func(ctx context.Context) error {
+		var err error
+		rules, err = firewall.ListRules(ctx, &network.ListRulesInput{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list firewall rules: %w", err)
+	}
+
+	prefix := c.firewallOwner(name) + " "
+	found := map[string]bool{}
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule.Description, prefix) {
+			continue
+		}
+		text, ok := desired[rule.Description]
+		if !ok || found[rule.Description] {
+			input := &network.DeleteRuleInput{ID: rule.ID}
+			err := c.call(ctx, "DeleteFirewallRule", func(ctx context.Context) error {
+				return firewall.DeleteRule(ctx, input)
+			})
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("failed to delete firewall rule %s: %w", rule.ID, err)
+			}
+			continue
+		}
+		found[rule.Description] = true
+		if rule.Rule == text && rule.Enabled {
+			continue
+		}
+		input := &network.UpdateRuleInput{ID: rule.ID, Enabled: true, Rule: text, Description: rule.Description}
+		err := c.call(ctx, "UpdateFirewallRule", func(ctx context.Context) error {
+			_, err := firewall.UpdateRule(ctx, input)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update firewall rule %s: %w", rule.ID, err)
+		}
+	}
+
+	descriptions := make([]string, 0, len(desired))
+	for description := range desired {
+		if !found[description] {
+			descriptions = append(descriptions, description)
+		}
+	}
+	sort.Strings(descriptions)
+	for _, description := range descriptions {
+		input := &network.CreateRuleInput{Enabled: true, Rule: desired[description], Description: description}
+		err := c.call(ctx, "CreateFirewallRule", func(ctx context.Context) error {
+			_, err := firewall.CreateRule(ctx, input)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create firewall rule %q: %w", description, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// MigrateTags adds the cluster tag to load balancers created with this
+// client's manager ID before a cluster name was configured, which the
+// cluster-scoped listings would otherwise never match again. Instances
+// already tagged with any cluster are left alone, so it is safe to run on
+// every start. Every untagged instance with the manager ID is claimed, so
+// only run it while no other cluster shares the manager ID. It returns the
+// migrated instances.
+func (c *Client) MigrateTags(ctx context.Context) ([]*TritonInstance, error) {
+	if clients := c.spannedDatacenters(ctx); clients != nil {
+		return listDatacenters(ctx, clients, (*Client).MigrateTags)
+	}
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return nil, err
+		}
+		return dc.MigrateTags(ctx)
+	}
+	if c.clusterName == "" {
+		return nil, nil
+	}
+
+	instances, err := c.listInstancesInCluster(ctx, "", nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances for tag migration: %w", err)
+	}
+
+	var migrated []*TritonInstance
+	for _, instance := range instances {
+		if _, ok := instance.Tags[clusterTag]; ok {
+			continue
+		}
+
+		tags := make(map[string]interface{}, len(instance.Tags)+1)
+		for k, v := range instance.Tags {
+			tags[k] = v
+		}
+		tags[clusterTag] = c.clusterName
+
+		replaceInput := &compute.ReplaceTagsInput{
+			ID:   instance.ID,
+			Tags: tags,
+		}
+		err := c.call(ctx, "ReplaceMachineTags", func(ctx context.Context) error {
+			return c.instances.ReplaceTags(ctx, replaceInput)
+		})
+		if err != nil {
+			return migrated, fmt.Errorf("failed to migrate tags of instance %s: %w", instance.ID, err)
+		}
+		instance.Tags = tags
+		migrated = append(migrated, newTritonInstance(instance))
+	}
+	return migrated, nil
+}
@@ -0,0 +1,51 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joyent/triton-go/v2/network"
+)
+
+// resolveNetworks returns the IDs of the networks, given by name or ID in
+// params, that load balancer instances are attached to, in the order given.
+// No networks leaves the choice to CloudAPI, which uses the account defaults.
+func (c *Client) resolveNetworks(ctx context.Context, params LoadBalancerParams) ([]string, error) {
+	if len(params.Networks) == 0 {
+		return nil, nil
+	}
+
+	networkClient, err := c.networkClient()
+	if err != nil {
+		return nil, err
+	}
+	var networks []*network.Network
+	err = c.call(ctx, "ListNetworks", func(ctx context.Context) error {
+		var err error
+		networks, err = networkClient.List(ctx, &network.ListInput{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, ref := range params.Networks {
+		id := ""
+		for _, n := range networks {
+			if n.Id == ref || n.Name == ref {
+				id = n.Id
+				break
+			}
+		}
+		if id == "" {
+			return nil, fmt.Errorf("invalid network %q: no network with that name or ID: %w", ref, ErrNotFound)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
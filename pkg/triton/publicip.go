@@ -0,0 +1,129 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// publicNICMetadataKey records the MAC address of the public NIC the
+// controller attached, so only that NIC is removed again
+const publicNICMetadataKey = "cloud.tritoncompute:public_nic"
+
+// PublicIPError is returned when a public IP could not be attached to or
+// released from a load balancer instance. The instance itself is fine, so
+// the operation can simply be retried.
+type PublicIPError struct {
+	InstanceID string
+	Err        error
+}
+
+func (e *PublicIPError) Error() string {
+	return fmt.Sprintf("failed to manage public IP of load balancer instance %s: %v", e.InstanceID, e.Err)
+}
+
+func (e *PublicIPError) Unwrap() error {
+	return e.Err
+}
+
+// ensurePublicIP attaches a NIC on a public network to instance when
+// params asks for a public IP and the instance has none, and removes the NIC
+// it attached earlier once the public IP is no longer wanted. NICs go away
+// with the instance, so deleting the load balancer releases the address.
+func (c *Client) ensurePublicIP(ctx context.Context, instance *compute.Instance, params LoadBalancerParams) error {
+	mac, _ := instance.Metadata[publicNICMetadataKey].(string)
+
+	if !params.AllocatePublicIP {
+		if mac == "" {
+			return nil
+		}
+		return c.releasePublicIP(ctx, instance, mac)
+	}
+	if mac != "" {
+		return nil
+	}
+
+	publicNetworks, err := c.ListPublicNetworks(ctx)
+	if err != nil {
+		return &PublicIPError{InstanceID: instance.ID, Err: err}
+	}
+	if len(publicNetworks) == 0 {
+		return &PublicIPError{InstanceID: instance.ID, Err: fmt.Errorf("no public network available to the account")}
+	}
+	for _, attached := range instance.Networks {
+		for _, public := range publicNetworks {
+			if attached == public {
+				// Already reachable on a public network
+				return nil
+			}
+		}
+	}
+
+	addInput := &compute.AddNICInput{
+		InstanceID: instance.ID,
+		Network:    publicNetworks[0],
+	}
+	var nic *compute.NIC
+	err = c.call(ctx, "AddNic", func(ctx context.Context) error {
+		var err error
+		nic, err = c.instances.AddNIC(ctx, addInput)
+		return err
+	})
+	if err != nil {
+		return &PublicIPError{InstanceID: instance.ID, Err: fmt.Errorf("failed to attach public network %s: %w", addInput.Network, err)}
+	}
+	fmt.Printf("Attached public IP %s to load balancer instance %s\n", nic.IP, instance.ID)
+
+	updateInput := &compute.UpdateMetadataInput{
+		ID:       instance.ID,
+		Metadata: map[string]interface{}{publicNICMetadataKey: nic.MAC},
+	}
+	err = c.call(ctx, "UpdateMachineMetadata", func(ctx context.Context) error {
+		_, err := c.instances.UpdateMetadata(ctx, updateInput)
+		return err
+	})
+	if err != nil {
+		return &PublicIPError{InstanceID: instance.ID, Err: fmt.Errorf("failed to record public NIC %s: %w", nic.MAC, err)}
+	}
+
+	if instance.Metadata == nil {
+		instance.Metadata = map[string]interface{}{}
+	}
+	instance.Metadata[publicNICMetadataKey] = nic.MAC
+	instance.Networks = append(instance.Networks, nic.Network)
+	if nic.IP != "" {
+		instance.IPs = append(instance.IPs, nic.IP)
+	}
+	return nil
+}
+
+// releasePublicIP removes the public NIC with the given MAC address that
+// ensurePublicIP attached to instance
+func (c *Client) releasePublicIP(ctx context.Context, instance *compute.Instance, mac string) error {
+	removeInput := &compute.RemoveNICInput{
+		InstanceID: instance.ID,
+		MAC:        mac,
+	}
+	err := c.call(ctx, "RemoveNic", func(ctx context.Context) error {
+		return c.instances.RemoveNIC(ctx, removeInput)
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return &PublicIPError{InstanceID: instance.ID, Err: fmt.Errorf("failed to remove public NIC %s: %w", mac, err)}
+	}
+	fmt.Printf("Released public NIC %s of load balancer instance %s\n", mac, instance.ID)
+
+	deleteInput := &compute.DeleteMetadataInput{
+		ID:  instance.ID,
+		Key: publicNICMetadataKey,
+	}
+	err = c.call(ctx, "DeleteMachineMetadata", func(ctx context.Context) error {
+		return c.instances.DeleteMetadata(ctx, deleteInput)
+	})
+	if err != nil {
+		return &PublicIPError{InstanceID: instance.ID, Err: fmt.Errorf("failed to clear public NIC record: %w", err)}
+	}
+	delete(instance.Metadata, publicNICMetadataKey)
+	return nil
+}
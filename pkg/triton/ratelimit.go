@@ -0,0 +1,108 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	tritonerrors "github.com/joyent/triton-go/v2/errors"
+	"golang.org/x/time/rate"
+)
+
+// retryBackoff is the wait before the first retry of a throttled or failed
+// CloudAPI request; it doubles after each attempt up to retryMaxBackoff
+var retryBackoff = 500 * time.Millisecond
+
+// retryMaxBackoff caps the wait between retries of a CloudAPI request
+const retryMaxBackoff = 30 * time.Second
+
+// unsafeRetryOps are the CloudAPI requests that may have taken effect when
+// they fail with a server error, so retrying them could create a second
+// instance, NIC or rule. They are only retried when throttled.
+var unsafeRetryOps = map[string]bool{
+	"CreateMachine":      true,
+	"AddNic":             true,
+	"CreateFirewallRule": true,
+}
+
+// WithRateLimit limits CloudAPI requests to rps per second, with bursts of up
+// to burst requests, so a burst of Service changes queues in the controller
+// instead of being throttled by CloudAPI. A non-positive rps disables the
+// limit.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *Client) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+}
+
+// WithRetries makes every CloudAPI request up to attempts times while it is
+// throttled (429) or fails with a server error (5xx), waiting with
+// exponential backoff and jitter in between. Requests that are not safe to
+// repeat are only retried when throttled. One attempt disables retries.
+func WithRetries(attempts int) ClientOption {
+	return func(c *Client) {
+		c.retryAttempts = attempts
+	}
+}
+
+// callWithRetries runs fn through callOnce, retrying it as configured by
+// WithRetries
+func (c *Client) callWithRetries(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.callOnce(ctx, op, fn)
+		if err == nil || attempt >= c.retryAttempts || !retryable(op, err) {
+			return err
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		fmt.Printf("CloudAPI %s request failed (attempt %d of %d), retrying in %s: %v\n", op, attempt, c.retryAttempts, wait, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff = min(2*backoff, retryMaxBackoff)
+	}
+}
+
+// retryable reports whether a failed op is worth sending again: throttled
+// requests always are, server errors only when op is safe to repeat
+func retryable(op string, err error) bool {
+	if errors.Is(err, ErrThrottled) {
+		return true
+	}
+	var apiErr *tritonerrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+	return !unsafeRetryOps[op]
+}
+
+// waitRateLimit blocks until the rate limit allows another request. A request that
+// can't be sent before the deadline of ctx fails as rate limited.
+func (c *Client) waitRateLimit(ctx context.Context, op string) error {
+	if c.limiter == nil {
+		return nil
+	}
+	if err := c.limiter.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("CloudAPI %s request not sent: %w", op, ctx.Err())
+		}
+		return &Error{
+			Class: ErrThrottled,
+			Err:   fmt.Errorf("CloudAPI %s request not sent within the client rate limit: %w", op, err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// DefaultReloadKeys are the metadata keys the load balancer image only reads
+// when it starts, so changing them on a running instance has no effect until
+// it is rebooted
+var DefaultReloadKeys = []string{
+	"cloud.tritoncompute:certificate_name",
+	"cloud.tritoncompute:certificate",
+	"cloud.tritoncompute:certificate_key",
+	"cloud.tritoncompute:timeout_connect",
+	"cloud.tritoncompute:timeout_client",
+	"cloud.tritoncompute:timeout_server",
+}
+
+// WithReloadKeys overrides DefaultReloadKeys as the metadata keys whose
+// change reboots load balancers that set ReloadOnChange
+func WithReloadKeys(keys []string) ClientOption {
+	return func(c *Client) {
+		if len(keys) > 0 {
+			c.reloadKeys = keys
+		}
+	}
+}
+
+// reloadKeyList returns the configured reload keys or the defaults
+func (c *Client) reloadKeyList() []string {
+	if c.reloadKeys == nil {
+		return DefaultReloadKeys
+	}
+	return c.reloadKeys
+}
+
+// changedReloadKeys returns the reload keys whose value on instance differs
+// from metadata, which is about to be written to it
+func (c *Client) changedReloadKeys(instance *compute.Instance, metadata map[string]interface{}) []string {
+	var changed []string
+	for _, key := range c.reloadKeyList() {
+		current, ok := instance.Metadata[key]
+		desired, want := metadata[key]
+		if ok != want || (ok && fmt.Sprint(current) != fmt.Sprint(desired)) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// rebootInstance reboots a load balancer instance so its image picks up new
+// configuration. CloudAPI reboots asynchronously, so the instance is marked
+// as stopping for callers to wait until it is running again.
+func (c *Client) rebootInstance(ctx context.Context, instance *compute.Instance, changed []string) error {
+	fmt.Printf("Rebooting load balancer instance %s (%s) to apply changed metadata %v\n",
+		instance.Name, instance.ID, changed)
+
+	input := &compute.RebootInstanceInput{InstanceID: instance.ID}
+	err := c.call(ctx, "RebootMachine", func(ctx context.Context) error {
+		return c.instances.Reboot(ctx, input)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reboot instance %s: %w", instance.ID, err)
+	}
+	instance.State = "stopping"
+	return nil
+}
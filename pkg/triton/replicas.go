@@ -0,0 +1,174 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// replicaOfTag marks additional replicas with the name of the load balancer
+// they belong to
+const replicaOfTag = "replica-of"
+
+// ReplicaCount returns the number of load balancer instances requested
+func (p LoadBalancerParams) ReplicaCount() int {
+	if p.Replicas < 1 {
+		return 1
+	}
+	return p.Replicas
+}
+
+// replicaName returns the instance name of the given replica. The first
+// replica keeps the load balancer name so existing single-instance load
+// balancers are adopted as replica 0.
+func replicaName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, index)
+}
+
+// replicaIndex returns the replica index encoded in an instance name, or -1
+// if the name does not belong to the load balancer
+func replicaIndex(instanceName, name string) int {
+	if instanceName == name {
+		return 0
+	}
+	suffix, ok := strings.CutPrefix(instanceName, name+"-")
+	if !ok {
+		return -1
+	}
+	index, err := strconv.Atoi(suffix)
+	if err != nil || index < 1 {
+		return -1
+	}
+	return index
+}
+
+// listReplicas returns every managed instance belonging to the named load
+// balancer, ordered by replica index. An instance with the name is only its
+// first replica if it isn't another load balancer's replica: with replicas,
+// Service web has an instance web-1, which is not Service web-1's.
+func (c *Client) listReplicas(ctx context.Context, name string) ([]*compute.Instance, error) {
+	named, err := c.listManagedInstances(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var instances []*compute.Instance
+	for _, instance := range named {
+		if _, replica := instance.Tags[replicaOfTag]; !replica {
+			instances = append(instances, instance)
+		}
+	}
+
+	others, err := c.listManagedInstancesTagged(ctx, "", map[string]interface{}{replicaOfTag: name})
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range others {
+		if replicaIndex(instance.Name, name) > 0 {
+			instances = append(instances, instance)
+		}
+	}
+
+	sortReplicas(instances, name)
+	return instances, nil
+}
+
+// findReplicas returns every managed instance belonging to the load balancer
+// whose first replica has ID id, with that replica first and the others
+// ordered by replica index. Looking it up by ID finds it even if it was
+// renamed, shares its name with another instance or is too new to be listed
+// yet. Without an ID, or if the instance is gone or no longer a load balancer
+// managed by this controller, the replicas are looked up by name instead.
+func (c *Client) findReplicas(ctx context.Context, id, name string) ([]*compute.Instance, error) {
+	if id == "" {
+		return c.listReplicas(ctx, name)
+	}
+
+	primary, err := c.getInstance(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return c.listReplicas(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if primary.State == "deleted" || primary.State == "destroyed" || !c.managesPrimary(primary) {
+		return c.listReplicas(ctx, name)
+	}
+
+	others, err := c.listManagedInstancesTagged(ctx, "", map[string]interface{}{replicaOfTag: name})
+	if err != nil {
+		return nil, err
+	}
+	var replicas []*compute.Instance
+	for _, instance := range others {
+		if instance.ID != primary.ID && replicaIndex(instance.Name, name) > 0 {
+			replicas = append(replicas, instance)
+		}
+	}
+	sortReplicas(replicas, name)
+	return append([]*compute.Instance{primary}, replicas...), nil
+}
+
+// checkReplicaName fails if the instance name of replica index of the named
+// load balancer is taken by the first replica of another load balancer
+func (c *Client) checkReplicaName(ctx context.Context, name string, index int) error {
+	if index == 0 {
+		return nil
+	}
+	instanceName := replicaName(name, index)
+	instances, err := c.listManagedInstances(ctx, instanceName)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if owner, ok := instance.Tags[replicaOfTag]; !ok || fmt.Sprint(owner) != name {
+			return newError(ErrConflict, "instance name %s of replica %d of load balancer %s is taken by another load balancer",
+				instanceName, index, name)
+		}
+	}
+	return nil
+}
+
+// managesPrimary reports whether instance is the first replica of a load
+// balancer managed by this controller in its cluster
+func (c *Client) managesPrimary(instance *compute.Instance) bool {
+	if fmt.Sprint(instance.Tags["loadbalancer"]) != "true" || fmt.Sprint(instance.Tags["managed-by"]) != c.managedBy() {
+		return false
+	}
+	if c.clusterName != "" && fmt.Sprint(instance.Tags[clusterTag]) != c.clusterName {
+		return false
+	}
+	_, replica := instance.Tags[replicaOfTag]
+	return !replica
+}
+
+// sortReplicas orders instances by their replica index
+func sortReplicas(instances []*compute.Instance, name string) {
+	sort.SliceStable(instances, func(i, j int) bool {
+		return replicaIndex(instances[i].Name, name) < replicaIndex(instances[j].Name, name)
+	})
+}
+
+// newReplicaSet converts a set of replicas into a TritonInstance for the
+// first replica, with every replica listed in Replicas
+func newReplicaSet(instances []*compute.Instance) *TritonInstance {
+	if len(instances) == 0 {
+		return nil
+	}
+
+	replicas := make([]*TritonInstance, 0, len(instances))
+	for _, instance := range instances {
+		replicas = append(replicas, newTritonInstance(instance))
+	}
+
+	primary := *replicas[0]
+	primary.Replicas = replicas
+	return &primary
+}
@@ -0,0 +1,51 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/joyent/triton-go/v2/compute"
+	tritonerrors "github.com/joyent/triton-go/v2/errors"
+)
+
+// resizeInstance moves a load balancer replica to the package pkg. CloudAPI
+// resizes zones in place, so the instance keeps serving while it happens.
+func (c *Client) resizeInstance(ctx context.Context, instance *compute.Instance, pkg string) error {
+	fmt.Printf("Resizing load balancer instance %s (%s) from package %s to %s\n",
+		instance.Name, instance.ID, instance.Package, pkg)
+
+	input := &compute.ResizeInstanceInput{ID: instance.ID, Package: pkg}
+	err := c.call(ctx, "ResizeMachine", func(ctx context.Context) error {
+		return c.instances.Resize(ctx, input)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resize instance %s to package %s: %w", instance.ID, pkg, err)
+	}
+	instance.Package = pkg
+	return nil
+}
+
+// isResizeRejected reports whether CloudAPI refused a resize itself, e.g.
+// because the package has a smaller disk or a different brand, so the
+// instance has to be replaced instead
+func isResizeRejected(err error) bool {
+	var apiErr *tritonerrors.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusConflict || apiErr.StatusCode == http.StatusUnprocessableEntity ||
+		apiErr.Code == "InvalidArgument" || apiErr.Code == "ValidationFailed"
+}
+
+// othersRunning reports whether every instance except skip is running, so
+// replacing skip leaves the load balancer serving
+func othersRunning(instances []*compute.Instance, skip *compute.Instance) bool {
+	for _, instance := range instances {
+		if instance != skip && instance.State != "running" {
+			return false
+		}
+	}
+	return true
+}
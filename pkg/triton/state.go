@@ -0,0 +1,71 @@
+package triton
+
+import (
+	"context"
+	"errors"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// terminalStates are instance states an instance stays in until it is acted
+// on, so polling for a change can stop
+var terminalStates = map[string]bool{
+	"running":   true,
+	"stopped":   true,
+	"failed":    true,
+	"deleted":   true,
+	"destroyed": true,
+}
+
+// failedStates are the terminal states an instance never leaves for running
+var failedStates = map[string]bool{
+	"failed":    true,
+	"deleted":   true,
+	"destroyed": true,
+}
+
+// isTerminalState reports whether an instance in state will stay there on
+// its own, as opposed to transient states such as provisioning or stopping
+func isTerminalState(state string) bool {
+	return terminalStates[state]
+}
+
+// IsFailedState reports whether an instance in state will never become
+// running and has to be provisioned again
+func IsFailedState(state string) bool {
+	return failedStates[state]
+}
+
+// GetInstanceState returns the current state of the instance with ID id. An
+// instance CloudAPI no longer knows about is reported as deleted.
+func (c *Client) GetInstanceState(ctx context.Context, id string) (string, error) {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return "", err
+		}
+		return dc.GetInstanceState(ctx, id)
+	}
+	instance, err := c.getInstance(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return "deleted", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return instance.State, nil
+}
+
+// getInstance fetches a single instance by ID
+func (c *Client) getInstance(ctx context.Context, id string) (*compute.Instance, error) {
+	getInput := &compute.GetInstanceInput{
+		ID: id,
+	}
+
+	var instance *compute.Instance
+	err := c.call(ctx, "GetMachine", func(ctx context.Context) error {
+		var err error
+		instance, err = c.instances.Get(ctx, getInput)
+		return err
+	})
+	return instance, err
+}
@@ -0,0 +1,101 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/joyent/triton-go/v2/compute"
+)
+
+// reservedTags are set and read by the controller itself and can never be
+// overwritten by user tags
+var reservedTags = map[string]bool{
+	"k8s-service":   true,
+	"k8s-namespace": true,
+	"managed-by":    true,
+	"loadbalancer":  true,
+	clusterTag:      true,
+	replicaOfTag:    true,
+}
+
+// IsReservedTag reports whether key is a tag owned by the controller or by
+// Triton (for example the triton.cns.* tags) rather than by the user
+func IsReservedTag(key string) bool {
+	return reservedTags[key] || strings.HasPrefix(key, "triton.")
+}
+
+// addUserTags copies the non-reserved user tags into tags
+func addUserTags(tags map[string]interface{}, userTags map[string]string) {
+	for k, v := range userTags {
+		if !IsReservedTag(k) {
+			tags[k] = v
+		}
+	}
+}
+
+// UpdateTags replaces the user tags of every replica of the load balancer
+// with tags, keeping the reserved tags, as UpdateLoadBalancer does. Replicas
+// whose tags already match are left alone.
+func (c *Client) UpdateTags(ctx context.Context, id, name string, tags map[string]string) error {
+	if dc, err := c.forDatacenter(ctx); dc != c {
+		if err != nil {
+			return err
+		}
+		return dc.UpdateTags(ctx, id, name, tags)
+	}
+	instances, err := c.findReplicas(ctx, id, name)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if err := c.syncUserTags(ctx, instance, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUserTags replaces the user tags on an existing instance with those in
+// params, keeping every reserved tag as it is. Nothing is sent when the tags
+// already match.
+func (c *Client) syncUserTags(ctx context.Context, instance *compute.Instance, userTags map[string]string) error {
+	desired := map[string]interface{}{}
+	for k, v := range instance.Tags {
+		if IsReservedTag(k) {
+			desired[k] = v
+		}
+	}
+	addUserTags(desired, userTags)
+
+	if tagsEqual(instance.Tags, desired) {
+		return nil
+	}
+
+	replaceInput := &compute.ReplaceTagsInput{
+		ID:   instance.ID,
+		Tags: desired,
+	}
+	err := c.call(ctx, "ReplaceMachineTags", func(ctx context.Context) error {
+		return c.instances.ReplaceTags(ctx, replaceInput)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update tags on instance %s: %w", instance.ID, err)
+	}
+	instance.Tags = desired
+	return nil
+}
+
+// tagsEqual compares two tag sets by their string values
+func tagsEqual(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		other, ok := b[k]
+		if !ok || fmt.Sprint(v) != fmt.Sprint(other) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package triton
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// userScriptMetadataKey is run by the instance when it boots
+	userScriptMetadataKey = "user-script"
+	// cloudInitMetadataKey is read by cloud-init on images that use it
+	cloudInitMetadataKey = "cloud-init:user-data"
+)
+
+// UserScriptData is what user-script templates are rendered with
+type UserScriptData struct {
+	// Name is the instance name of the first replica, and Replica the index
+	// of the instance being provisioned
+	Name    string
+	Replica int
+
+	ServiceName  string
+	Namespace    string
+	PortMappings []PortMapping
+}
+
+// ParseUserScriptTemplate parses a user-script or cloud-init template
+// rendered with UserScriptData, e.g. "{{range .PortMappings}}{{.ListenPort}}
+// {{end}}". The template is rendered once with sample values so that unknown
+// fields are reported at startup.
+func ParseUserScriptTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("user-script").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user-script template: %w", err)
+	}
+	sample := UserScriptData{
+		Name:         "example",
+		ServiceName:  "example",
+		Namespace:    "default",
+		PortMappings: []PortMapping{{Type: "http", ListenPort: 80, BackendName: "example", BackendPort: 8080}},
+	}
+	if _, _, err := renderUserScript(tmpl, sample); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// WithUserScript renders tmpl into the metadata of every new instance: as its
+// cloud-init:user-data when it renders to a #cloud-config document, and as
+// its user-script otherwise. Running instances keep the script they were
+// provisioned with.
+func WithUserScript(tmpl *template.Template) ClientOption {
+	return func(c *Client) {
+		c.userScript = tmpl
+	}
+}
+
+// addUserScript renders the user-script of replica index of the load
+// balancer described by params into metadata
+func (c *Client) addUserScript(metadata map[string]interface{}, params LoadBalancerParams, index int) error {
+	if c.userScript == nil {
+		return nil
+	}
+	key, script, err := renderUserScript(c.userScript, UserScriptData{
+		Name:         params.Name,
+		Replica:      index,
+		ServiceName:  params.serviceName(),
+		Namespace:    params.Namespace,
+		PortMappings: params.PortMappings,
+	})
+	if err != nil {
+		return err
+	}
+	metadata[key] = script
+	return nil
+}
+
+// renderUserScript renders tmpl with data and returns the metadata key the
+// result belongs in
+func renderUserScript(tmpl *template.Template, data UserScriptData) (string, string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("invalid user-script template: %w", err)
+	}
+	script := b.String()
+	if strings.HasPrefix(strings.TrimLeft(script, " \t\r\n"), "#cloud-config") {
+		return cloudInitMetadataKey, script, nil
+	}
+	return userScriptMetadataKey, script, nil
+}
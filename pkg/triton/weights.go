@@ -0,0 +1,64 @@
+package triton
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxBackendWeight is the largest weight HAProxy accepts for a server
+const maxBackendWeight = 256
+
+// ParseBackendWeights parses a comma-separated list of name=weight pairs
+// such as "web-v1=80,web-v2=20". Weights must be integers between 0 and
+// 256, names must be unique and at least one weight must be positive.
+func ParseBackendWeights(s string) (map[string]int, error) {
+	weights := map[string]int{}
+	total := 0
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid backend weight %q: expected <name>=<weight>", entry)
+		}
+		if _, dup := weights[name]; dup {
+			return nil, fmt.Errorf("duplicate backend weight for %s", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 || weight > maxBackendWeight {
+			return nil, fmt.Errorf("invalid weight %q for backend %s: must be an integer between 0 and %d",
+				value, name, maxBackendWeight)
+		}
+		weights[name] = weight
+		total += weight
+	}
+
+	if len(weights) == 0 {
+		return nil, nil
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("backend weights %q send no traffic: at least one weight must be positive", s)
+	}
+	return weights, nil
+}
+
+// formatBackendWeights renders weights in the format read by
+// ParseBackendWeights, sorted by backend name
+func formatBackendWeights(weights map[string]int) string {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]string, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, fmt.Sprintf("%s=%d", name, weights[name]))
+	}
+	return strings.Join(entries, ",")
+}
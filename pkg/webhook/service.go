@@ -0,0 +1,115 @@
+// Package webhook validates the load balancer annotations of Services at
+// admission time, so a misconfigured Service is rejected when it is applied
+// instead of failing its reconciles.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// ServiceValidator checks what ValidateService of the LoadBalancerReconciler
+// checks
+type ServiceValidator interface {
+	ValidateService(service *corev1.Service) (*triton.LoadBalancerParams, error)
+}
+
+// CatalogValidator looks up package and image overrides in the datacenter
+// ctx selects, as *triton.Client does
+type CatalogValidator interface {
+	ValidateCatalog(ctx context.Context, params triton.LoadBalancerParams) error
+}
+
+// ServiceWebhook rejects LoadBalancer Services whose annotations the
+// controller can't apply
+type ServiceWebhook struct {
+	// Validator checks the annotations, as the controller reads them
+	Validator ServiceValidator
+	// Catalog, if set, checks that package and image overrides, and the
+	// datacenter, exist
+	Catalog CatalogValidator
+}
+
+var _ admission.CustomValidator = &ServiceWebhook{}
+
+// SetupWithManager serves the webhook at /validate--v1-service from the
+// webhook server of mgr
+func (w *ServiceWebhook) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&corev1.Service{}).
+		WithValidator(w).
+		Complete()
+}
+
+// ValidateCreate rejects a new Service with invalid annotations
+func (w *ServiceWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("expected a Service, got %T", obj)
+	}
+	return w.validate(ctx, nil, service)
+}
+
+// ValidateUpdate rejects a change that makes the annotations of a Service
+// invalid
+func (w *ServiceWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldService, ok := oldObj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("expected a Service, got %T", oldObj)
+	}
+	service, ok := newObj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("expected a Service, got %T", newObj)
+	}
+	return w.validate(ctx, oldService, service)
+}
+
+// ValidateDelete allows every delete
+func (w *ServiceWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks service, which replaces old unless old is nil. A Service
+// that was already invalid before the update is only warned about, so the
+// controller can keep recording its state on it until it is fixed.
+func (w *ServiceWebhook) validate(ctx context.Context, old, service *corev1.Service) (admission.Warnings, error) {
+	params, err := w.Validator.ValidateService(service)
+	if err != nil {
+		if old != nil {
+			if _, oldErr := w.Validator.ValidateService(old); oldErr != nil {
+				return admission.Warnings{err.Error()}, nil
+			}
+		}
+		return nil, err
+	}
+	if params == nil || w.Catalog == nil || (params.Package == "" && params.Image == "" && params.Datacenter == "") {
+		return nil, nil
+	}
+
+	// Only look overrides up when they change, not on every update
+	if old != nil {
+		oldParams, err := w.Validator.ValidateService(old)
+		if err == nil && oldParams != nil && oldParams.Package == params.Package && oldParams.Image == params.Image &&
+			oldParams.Datacenter == params.Datacenter {
+			return nil, nil
+		}
+	}
+
+	// Only overrides CloudAPI doesn't know are rejected; a failed lookup
+	// is left to the reconcile
+	if err := w.Catalog.ValidateCatalog(triton.WithDatacenter(ctx, params.Datacenter), *params); err != nil {
+		if errors.Is(err, triton.ErrNotFound) {
+			return nil, err
+		}
+		return admission.Warnings{fmt.Sprintf("could not check the package and image: %v", err)}, nil
+	}
+	return nil, nil
+}
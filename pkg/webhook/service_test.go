@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/triton/loadbalancer-controller/pkg/controller"
+	"github.com/triton/loadbalancer-controller/pkg/triton"
+)
+
+// fakeCatalog knows a fixed set of packages and datacenters and counts
+// lookups
+type fakeCatalog struct {
+	packages    map[string]bool
+	datacenters map[string]bool
+	err         error
+	calls       int
+}
+
+func (f *fakeCatalog) ValidateCatalog(ctx context.Context, params triton.LoadBalancerParams) error {
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+	if dc := triton.DatacenterFromContext(ctx); dc != "" && !f.datacenters[dc] {
+		return fmt.Errorf("unknown Triton datacenter %q: %w", dc, triton.ErrNotFound)
+	}
+	if params.Package != "" && !f.packages[params.Package] {
+		return fmt.Errorf("invalid package %q: %w", params.Package, triton.ErrNotFound)
+	}
+	return nil
+}
+
+func newService(annotations map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: annotations},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+}
+
+func newWebhook(t *testing.T, catalog *fakeCatalog) *ServiceWebhook {
+	reconciler := controller.NewLoadBalancerReconciler(nil, testr.New(t), nil, nil, nil)
+	return &ServiceWebhook{Validator: reconciler, Catalog: catalog}
+}
+
+func TestValidateCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		valid       bool
+	}{
+		{name: "no annotations", valid: true},
+		{name: "valid max_rs", annotations: map[string]string{"cloud.tritoncompute/max_rs": "64"}, valid: true},
+		{name: "max_rs not a number", annotations: map[string]string{"cloud.tritoncompute/max_rs": "many"}},
+		{name: "max_rs out of range", annotations: map[string]string{"cloud.tritoncompute/max_rs": "5000"}},
+		{name: "valid metrics_acl", annotations: map[string]string{"cloud.tritoncompute/metrics_acl": "10.0.0.0/8 192.0.2.1"}, valid: true},
+		{name: "invalid metrics_acl", annotations: map[string]string{"cloud.tritoncompute/metrics_acl": "10.0.0.0/33"}},
+		{name: "invalid protocol", annotations: map[string]string{"cloud.tritoncompute/protocol.http": "ftp"}},
+		{name: "invalid port range", annotations: map[string]string{"cloud.tritoncompute/port-range.http": "90-80"}},
+		{name: "known package", annotations: map[string]string{"cloud.tritoncompute/package": "g4-highcpu-4G"}, valid: true},
+		{name: "unknown package", annotations: map[string]string{"cloud.tritoncompute/package": "g4-typo"}},
+		{name: "known datacenter", annotations: map[string]string{"cloud.tritoncompute/datacenter": "us-west-1"}, valid: true},
+		{name: "unknown datacenter", annotations: map[string]string{"cloud.tritoncompute/datacenter": "us-wset-1"}},
+		{name: "ignored service", annotations: map[string]string{"cloud.tritoncompute/ignore": "true", "cloud.tritoncompute/max_rs": "many"}, valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWebhook(t, &fakeCatalog{
+				packages:    map[string]bool{"g4-highcpu-4G": true},
+				datacenters: map[string]bool{"us-west-1": true},
+			})
+			_, err := w.ValidateCreate(context.Background(), newService(tt.annotations))
+			if tt.valid && err != nil {
+				t.Errorf("expected the Service to be admitted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected the Service to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidateCreateSkipsOtherServices(t *testing.T) {
+	w := newWebhook(t, &fakeCatalog{})
+	service := newService(map[string]string{"cloud.tritoncompute/max_rs": "many"})
+	service.Spec.Type = corev1.ServiceTypeClusterIP
+	if _, err := w.ValidateCreate(context.Background(), service); err != nil {
+		t.Errorf("expected a ClusterIP Service to be admitted, got %v", err)
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	ctx := context.Background()
+	catalog := &fakeCatalog{packages: map[string]bool{"g4-highcpu-4G": true}}
+	w := newWebhook(t, catalog)
+
+	valid := newService(map[string]string{"cloud.tritoncompute/package": "g4-highcpu-4G"})
+	invalid := newService(map[string]string{"cloud.tritoncompute/max_rs": "0"})
+
+	if _, err := w.ValidateUpdate(ctx, valid, invalid); err == nil {
+		t.Error("expected an update making the annotations invalid to be rejected")
+	}
+
+	// The controller keeps recording its state on an already invalid Service
+	recorded := invalid.DeepCopy()
+	recorded.Annotations["cloud.tritoncompute/last-error"] = "invalid"
+	warnings, err := w.ValidateUpdate(ctx, invalid, recorded)
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("expected an already invalid Service to be admitted with a warning, got %v and %v", warnings, err)
+	}
+
+	// Unchanged overrides aren't looked up again
+	catalog.calls = 0
+	relabeled := valid.DeepCopy()
+	relabeled.Labels = map[string]string{"team": "web"}
+	if _, err := w.ValidateUpdate(ctx, valid, relabeled); err != nil {
+		t.Errorf("expected the update to be admitted, got %v", err)
+	}
+	if catalog.calls != 0 {
+		t.Errorf("expected no catalog lookup for unchanged overrides, got %d", catalog.calls)
+	}
+}
+
+func TestValidateCatalogUnavailable(t *testing.T) {
+	w := newWebhook(t, &fakeCatalog{err: errors.New("connection refused")})
+	service := newService(map[string]string{"cloud.tritoncompute/image": "haproxy@2.0"})
+
+	warnings, err := w.ValidateCreate(context.Background(), service)
+	if err != nil {
+		t.Fatalf("expected a failed lookup to be left to the reconcile, got %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning about the failed lookup, got %v", warnings)
+	}
+}